@@ -2,11 +2,11 @@ package converter
 
 import (
 	"cmp"
-	"fmt"
 	"slices"
 
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	intconverter "github.com/sudorandom/protoc-gen-connect-openapi/internal/converter"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -16,12 +16,38 @@ import (
 
 var Convert = intconverter.Convert
 
+// ConvertFrom reads a *pluginpb.CodeGeneratorRequest and converts it like Convert. Programs that wrap the plugin with
+// their own transformers can use it as their main function:
+//
+//	func main() {
+//		converter.RegisterTransformer(myTransformer{})
+//		resp, err := converter.ConvertFrom(os.Stdin)
+//		...
+//	}
+var ConvertFrom = intconverter.ConvertFrom
+
+// DocumentTransformer changes a generated document after every option is applied, right before it's written.
+type DocumentTransformer = options.DocumentTransformer
+
+// SchemaTransformer changes the schema of a message after every annotation is applied. It can be called more than
+// once for the same message.
+type SchemaTransformer = options.SchemaTransformer
+
+// RegisterTransformer adds a DocumentTransformer, a SchemaTransformer or a type that implements both to every
+// conversion, including conversions with Convert and ConvertFrom. It panics if the transformer implements neither.
+func RegisterTransformer(transformer any) {
+	options.RegisterTransformer(transformer)
+}
+
+// TagHumanizer returns the name that documentation viewers show for the tag of a service.
+type TagHumanizer = options.TagHumanizer
+
 type generator struct {
 	req     *pluginpb.CodeGeneratorRequest
 	options options.Options
 }
 
-// Generate a single OpenAPI file.
+// Generate a single OpenAPI file. The options aren't validated, use options.New and WithOptions for that.
 func GenerateSingle(opts ...Option) ([]byte, error) {
 	g, err := generatorWithOptions(opts...)
 	if err != nil {
@@ -35,7 +61,8 @@ func GenerateSingle(opts ...Option) ([]byte, error) {
 	return []byte(resp.File[0].GetContent()), nil
 }
 
-// Generate OpenAPI files with the given options.
+// Generate OpenAPI files with the given options. The options aren't validated, use options.New and WithOptions for
+// that.
 func Generate(opts ...Option) ([]*pluginpb.CodeGeneratorResponse_File, error) {
 	g, err := generatorWithOptions(opts...)
 	if err != nil {
@@ -67,8 +94,37 @@ func generatorWithOptions(opts ...Option) (*generator, error) {
 	return g, nil
 }
 
+// MessageToSchema returns the schema of a single message, built with the same logic as the message schemas in
+// generated documents, for tools like mock servers and request validators. Options that change schemas, like
+// IncludeNumberEnumValues or transformers, apply. Options that change the finished document, like inline-threshold
+// or spectral-compat, don't. References to other messages and enums point to #/components/schemas.
+func MessageToSchema(desc protoreflect.MessageDescriptor, opts options.Options) (*base.Schema, error) {
+	return intconverter.MessageToSchema(opts, desc)
+}
+
 type Option func(*generator) error
 
+// WithOptions replaces all options with the given ones, which are usually made with options.New. Options after this
+// one still apply.
+func WithOptions(opts options.Options) Option {
+	return func(g *generator) error {
+		g.options = opts
+		return nil
+	}
+}
+
+// withOptions applies functional options from the options package to the generator.
+func withOptions(opts ...options.Option) Option {
+	return func(g *generator) error {
+		for _, opt := range opts {
+			if err := opt(&g.options); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 // WithSourceFiles adds the given files as source files but won't generate OpenAPI based on any services found in here.
 func WithSourceFiles(files *protoregistry.Files) Option {
 	return func(g *generator) error {
@@ -109,128 +165,467 @@ func WithGlobal() Option {
 	return WithFiles(protoregistry.GlobalFiles)
 }
 
-// WithFormat sets the format for the OpenAPI file.
+// WithParameters applies plugin parameters, written like they're given to the plugin: a comma-separated list like
+// "allow-get,format=json".
+func WithParameters(s string) Option {
+	return withOptions(options.WithParameters(s))
+}
+
+// WithAllowUnknownParams logs a warning for parameters given to WithParameters that aren't known instead of
+// failing. It applies to the parameters that come after it.
+func WithAllowUnknownParams(allow bool) Option {
+	return withOptions(options.WithAllowUnknownParams(allow))
+}
+
+// WithLog sets where the generator logs to, in which format and from which level, like
+// "level:debug;format:json;file:gen.log". A debug level also enables the debug logging of WithDebug.
+func WithLog(config string) Option {
+	return withOptions(options.WithLog(config))
+}
+
+// WithKeepGoing skips the files that fail to convert, after logging why, instead of failing the whole generation.
+// It can't be used with WithPath.
+func WithKeepGoing(enabled bool) Option {
+	return withOptions(options.WithKeepGoing(enabled))
+}
+
+// WithFormat sets the format of the OpenAPI files: "yaml" or "json".
 func WithFormat(format string) Option {
-	return func(g *generator) error {
-		g.options.Format = format
-		return nil
-	}
+	return withOptions(options.WithFormat(format))
 }
 
 // WithBaseOpenAPI sets a file to use as a base for all OpenAPI files.
 func WithBaseOpenAPI(baseOpenAPI []byte) Option {
-	return func(g *generator) error {
-		g.options.BaseOpenAPI = baseOpenAPI
-		return nil
-	}
+	return withOptions(options.WithBaseOpenAPI(baseOpenAPI))
 }
 
-// WithAllowGET sets a file to use as a base for all OpenAPI files.
+// WithAllowGET documents methods with `idempotency_level = NO_SIDE_EFFECTS` with GET requests.
 func WithAllowGET(allowGet bool) Option {
-	return func(g *generator) error {
-		g.options.AllowGET = allowGet
-		return nil
-	}
+	return withOptions(options.WithAllowGET(allowGet))
 }
 
-// WithContentTypes sets a file to use as a base for all OpenAPI files.
+// WithContentTypes sets the content types of requests and responses. Available values are in options.Protocols.
 func WithContentTypes(contentTypes ...string) Option {
-	return func(g *generator) error {
-		g.options.ContentTypes = map[string]struct{}{}
-		for _, contentType := range contentTypes {
-			if !options.IsValidContentType(contentType) {
-				return fmt.Errorf("unknown content type: '%s'", contentType)
-			}
-			g.options.ContentTypes[contentType] = struct{}{}
-		}
-		return nil
-	}
+	return withOptions(options.WithContentTypes(contentTypes...))
 }
 
-// WithIncludeNumberEnumValues sets a file to use as a base for all OpenAPI files.
+// WithIncludeNumberEnumValues includes the numbers of enum values in addition to their names.
 func WithIncludeNumberEnumValues(includeNumberEnumValues bool) Option {
-	return func(g *generator) error {
-		g.options.IncludeNumberEnumValues = includeNumberEnumValues
-		return nil
-	}
+	return withOptions(options.WithIncludeNumberEnumValues(includeNumberEnumValues))
 }
 
 // WithIgnoreGoogleapiHTTP tells the generator to ignore google.api.http options.
 func WithIgnoreGoogleapiHTTP(ignoreGoogleapiHTTP bool) Option {
-	return func(g *generator) error {
-		g.options.IgnoreGoogleapiHTTP = ignoreGoogleapiHTTP
-		return nil
-	}
+	return withOptions(options.WithIgnoreGoogleapiHTTP(ignoreGoogleapiHTTP))
 }
 
-// WithStreaming sets a file to use as a base for all OpenAPI files.
+// WithStreaming documents the content types related to streaming.
 func WithStreaming(streaming bool) Option {
-	return func(g *generator) error {
-		g.options.WithStreaming = streaming
-		return nil
-	}
+	return withOptions(options.WithStreaming(streaming))
 }
 
-// WithDebug sets up the logger to emit debug entries
+// WithEndStreamFrame documents the end-of-stream frame of Connect streaming responses. It needs WithStreaming.
+func WithEndStreamFrame(enabled bool) Option {
+	return withOptions(options.WithEndStreamFrame(enabled))
+}
+
+// WithDebug sets up the logger to emit debug entries.
 func WithDebug(enabled bool) Option {
-	return func(g *generator) error {
-		g.options.Debug = enabled
-		return nil
-	}
+	return withOptions(options.WithDebug(enabled))
 }
 
-// WithProtoAnnotations adds some details about protobuf to descrioptions.
+// WithProtoAnnotations adds some details about protobuf to descriptions.
 func WithProtoAnnotations(enabled bool) Option {
-	return func(g *generator) error {
-		g.options.WithProtoAnnotations = enabled
-		return nil
-	}
+	return withOptions(options.WithProtoAnnotations(enabled))
 }
 
 // WithServices will limit the services generated.
 func WithServices(serviceNames []protoreflect.FullName) Option {
-	return func(g *generator) error {
-		g.options.Services = append(g.options.Services, serviceNames...)
-		return nil
-	}
+	return withOptions(options.WithServices(serviceNames))
 }
 
 // WithShortServiceTags uses the short service name instead of the full name for OpenAPI tags.
 func WithShortServiceTags(enabled bool) Option {
-	return func(g *generator) error {
-		g.options.ShortServiceTags = enabled
-		return nil
-	}
+	return withOptions(options.WithShortServiceTags(enabled))
 }
 
 // WithShortOperationIds sets the operationId to shortServiceName + "_" + method short name instead of the full method name.
 func WithShortOperationIds(enabled bool) Option {
-	return func(g *generator) error {
-		g.options.ShortOperationIds = enabled
-		return nil
-	}
+	return withOptions(options.WithShortOperationIds(enabled))
 }
 
 // WithFullyQualifiedMessageNames decides if you want to use the full path in message names.
 func WithFullyQualifiedMessageNames(enabled bool) Option {
-	return func(g *generator) error {
-		g.options.FullyQualifiedMessageNames = enabled
-		return nil
-	}
+	return withOptions(options.WithFullyQualifiedMessageNames(enabled))
 }
 
 // WithServiceDescriptions decides if service names and their comments to be added to the end of info.description.
 func WithServiceDescriptions(enabled bool) Option {
-	return func(g *generator) error {
-		g.options.WithServiceDescriptions = enabled
-		return nil
-	}
+	return withOptions(options.WithServiceDescriptions(enabled))
 }
 
 // WithPathPrefix prepends a given string to each HTTP path.
 func WithPathPrefix(prefix string) Option {
-	return func(g *generator) error {
-		g.options.PathPrefix = prefix
-		return nil
-	}
+	return withOptions(options.WithPathPrefix(prefix))
+}
+
+// WithGRPCSystemServices includes the gRPC health and reflection services, which are excluded by default.
+func WithGRPCSystemServices(enabled bool) Option {
+	return withOptions(options.WithGRPCSystemServices(enabled))
+}
+
+// WithSkipImports skips the well-known google files passed to the plugin, which is the default, or generates
+// documents for them too.
+func WithSkipImports(skip bool) Option {
+	return withOptions(options.WithSkipImports(skip))
+}
+
+// WithConstraintDescriptions appends a human-readable summary of validation constraints to property descriptions.
+func WithConstraintDescriptions(enabled bool) Option {
+	return withOptions(options.WithConstraintDescriptions(enabled))
+}
+
+// WithOpenAPIv2Annotations applies the grpc.gateway.protoc_gen_openapiv2.options annotations of protoc-gen-openapiv2,
+// so files that were annotated for it don't have to be annotated again.
+func WithOpenAPIv2Annotations(enabled bool) Option {
+	return withOptions(options.WithOpenAPIv2Annotations(enabled))
+}
+
+// WithLifecycleHeaders documents the Deprecation and Sunset response headers on deprecated operations, with the
+// dates from the x-lifecycle extension of their methods.
+func WithLifecycleHeaders(enabled bool) Option {
+	return withOptions(options.WithLifecycleHeaders(enabled))
+}
+
+// WithTraceHeaders documents the traceparent, tracestate and baggage tracing headers on every operation.
+func WithTraceHeaders(enabled bool) Option {
+	return withOptions(options.WithTraceHeaders(enabled))
+}
+
+// WithIdempotencyKey documents an Idempotency-Key header and a 409 response on operations that have side effects.
+func WithIdempotencyKey(enabled bool) Option {
+	return withOptions(options.WithIdempotencyKey(enabled))
+}
+
+// WithRateLimitResponses adds a 429 response with rate limiting headers to every operation.
+func WithRateLimitResponses(enabled bool) Option {
+	return withOptions(options.WithRateLimitResponses(enabled))
+}
+
+// WithAuthResponses adds 401 and 403 responses to every operation with a security requirement.
+func WithAuthResponses(enabled bool) Option {
+	return withOptions(options.WithAuthResponses(enabled))
+}
+
+// WithGlobalResponse adds the response with the given name from components.responses to every operation, using
+// the given status code.
+func WithGlobalResponse(code, responseName string) Option {
+	return withOptions(options.WithGlobalResponse(code, responseName))
+}
+
+// WithSummarySources sets the sources of operation summaries, which are tried in order: "comment" for the first line
+// of the method's comments, "humanized" for the humanized method name ("ListBooks" → "List books") and "name" for the
+// method name, which is the last resort.
+func WithSummarySources(sources ...string) Option {
+	return withOptions(options.WithSummarySources(sources...))
+}
+
+// WithResponseEnvelope wraps every successful JSON response in the given schema from components.schemas. The slot
+// property of the envelope, "data" when it's empty, is replaced with the actual response schema.
+func WithResponseEnvelope(schemaName, slot string) Option {
+	return withOptions(options.WithResponseEnvelope(schemaName, slot))
+}
+
+// WithTerse removes descriptions, titles and examples from the output.
+func WithTerse(enabled bool) Option {
+	return withOptions(options.WithTerse(enabled))
+}
+
+// WithOverrideStrategy sets how annotations in the given category ("schema" or "operation") are combined with
+// generated content. The strategy is "merge", "replace" or "generated-wins".
+func WithOverrideStrategy(category, strategy string) Option {
+	return withOptions(options.WithOverrideStrategy(category, strategy))
+}
+
+// WithStrict makes conflicts between annotations and generated content an error.
+func WithStrict(enabled bool) Option {
+	return withOptions(options.WithStrict(enabled))
+}
+
+// WithSpectralCompat adjusts the output so it passes the default Spectral ruleset.
+func WithSpectralCompat(enabled bool) Option {
+	return withOptions(options.WithSpectralCompat(enabled))
+}
+
+// WithBackstage writes a Backstage catalog-info.yaml next to every generated spec.
+func WithBackstage(enabled bool) Option {
+	return withOptions(options.WithBackstage(enabled))
+}
+
+// WithChangelog compares the generated spec with a previous version and adds a changelog. The changelog is written
+// to a markdown file with the given name or, when the name is "description", appended to info.description.
+func WithChangelog(previous []byte, name string) Option {
+	return withOptions(options.WithChangelog(previous, name))
+}
+
+// WithStampVersion sets info.version to the version of the previous spec given to WithChangelog, with the
+// recommended semantic version bump applied.
+func WithStampVersion(enabled bool) Option {
+	return withOptions(options.WithStampVersion(enabled))
+}
+
+// WithVersionBump writes the recommended semantic version bump since the previous spec given to WithChangelog to a
+// JSON file with the given name.
+func WithVersionBump(name string) Option {
+	return withOptions(options.WithVersionBump(name))
+}
+
+// WithConnectValidation adds the protovalidate rules of the request message to every operation as an
+// x-connect-validation extension.
+func WithConnectValidation(enabled bool) Option {
+	return withOptions(options.WithConnectValidation(enabled))
+}
+
+// WithFileTransfers renders methods whose request or response is a single bytes field as multipart/form-data
+// uploads and application/octet-stream downloads.
+func WithFileTransfers(enabled bool) Option {
+	return withOptions(options.WithFileTransfers(enabled))
+}
+
+// WithFlavor sets the runtime that serves the HTTP/JSON API: "connect" (the default), "grpc-gateway" or
+// "envoy-json-transcoder".
+func WithFlavor(flavor string) Option {
+	return withOptions(options.WithFlavor(flavor))
+}
+
+// WithEnvoyJWTConfig derives security schemes and the security of each operation from the contents of an Envoy
+// jwt_authn filter config.
+func WithEnvoyJWTConfig(config []byte) Option {
+	return withOptions(options.WithEnvoyJWTConfig(config))
+}
+
+// WithOIDCIssuer adds an openIdConnect security scheme with the discovery URL of the issuer and uses it as the
+// default security of the document.
+func WithOIDCIssuer(issuer string) Option {
+	return withOptions(options.WithOIDCIssuer(issuer))
+}
+
+// WithMTLS makes the methods of a service, or a single method, require a TLS client certificate. The spiffeID is an
+// optional pattern for the SPIFFE ID of the certificate, like spiffe://example.com/partners/*.
+func WithMTLS(name protoreflect.FullName, spiffeID string) Option {
+	return withOptions(options.WithMTLS(name, spiffeID))
+}
+
+// WithCodeSamples adds curl, connect-web and connect-go samples to every operation as an x-codeSamples extension.
+func WithCodeSamples(enabled bool) Option {
+	return withOptions(options.WithCodeSamples(enabled))
+}
+
+// WithBufModule records the buf module and commit that the spec is generated from as an x-buf-module extension. The
+// commit can be empty. When inDescription is true, the module is also mentioned in info.description.
+func WithBufModule(name, commit string, inDescription bool) Option {
+	return withOptions(options.WithBufModule(name, commit, inDescription))
+}
+
+// WithHTML writes a self-contained HTML page with the spec embedded next to every generated spec.
+func WithHTML(enabled bool) Option {
+	return withOptions(options.WithHTML(enabled))
+}
+
+// WithPropertyOrder sets the order of the properties of message schemas: "declaration" for the order the fields are
+// declared in or "number" for field number order.
+func WithPropertyOrder(order string) Option {
+	return withOptions(options.WithPropertyOrder(order))
+}
+
+// WithTransformer adds a DocumentTransformer, a SchemaTransformer or a type that implements both to this conversion.
+// Transformers run in the order they're added, after the ones added with RegisterTransformer.
+func WithTransformer(transformer any) Option {
+	return withOptions(options.WithTransformer(transformer))
+}
+
+// WithPostProcessCmd pipes every generated document through a shell command, like a jq or yq script, and writes
+// what the command prints instead.
+func WithPostProcessCmd(cmd string) Option {
+	return withOptions(options.WithPostProcessCmd(cmd))
+}
+
+// WithOverlay applies an OpenAPI Overlay document to every generated document. Overlays are applied in the order
+// they're added, after everything else.
+func WithOverlay(overlay []byte) Option {
+	return withOptions(options.WithOverlay(overlay))
+}
+
+// WithJSONPatch applies an RFC 6902 JSON Patch, written in JSON or YAML, to every generated document after the
+// overlays.
+func WithJSONPatch(patch []byte) Option {
+	return withOptions(options.WithJSONPatch(patch))
+}
+
+// WithMergePatch applies an RFC 7386 JSON Merge Patch, written in JSON or YAML, to every generated document after
+// the JSON patches.
+func WithMergePatch(patch []byte) Option {
+	return withOptions(options.WithMergePatch(patch))
+}
+
+// WithInferGETFromNames treats methods without an idempotency_level that are named like a read, such as GetBook,
+// ListBooks or BatchGetBooks, as free of side effects and enables GET requests for them.
+func WithInferGETFromNames(infer bool) Option {
+	return withOptions(options.WithInferGETFromNames(infer))
+}
+
+// WithConnectPaths also documents the Connect path of methods that have google.api.http paths. The operations of
+// both route styles are linked with an x-alternate-operations extension.
+func WithConnectPaths(withConnectPaths bool) Option {
+	return withOptions(options.WithConnectPaths(withConnectPaths))
+}
+
+// WithVisibilityLabels sets the google.api.visibility restriction labels of the audience the spec is for. Methods and
+// services restricted to other labels are marked with x-internal: true.
+func WithVisibilityLabels(labels ...string) Option {
+	return withOptions(options.WithVisibilityLabels(labels...))
+}
+
+// WithRemoveInternal removes operations marked with x-internal: true from the output, for specs that are published.
+func WithRemoveInternal(removeInternal bool) Option {
+	return withOptions(options.WithRemoveInternal(removeInternal))
+}
+
+// WithStableAnchors renames tags and operationIds to collision-free slugs, so the deep links that Redoc and Stoplight
+// build from them are URL-safe. Tags keep their original name as x-displayName.
+func WithStableAnchors(stableAnchors bool) Option {
+	return withOptions(options.WithStableAnchors(stableAnchors))
+}
+
+// WithInventory writes a file with the given name that lists every operation with its service, method, path, HTTP
+// method, idempotency and security requirements. Names that end with .csv are written as CSV, others as JSON.
+func WithInventory(name string) Option {
+	return withOptions(options.WithInventory(name))
+}
+
+// WithInlineThreshold inlines the message schemas with fewer properties than inlineThreshold at every use, instead
+// of referencing them in components.schemas, unless they refer to themselves.
+func WithInlineThreshold(inlineThreshold int) Option {
+	return withOptions(options.WithInlineThreshold(inlineThreshold))
+}
+
+// WithMaxBodyBytes documents the maximum size of request bodies in bytes on every operation with a request body as
+// an x-max-body-bytes extension. Methods can set their own limit with the x-max-body-bytes extension.
+func WithMaxBodyBytes(maxBodyBytes int64) Option {
+	return withOptions(options.WithMaxBodyBytes(maxBodyBytes))
+}
+
+// WithTagDisplayNames adds a human-friendly x-displayName to the tag of every service, so documentation sidebars
+// don't show raw protobuf names. When humanizer is nil, "UserAccountService" becomes "User Account".
+func WithTagDisplayNames(humanizer TagHumanizer) Option {
+	return withOptions(options.WithTagDisplayNames(humanizer))
+}
+
+// WithDescriptionFile puts a markdown file at the head of info.description, like "{package_dir}/README.md".
+// {package_dir} is replaced by the directory of each proto file and {package} by its package. Packages without the
+// file are skipped.
+func WithDescriptionFile(name string) Option {
+	return withOptions(options.WithDescriptionFile(name))
+}
+
+// WithDuplicatesReport writes a JSON file with the given name that lists messages with identical or similar schemas
+// across packages, to help find duplicated DTOs.
+func WithDuplicatesReport(name string) Option {
+	return withOptions(options.WithDuplicatesReport(name))
+}
+
+// WithPathCase converts the literal segments of every path to a case: "asis", "kebab", "snake" or "lower". Every
+// operation records its Connect path in an x-rpc-path extension.
+func WithPathCase(pathCase string) Option {
+	return withOptions(options.WithPathCase(pathCase))
+}
+
+// WithDefaultResponse decides the `default` response of every operation: "error" references the error schema, "off"
+// leaves it out and "echo" references the given schema from components.schemas.
+func WithDefaultResponse(mode, schemaName string) Option {
+	return withOptions(options.WithDefaultResponse(mode, schemaName))
+}
+
+// WithEnumExtensions adds x-enum-varnames, x-enum-descriptions and x-ms-enum to enum schemas for code generators.
+func WithEnumExtensions(enabled bool) Option {
+	return withOptions(options.WithEnumExtensions(enabled))
+}
+
+// WithStructDocs documents google.protobuf.Struct and google.protobuf.Value as recursive JSON values, with an example
+// and a note about the nesting, instead of free-form objects.
+func WithStructDocs(enabled bool) Option {
+	return withOptions(options.WithStructDocs(enabled))
+}
+
+// WithFeaturesReport writes a JSON file with the given name that lists the features of the input that change the
+// output, like streaming methods, google.api.http rules or protovalidate rules, with the options that go with them.
+func WithFeaturesReport(name string) Option {
+	return withOptions(options.WithFeaturesReport(name))
+}
+
+// WithWhy logs which stage of the converter, and the option behind it, added, changed or removed an element of the
+// generated documents, like components.schemas.connect.error or a JSON pointer like /paths/~1v1~1books/get.
+func WithWhy(element string) Option {
+	return withOptions(options.WithWhy(element))
+}
+
+// WithRouteTable writes a JSON file with the given name that lists the Connect procedure and the google.api.http
+// rules of every method with its request and response types, for runtimes like vanguard-go.
+func WithRouteTable(name string) Option {
+	return withOptions(options.WithRouteTable(name))
+}
+
+// WithJSONSchemaDialect sets jsonSchemaDialect to "oas", "2020-12" or the URI of a dialect, and rewrites the schema
+// keywords that the dialect doesn't define.
+func WithJSONSchemaDialect(dialect string) Option {
+	return withOptions(options.WithJSONSchemaDialect(dialect))
+}
+
+// WithValidationErrors adds a 400 response with a google.rpc.BadRequest detail to every operation whose request
+// message has protovalidate rules. The field of each violation is restricted to the constrained fields.
+func WithValidationErrors(enabled bool) Option {
+	return withOptions(options.WithValidationErrors(enabled))
+}
+
+// WithAIPs documents the conventions of the given Google API Improvement Proposals, like "132" for the fields of
+// standard List methods or "154" for the etags and conditional requests of AIP-154.
+func WithAIPs(numbers ...string) Option {
+	return withOptions(options.WithAIPs(numbers...))
+}
+
+// WithSplitByTag writes the paths of every tag to a separate document, with the components they use. The output
+// file becomes an index whose paths reference those documents.
+func WithSplitByTag(enabled bool) Option {
+	return withOptions(options.WithSplitByTag(enabled))
+}
+
+// WithPath writes every file to a single OpenAPI file at the given path instead of one file per proto file.
+func WithPath(path string) Option {
+	return withOptions(options.WithPath(path))
+}
+
+// WithProtoNames uses protobuf field names instead of JSON names.
+func WithProtoNames(enabled bool) Option {
+	return withOptions(options.WithProtoNames(enabled))
+}
+
+// WithInlineEnums copies the values of an enum into every field of that enum instead of referencing a shared
+// schema.
+func WithInlineEnums(enabled bool) Option {
+	return withOptions(options.WithInlineEnums(enabled))
+}
+
+// WithTrimUnusedTypes removes types that aren't referenced by a service.
+func WithTrimUnusedTypes(enabled bool) Option {
+	return withOptions(options.WithTrimUnusedTypes(enabled))
+}
+
+// WithoutDefaultTags prevents adding default tags to converted fields.
+func WithoutDefaultTags(enabled bool) Option {
+	return withOptions(options.WithoutDefaultTags(enabled))
+}
+
+// WithManifest writes a JSON file with the given name that lists every generated file with its SHA-256 hash.
+func WithManifest(name string) Option {
+	return withOptions(options.WithManifest(name))
 }
@@ -1,14 +1,19 @@
 package converter
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	elizav1 "buf.build/gen/go/connectrpc/eliza/protocolbuffers/go/connectrpc/eliza/v1"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -69,3 +74,78 @@ func TestGenerate(t *testing.T) {
 	require.Len(t, outFiles, 1)
 	assert.Greater(t, len(*outFiles[0].Content), 4000)
 }
+
+type testTransformer struct {
+	schemas []string
+}
+
+func (t *testTransformer) TransformSchema(schema *base.Schema, desc protoreflect.MessageDescriptor) *base.Schema {
+	t.schemas = append(t.schemas, string(desc.FullName()))
+	schema.Title = "Custom" + string(desc.Name())
+	return schema
+}
+
+func (t *testTransformer) TransformDocument(doc *v3.Document) error {
+	doc.Info.Version = "transformed"
+	return nil
+}
+
+type failingTransformer struct{}
+
+func (failingTransformer) TransformDocument(doc *v3.Document) error {
+	return errors.New("boom")
+}
+
+func TestTransformers(t *testing.T) {
+	files := new(protoregistry.Files)
+	require.NoError(t, files.RegisterFile(elizav1.File_connectrpc_eliza_v1_eliza_proto))
+
+	transformer := &testTransformer{}
+	b, err := GenerateSingle(WithFiles(files), WithTransformer(transformer))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "version: transformed")
+	assert.Contains(t, string(b), "title: CustomSayRequest")
+	assert.Contains(t, transformer.schemas, "connectrpc.eliza.v1.SayRequest")
+
+	_, err = GenerateSingle(WithFiles(files), WithTransformer(failingTransformer{}))
+	assert.ErrorContains(t, err, "boom")
+
+	_, err = GenerateSingle(WithFiles(files), WithTransformer("not a transformer"))
+	assert.ErrorContains(t, err, "is not a DocumentTransformer or SchemaTransformer")
+}
+
+type upperHumanizer struct{}
+
+func (upperHumanizer) HumanizeTag(service protoreflect.ServiceDescriptor) string {
+	return strings.ToUpper(string(service.Name()))
+}
+
+func TestTagDisplayNames(t *testing.T) {
+	files := new(protoregistry.Files)
+	require.NoError(t, files.RegisterFile(elizav1.File_connectrpc_eliza_v1_eliza_proto))
+
+	b, err := GenerateSingle(WithFiles(files), WithTagDisplayNames(nil))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "x-displayName: Eliza")
+
+	b, err = GenerateSingle(WithFiles(files), WithTagDisplayNames(upperHumanizer{}))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "x-displayName: ELIZASERVICE")
+}
+
+func TestMessageToSchema(t *testing.T) {
+	desc := (&elizav1.SayRequest{}).ProtoReflect().Descriptor()
+	schema, err := MessageToSchema(desc, options.NewOptions())
+	require.NoError(t, err)
+	assert.Equal(t, "SayRequest", schema.Title)
+	assert.Equal(t, []string{"object"}, schema.Type)
+	prop, ok := schema.Properties.Get("sentence")
+	require.True(t, ok)
+	assert.Equal(t, []string{"string"}, prop.Schema().Type)
+
+	opts := options.NewOptions()
+	require.NoError(t, opts.AddTransformer(&testTransformer{}))
+	schema, err = MessageToSchema(desc, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "CustomSayRequest", schema.Title)
+}
@@ -0,0 +1,73 @@
+package options
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// LogFormat is the format of log entries.
+type LogFormat string
+
+const (
+	// LogFormatText writes log entries as text. Entries written to stderr are colored.
+	LogFormatText LogFormat = "text"
+	// LogFormatJSON writes every log entry as a JSON object on its own line, for log collectors.
+	LogFormatJSON LogFormat = "json"
+)
+
+// LogConfig is where the plugin logs to, in which format and from which level.
+type LogConfig struct {
+	// Level is the minimum level of the entries that are logged.
+	Level slog.Level
+	// Format is the format of log entries. Defaults to LogFormatText.
+	Format LogFormat
+	// File is the path of a file that log entries are appended to instead of stderr, so they aren't interleaved with
+	// the output of protoc.
+	File string
+}
+
+// logKeys are the keys of the log parameter.
+var logKeys = []string{"level", "format", "file"}
+
+// ParseLogConfig parses a semicolon-separated list of keys and values, like "level:debug;format:json;file:gen.log".
+// Keys that aren't given keep their defaults: level info, format text and stderr.
+func ParseLogConfig(s string) (*LogConfig, error) {
+	cfg := &LogConfig{Level: slog.LevelInfo, Format: LogFormatText}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("log should be a semicolon-separated list of %s with their values, like level:debug, not '%s'", strings.Join(logKeys, ", "), part)
+		}
+		switch key {
+		case "level":
+			if err := cfg.Level.UnmarshalText([]byte(value)); err != nil {
+				return nil, fmt.Errorf("log level should be debug, info, warn or error, not '%s'", value)
+			}
+		case "format":
+			switch format := LogFormat(value); format {
+			case LogFormatText, LogFormatJSON:
+				cfg.Format = format
+			default:
+				return nil, fmt.Errorf("log format should be %s or %s, not '%s'", LogFormatText, LogFormatJSON, value)
+			}
+		case "file":
+			cfg.File = value
+		default:
+			return nil, fmt.Errorf("log key should be one of %s, not '%s'", strings.Join(logKeys, ", "), key)
+		}
+	}
+	return cfg, nil
+}
+
+// isLogPart returns true for a plugin parameter like "format:json" that continues the log parameter before it, since
+// the parts of the log parameter can also be separated with commas.
+func isLogPart(param string) bool {
+	key, _, ok := strings.Cut(param, ":")
+	return ok && slices.Contains(logKeys, key)
+}
@@ -0,0 +1,811 @@
+package options
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Option changes Options. Options are applied in order, so a later option overrides an earlier one.
+type Option func(*Options) error
+
+// New returns the default options with opts applied. It returns an error when an option is invalid or when the
+// options conflict with each other, like the plugin does for its parameters.
+func New(opts ...Option) (Options, error) {
+	o := NewOptions()
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return o, err
+		}
+	}
+	if err := o.Validate(); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// WithParameters applies plugin parameters, written like they're given to the plugin: a comma-separated list like
+// "allow-get,format=json".
+func WithParameters(s string) Option {
+	return func(opts *Options) error {
+		return opts.setParameters(s)
+	}
+}
+
+// WithAllowUnknownParams logs a warning for parameters given to WithParameters that aren't known instead of
+// failing. It applies to the parameters that come after it.
+func WithAllowUnknownParams(allow bool) Option {
+	return func(opts *Options) error {
+		opts.AllowUnknownParams = allow
+		return nil
+	}
+}
+
+// WithLog sets where the plugin logs to, in which format and from which level, like
+// "level:debug;format:json;file:gen.log". A debug level also enables the debug logging of WithDebug.
+func WithLog(config string) Option {
+	return func(opts *Options) error {
+		cfg, err := ParseLogConfig(config)
+		if err != nil {
+			return err
+		}
+		opts.Log = cfg
+		if cfg.Level <= slog.LevelDebug {
+			opts.Debug = true
+		}
+		return nil
+	}
+}
+
+// WithKeepGoing skips the files that fail to convert, after logging why, instead of failing the whole generation.
+// It can't be used with WithPath.
+func WithKeepGoing(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.KeepGoing = enabled
+		return nil
+	}
+}
+
+// WithFormat sets the format of the OpenAPI files: "yaml" or "json". The format is checked by Validate.
+func WithFormat(format string) Option {
+	return func(opts *Options) error {
+		opts.Format = format
+		return nil
+	}
+}
+
+// WithBaseOpenAPI sets a file to use as a base for all OpenAPI files.
+func WithBaseOpenAPI(baseOpenAPI []byte) Option {
+	return func(opts *Options) error {
+		opts.BaseOpenAPI = baseOpenAPI
+		return nil
+	}
+}
+
+// WithAllowGET documents methods with `idempotency_level = NO_SIDE_EFFECTS` with GET requests.
+func WithAllowGET(allowGet bool) Option {
+	return func(opts *Options) error {
+		opts.AllowGET = allowGet
+		return nil
+	}
+}
+
+// WithContentTypes sets the content types of requests and responses. Available values are in Protocols.
+func WithContentTypes(contentTypes ...string) Option {
+	return func(opts *Options) error {
+		opts.ContentTypes = map[string]struct{}{}
+		for _, contentType := range contentTypes {
+			if !IsValidContentType(contentType) {
+				return fmt.Errorf("unknown content type: '%s'", contentType)
+			}
+			opts.ContentTypes[contentType] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// WithIncludeNumberEnumValues includes the numbers of enum values in addition to their names.
+func WithIncludeNumberEnumValues(includeNumberEnumValues bool) Option {
+	return func(opts *Options) error {
+		opts.IncludeNumberEnumValues = includeNumberEnumValues
+		return nil
+	}
+}
+
+// WithIgnoreGoogleapiHTTP tells the generator to ignore google.api.http options.
+func WithIgnoreGoogleapiHTTP(ignoreGoogleapiHTTP bool) Option {
+	return func(opts *Options) error {
+		opts.IgnoreGoogleapiHTTP = ignoreGoogleapiHTTP
+		return nil
+	}
+}
+
+// WithStreaming documents the content types related to streaming.
+func WithStreaming(streaming bool) Option {
+	return func(opts *Options) error {
+		opts.WithStreaming = streaming
+		return nil
+	}
+}
+
+// WithEndStreamFrame documents the end-of-stream frame of Connect streaming responses. It needs WithStreaming.
+func WithEndStreamFrame(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithEndStreamFrame = enabled
+		return nil
+	}
+}
+
+// WithDebug sets up the logger to emit debug entries.
+func WithDebug(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.Debug = enabled
+		return nil
+	}
+}
+
+// WithProtoAnnotations adds some details about protobuf to descriptions.
+func WithProtoAnnotations(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithProtoAnnotations = enabled
+		return nil
+	}
+}
+
+// WithServices will limit the services generated.
+func WithServices(serviceNames []protoreflect.FullName) Option {
+	return func(opts *Options) error {
+		opts.Services = append(opts.Services, serviceNames...)
+		return nil
+	}
+}
+
+// WithShortServiceTags uses the short service name instead of the full name for OpenAPI tags.
+func WithShortServiceTags(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.ShortServiceTags = enabled
+		return nil
+	}
+}
+
+// WithShortOperationIds sets the operationId to shortServiceName + "_" + method short name instead of the full method name.
+func WithShortOperationIds(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.ShortOperationIds = enabled
+		return nil
+	}
+}
+
+// WithFullyQualifiedMessageNames decides if you want to use the full path in message names.
+func WithFullyQualifiedMessageNames(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.FullyQualifiedMessageNames = enabled
+		return nil
+	}
+}
+
+// WithServiceDescriptions decides if service names and their comments to be added to the end of info.description.
+func WithServiceDescriptions(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithServiceDescriptions = enabled
+		return nil
+	}
+}
+
+// WithPathPrefix prepends a given string to each HTTP path.
+func WithPathPrefix(prefix string) Option {
+	return func(opts *Options) error {
+		opts.PathPrefix = prefix
+		return nil
+	}
+}
+
+// WithGRPCSystemServices includes the gRPC health and reflection services, which are excluded by default.
+func WithGRPCSystemServices(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithGRPCSystemServices = enabled
+		return nil
+	}
+}
+
+// WithSkipImports skips the well-known google files passed to the plugin, which is the default, or generates
+// documents for them too.
+func WithSkipImports(skip bool) Option {
+	return func(opts *Options) error {
+		opts.GenerateImports = !skip
+		return nil
+	}
+}
+
+// WithConstraintDescriptions appends a human-readable summary of validation constraints to property descriptions.
+func WithConstraintDescriptions(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithConstraintDescriptions = enabled
+		return nil
+	}
+}
+
+// WithOpenAPIv2Annotations applies the grpc.gateway.protoc_gen_openapiv2.options annotations of protoc-gen-openapiv2,
+// so files that were annotated for it don't have to be annotated again.
+func WithOpenAPIv2Annotations(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithOpenAPIv2Annotations = enabled
+		return nil
+	}
+}
+
+// WithLifecycleHeaders documents the Deprecation and Sunset response headers on deprecated operations, with the
+// dates from the x-lifecycle extension of their methods.
+func WithLifecycleHeaders(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithLifecycleHeaders = enabled
+		return nil
+	}
+}
+
+// WithTraceHeaders documents the traceparent, tracestate and baggage tracing headers on every operation.
+func WithTraceHeaders(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithTraceHeaders = enabled
+		return nil
+	}
+}
+
+// WithIdempotencyKey documents an Idempotency-Key header and a 409 response on operations that have side effects.
+func WithIdempotencyKey(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithIdempotencyKey = enabled
+		return nil
+	}
+}
+
+// WithRateLimitResponses adds a 429 response with rate limiting headers to every operation.
+func WithRateLimitResponses(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithRateLimitResponses = enabled
+		return nil
+	}
+}
+
+// WithAuthResponses adds 401 and 403 responses to every operation with a security requirement.
+func WithAuthResponses(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithAuthResponses = enabled
+		return nil
+	}
+}
+
+// WithGlobalResponse adds the response with the given name from components.responses to every operation, using
+// the given status code.
+func WithGlobalResponse(code, responseName string) Option {
+	return func(opts *Options) error {
+		opts.GlobalResponses = append(opts.GlobalResponses, GlobalResponse{Code: code, Response: responseName})
+		return nil
+	}
+}
+
+// WithSummarySources sets the sources of operation summaries, which are tried in order: "comment" for the first line
+// of the method's comments, "humanized" for the humanized method name ("ListBooks" → "List books") and "name" for the
+// method name, which is the last resort.
+func WithSummarySources(sources ...string) Option {
+	return func(opts *Options) error {
+		opts.SummarySources = nil
+		for _, source := range sources {
+			switch s := SummarySource(strings.TrimSpace(source)); s {
+			case SummarySourceComment, SummarySourceHumanized, SummarySourceName:
+				opts.SummarySources = append(opts.SummarySources, s)
+			default:
+				return fmt.Errorf("summary source should be comment, humanized or name, not '%s'", source)
+			}
+		}
+		return nil
+	}
+}
+
+// WithResponseEnvelope wraps every successful JSON response in the given schema from components.schemas. The slot
+// property of the envelope, "data" when it's empty, is replaced with the actual response schema.
+func WithResponseEnvelope(schemaName, slot string) Option {
+	return func(opts *Options) error {
+		opts.ResponseEnvelope = schemaName
+		if slot != "" {
+			opts.ResponseEnvelopeSlot = slot
+		}
+		return nil
+	}
+}
+
+// WithTerse removes descriptions, titles and examples from the output.
+func WithTerse(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.Terse = enabled
+		return nil
+	}
+}
+
+// WithOverrideStrategy sets how annotations in the given category ("schema" or "operation") are combined with
+// generated content. The strategy is "merge", "replace" or "generated-wins".
+func WithOverrideStrategy(category, strategy string) Option {
+	return func(opts *Options) error {
+		strategies, err := ParseOverrideStrategies(category + ":" + strategy)
+		if err != nil {
+			return err
+		}
+		if opts.OverrideStrategies == nil {
+			opts.OverrideStrategies = map[string]OverrideStrategy{}
+		}
+		opts.OverrideStrategies[category] = strategies[category]
+		return nil
+	}
+}
+
+// WithStrict makes conflicts between annotations and generated content an error.
+func WithStrict(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.Strict = enabled
+		return nil
+	}
+}
+
+// WithSpectralCompat adjusts the output so it passes the default Spectral ruleset.
+func WithSpectralCompat(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.SpectralCompat = enabled
+		return nil
+	}
+}
+
+// WithBackstage writes a Backstage catalog-info.yaml next to every generated spec.
+func WithBackstage(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.Backstage = enabled
+		return nil
+	}
+}
+
+// WithChangelog compares the generated spec with a previous version and adds a changelog. The changelog is written
+// to a markdown file with the given name or, when the name is "description", appended to info.description.
+func WithChangelog(previous []byte, name string) Option {
+	return func(opts *Options) error {
+		opts.DiffAgainst = previous
+		opts.Changelog = name
+		return nil
+	}
+}
+
+// WithStampVersion sets info.version to the version of the previous spec given to WithChangelog, with the
+// recommended semantic version bump applied.
+func WithStampVersion(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.StampVersion = enabled
+		return nil
+	}
+}
+
+// WithVersionBump writes the recommended semantic version bump since the previous spec given to WithChangelog to a
+// JSON file with the given name.
+func WithVersionBump(name string) Option {
+	return func(opts *Options) error {
+		opts.VersionBump = name
+		return nil
+	}
+}
+
+// WithConnectValidation adds the protovalidate rules of the request message to every operation as an
+// x-connect-validation extension.
+func WithConnectValidation(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithConnectValidation = enabled
+		return nil
+	}
+}
+
+// WithFileTransfers renders methods whose request or response is a single bytes field as multipart/form-data
+// uploads and application/octet-stream downloads.
+func WithFileTransfers(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithFileTransfers = enabled
+		return nil
+	}
+}
+
+// WithFlavor sets the runtime that serves the HTTP/JSON API: "connect" (the default), "grpc-gateway" or
+// "envoy-json-transcoder".
+func WithFlavor(flavor string) Option {
+	return func(opts *Options) error {
+		f, err := ParseFlavor(flavor)
+		if err != nil {
+			return err
+		}
+		opts.Flavor = f
+		return nil
+	}
+}
+
+// WithEnvoyJWTConfig derives security schemes and the security of each operation from the contents of an Envoy
+// jwt_authn filter config.
+func WithEnvoyJWTConfig(config []byte) Option {
+	return func(opts *Options) error {
+		opts.EnvoyJWTConfig = config
+		return nil
+	}
+}
+
+// WithOIDCIssuer adds an openIdConnect security scheme with the discovery URL of the issuer and uses it as the
+// default security of the document.
+func WithOIDCIssuer(issuer string) Option {
+	return func(opts *Options) error {
+		issuer, err := ParseOIDCIssuer(issuer)
+		if err != nil {
+			return err
+		}
+		opts.OIDCIssuer = issuer
+		return nil
+	}
+}
+
+// WithMTLS makes the methods of a service, or a single method, require a TLS client certificate. The spiffeID is an
+// optional pattern for the SPIFFE ID of the certificate, like spiffe://example.com/partners/*.
+func WithMTLS(name protoreflect.FullName, spiffeID string) Option {
+	return func(opts *Options) error {
+		opts.MTLS = append(opts.MTLS, MTLSRequirement{Name: name, SPIFFEID: spiffeID})
+		return nil
+	}
+}
+
+// WithCodeSamples adds curl, connect-web and connect-go samples to every operation as an x-codeSamples extension.
+func WithCodeSamples(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithCodeSamples = enabled
+		return nil
+	}
+}
+
+// WithBufModule records the buf module and commit that the spec is generated from as an x-buf-module extension. The
+// commit can be empty. When inDescription is true, the module is also mentioned in info.description.
+func WithBufModule(name, commit string, inDescription bool) Option {
+	return func(opts *Options) error {
+		opts.BufModule = name
+		opts.BufModuleCommit = commit
+		opts.BufModuleInDescription = inDescription
+		return nil
+	}
+}
+
+// WithHTML writes a self-contained HTML page with the spec embedded next to every generated spec.
+func WithHTML(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.HTML = enabled
+		return nil
+	}
+}
+
+// WithPropertyOrder sets the order of the properties of message schemas: "declaration" for the order the fields are
+// declared in or "number" for field number order.
+func WithPropertyOrder(order string) Option {
+	return func(opts *Options) error {
+		switch o := PropertyOrder(order); o {
+		case PropertyOrderDeclaration, PropertyOrderNumber:
+			opts.PropertyOrder = o
+			return nil
+		}
+		return fmt.Errorf("property order should be declaration or number, not '%s'", order)
+	}
+}
+
+// WithTransformer adds a DocumentTransformer, a SchemaTransformer or a type that implements both to this conversion.
+// Transformers run in the order they're added, after the ones added with RegisterTransformer.
+func WithTransformer(transformer any) Option {
+	return func(opts *Options) error {
+		return opts.AddTransformer(transformer)
+	}
+}
+
+// WithPostProcessCmd pipes every generated document through a shell command, like a jq or yq script, and writes
+// what the command prints instead.
+func WithPostProcessCmd(cmd string) Option {
+	return func(opts *Options) error {
+		opts.PostProcessCmd = cmd
+		return nil
+	}
+}
+
+// WithOverlay applies an OpenAPI Overlay document to every generated document. Overlays are applied in the order
+// they're added, after everything else.
+func WithOverlay(overlay []byte) Option {
+	return func(opts *Options) error {
+		opts.Overlays = append(opts.Overlays, overlay)
+		return nil
+	}
+}
+
+// WithJSONPatch applies an RFC 6902 JSON Patch, written in JSON or YAML, to every generated document after the
+// overlays.
+func WithJSONPatch(patch []byte) Option {
+	return func(opts *Options) error {
+		opts.JSONPatches = append(opts.JSONPatches, patch)
+		return nil
+	}
+}
+
+// WithMergePatch applies an RFC 7386 JSON Merge Patch, written in JSON or YAML, to every generated document after
+// the JSON patches.
+func WithMergePatch(patch []byte) Option {
+	return func(opts *Options) error {
+		opts.MergePatches = append(opts.MergePatches, patch)
+		return nil
+	}
+}
+
+// WithInferGETFromNames treats methods without an idempotency_level that are named like a read, such as GetBook,
+// ListBooks or BatchGetBooks, as free of side effects and enables GET requests for them.
+func WithInferGETFromNames(infer bool) Option {
+	return func(opts *Options) error {
+		opts.InferGETFromNames = infer
+		if infer {
+			opts.AllowGET = true
+		}
+		return nil
+	}
+}
+
+// WithConnectPaths also documents the Connect path of methods that have google.api.http paths. The operations of
+// both route styles are linked with an x-alternate-operations extension.
+func WithConnectPaths(withConnectPaths bool) Option {
+	return func(opts *Options) error {
+		opts.WithConnectPaths = withConnectPaths
+		return nil
+	}
+}
+
+// WithVisibilityLabels sets the google.api.visibility restriction labels of the audience the spec is for. Methods and
+// services restricted to other labels are marked with x-internal: true.
+func WithVisibilityLabels(labels ...string) Option {
+	return func(opts *Options) error {
+		opts.VisibilityLabels = labels
+		return nil
+	}
+}
+
+// WithRemoveInternal removes operations marked with x-internal: true from the output, for specs that are published.
+func WithRemoveInternal(removeInternal bool) Option {
+	return func(opts *Options) error {
+		opts.RemoveInternal = removeInternal
+		return nil
+	}
+}
+
+// WithStableAnchors renames tags and operationIds to collision-free slugs, so the deep links that Redoc and Stoplight
+// build from them are URL-safe. Tags keep their original name as x-displayName.
+func WithStableAnchors(stableAnchors bool) Option {
+	return func(opts *Options) error {
+		opts.StableAnchors = stableAnchors
+		return nil
+	}
+}
+
+// WithInventory writes a file with the given name that lists every operation with its service, method, path, HTTP
+// method, idempotency and security requirements. Names that end with .csv are written as CSV, others as JSON.
+func WithInventory(name string) Option {
+	return func(opts *Options) error {
+		opts.Inventory = name
+		return nil
+	}
+}
+
+// WithInlineThreshold inlines the message schemas with fewer properties than inlineThreshold at every use, instead
+// of referencing them in components.schemas, unless they refer to themselves.
+func WithInlineThreshold(inlineThreshold int) Option {
+	return func(opts *Options) error {
+		opts.InlineThreshold = inlineThreshold
+		return nil
+	}
+}
+
+// WithMaxBodyBytes documents the maximum size of request bodies in bytes on every operation with a request body as
+// an x-max-body-bytes extension. Methods can set their own limit with the x-max-body-bytes extension.
+func WithMaxBodyBytes(maxBodyBytes int64) Option {
+	return func(opts *Options) error {
+		opts.MaxBodyBytes = maxBodyBytes
+		return nil
+	}
+}
+
+// WithTagDisplayNames adds a human-friendly x-displayName to the tag of every service, so documentation sidebars
+// don't show raw protobuf names. When humanizer is nil, "UserAccountService" becomes "User Account".
+func WithTagDisplayNames(humanizer TagHumanizer) Option {
+	return func(opts *Options) error {
+		opts.WithTagDisplayNames = true
+		opts.TagHumanizer = humanizer
+		return nil
+	}
+}
+
+// WithDescriptionFile puts a markdown file at the head of info.description, like "{package_dir}/README.md".
+// {package_dir} is replaced by the directory of each proto file and {package} by its package. Packages without the
+// file are skipped.
+func WithDescriptionFile(name string) Option {
+	return func(opts *Options) error {
+		opts.DescriptionFile = name
+		return nil
+	}
+}
+
+// WithDuplicatesReport writes a JSON file with the given name that lists messages with identical or similar schemas
+// across packages, to help find duplicated DTOs.
+func WithDuplicatesReport(name string) Option {
+	return func(opts *Options) error {
+		opts.DuplicatesReport = name
+		return nil
+	}
+}
+
+// WithPathCase converts the literal segments of every path to a case: "asis", "kebab", "snake" or "lower". Every
+// operation records its Connect path in an x-rpc-path extension.
+func WithPathCase(pathCase string) Option {
+	return func(opts *Options) error {
+		c, err := ParsePathCase(pathCase)
+		if err != nil {
+			return err
+		}
+		opts.PathCase = c
+		return nil
+	}
+}
+
+// WithDefaultResponse decides the `default` response of every operation: "error" references the error schema, "off"
+// leaves it out and "echo" references the given schema from components.schemas.
+func WithDefaultResponse(mode, schemaName string) Option {
+	return func(opts *Options) error {
+		m, err := ParseDefaultResponse(mode)
+		if err != nil {
+			return err
+		}
+		opts.DefaultResponse = m
+		opts.DefaultResponseSchema = schemaName
+		return nil
+	}
+}
+
+// WithEnumExtensions adds x-enum-varnames, x-enum-descriptions and x-ms-enum to enum schemas for code generators.
+func WithEnumExtensions(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithEnumExtensions = enabled
+		return nil
+	}
+}
+
+// WithStructDocs documents google.protobuf.Struct and google.protobuf.Value as recursive JSON values, with an example
+// and a note about the nesting, instead of free-form objects.
+func WithStructDocs(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithStructDocs = enabled
+		return nil
+	}
+}
+
+// WithFeaturesReport writes a JSON file with the given name that lists the features of the input that change the
+// output, like streaming methods, google.api.http rules or protovalidate rules, with the options that go with them.
+func WithFeaturesReport(name string) Option {
+	return func(opts *Options) error {
+		opts.FeaturesReport = name
+		return nil
+	}
+}
+
+// WithWhy logs which stage of the converter, and the option behind it, added, changed or removed an element of the
+// generated documents, like components.schemas.connect.error or a JSON pointer like /paths/~1v1~1books/get.
+func WithWhy(element string) Option {
+	return func(opts *Options) error {
+		opts.Why = element
+		return nil
+	}
+}
+
+// WithRouteTable writes a JSON file with the given name that lists the Connect procedure and the google.api.http
+// rules of every method with its request and response types, for runtimes like vanguard-go.
+func WithRouteTable(name string) Option {
+	return func(opts *Options) error {
+		opts.RouteTable = name
+		return nil
+	}
+}
+
+// WithJSONSchemaDialect sets jsonSchemaDialect to "oas", "2020-12" or the URI of a dialect, and rewrites the schema
+// keywords that the dialect doesn't define.
+func WithJSONSchemaDialect(dialect string) Option {
+	return func(opts *Options) error {
+		uri, err := ParseJSONSchemaDialect(dialect)
+		if err != nil {
+			return err
+		}
+		opts.JSONSchemaDialect = uri
+		return nil
+	}
+}
+
+// WithValidationErrors adds a 400 response with a google.rpc.BadRequest detail to every operation whose request
+// message has protovalidate rules. The field of each violation is restricted to the constrained fields.
+func WithValidationErrors(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithValidationErrors = enabled
+		return nil
+	}
+}
+
+// WithAIPs documents the conventions of the given Google API Improvement Proposals, like "132" for the fields of
+// standard List methods or "154" for the etags and conditional requests of AIP-154.
+func WithAIPs(numbers ...string) Option {
+	return func(opts *Options) error {
+		if opts.AIPs == nil {
+			opts.AIPs = map[string]struct{}{}
+		}
+		for _, number := range numbers {
+			if !slices.Contains(SupportedAIPs, number) {
+				return fmt.Errorf("unsupported AIP: %s", number)
+			}
+			opts.AIPs[number] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// WithSplitByTag writes the paths of every tag to a separate document, with the components they use. The output
+// file becomes an index whose paths reference those documents.
+func WithSplitByTag(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.SplitByTag = enabled
+		return nil
+	}
+}
+
+// WithPath writes every file to a single OpenAPI file at the given path instead of one file per proto file.
+func WithPath(path string) Option {
+	return func(opts *Options) error {
+		opts.Path = path
+		return nil
+	}
+}
+
+// WithProtoNames uses protobuf field names instead of JSON names.
+func WithProtoNames(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithProtoNames = enabled
+		return nil
+	}
+}
+
+// WithInlineEnums copies the values of an enum into every field of that enum instead of referencing a shared
+// schema.
+func WithInlineEnums(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.InlineEnums = enabled
+		return nil
+	}
+}
+
+// WithTrimUnusedTypes removes types that aren't referenced by a service.
+func WithTrimUnusedTypes(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.TrimUnusedTypes = enabled
+		return nil
+	}
+}
+
+// WithoutDefaultTags prevents adding default tags to converted fields.
+func WithoutDefaultTags(enabled bool) Option {
+	return func(opts *Options) error {
+		opts.WithoutDefaultTags = enabled
+		return nil
+	}
+}
+
+// WithManifest writes a JSON file with the given name that lists every generated file with its SHA-256 hash.
+func WithManifest(name string) Option {
+	return func(opts *Options) error {
+		opts.Manifest = name
+		return nil
+	}
+}
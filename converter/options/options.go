@@ -0,0 +1,1028 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/conversion"
+)
+
+type Options struct {
+	// Format is either 'yaml' or 'json' and is the format of the output OpenAPI file(s).
+	Format string
+	// BaseOpenAPI is the file contents of a base OpenAPI file.
+	BaseOpenAPI []byte
+	// WithStreaming will content types related to streaming (warning: can be messy).
+	WithStreaming bool
+	// WithEndStreamFrame documents the `connect.end-stream` frame that ends Connect streaming responses.
+	WithEndStreamFrame bool
+	// AllowGET will let methods with `idempotency_level = NO_SIDE_EFFECTS` to be documented with GET requests.
+	AllowGET bool
+	// WithConnectPaths also documents the Connect path of methods that have google.api.http paths.
+	WithConnectPaths bool
+	// InferGETFromNames treats methods without an `idempotency_level` that are named like a read, such as GetBook,
+	// ListBooks or BatchGetBooks, as free of side effects. It implies AllowGET.
+	InferGETFromNames bool
+	// ContentTypes is a map of all content types. Available values are in Protocols.
+	ContentTypes map[string]struct{}
+	// AllowUnknownParams logs a warning for plugin parameters that aren't known instead of failing, for build setups
+	// that pass the same parameters to several versions of the plugin.
+	AllowUnknownParams bool
+	// KeepGoing skips the files that fail to convert, after logging why, instead of failing the whole generation.
+	// It can't be used with Path.
+	KeepGoing bool
+	// Debug enables debug logging if set to true.
+	Debug bool
+	// Log is where the plugin logs to, in which format and from which level. When it's nil, warnings and errors are
+	// written to stderr.
+	Log *LogConfig
+	// IncludeNumberEnumValues indicates if numbers are included for enum values in addition to the string representations.
+	IncludeNumberEnumValues bool
+	// InlineEnums copies the values of an enum into every field of that enum instead of referencing a shared schema
+	// in components.schemas.
+	InlineEnums bool
+	// InlineThreshold inlines the message schemas with fewer properties than this at every use, instead of
+	// referencing them in components.schemas, unless they refer to themselves. Zero keeps every schema.
+	InlineThreshold int
+	// WithEnumExtensions adds x-enum-varnames, x-enum-descriptions and x-ms-enum to enum schemas, so code generators
+	// name the constants after the enum values and document them.
+	WithEnumExtensions bool
+	// WithStructDocs documents google.protobuf.Struct and google.protobuf.Value as recursive JSON values, with an
+	// example and a note about the nesting, instead of free-form objects.
+	WithStructDocs bool
+	// WithProtoNames indicates if protobuf field names should be used instead of JSON names.
+	WithProtoNames bool
+	// Path is the output OpenAPI path.
+	Path string
+	// PathCase is the case of the literal segments of every path. Defaults to PathCaseAsIs.
+	PathCase PathCase
+	// PathPrefix is a prefix that is prepended to every HTTP path.
+	PathPrefix string
+	// TrimUnusedTypes will remove types that aren't referenced by a service.
+	TrimUnusedTypes bool
+	// WithProtoAnnotations will add some protobuf annotations for descriptions
+	WithProtoAnnotations bool
+	// FullyQualifiedMessageNames uses the full path for message types: {pkg}.{name} instead of just the name. This
+	// is helpful if you are mixing types from multiple services.
+	FullyQualifiedMessageNames bool
+	// Prevents adding default tags to converted fields
+	WithoutDefaultTags bool
+	// DescriptionFile is the path of a markdown file that is put at the head of info.description, like
+	// {package_dir}/README.md. {package_dir} is the directory of the proto file and {package} is its package.
+	// Packages without the file are skipped.
+	DescriptionFile string
+	// WithServiceDescriptions set to true will cause service names and their comments to be added to the end of info.description.
+	WithServiceDescriptions bool
+	// IgnoreGoogleapiHTTP set to true will cause service to always generate OpenAPI specs for connect endpoints, and ignore any google.api.http options.
+	IgnoreGoogleapiHTTP bool
+	// Services filters which services will be used for generating OpenAPI spec.
+	Services []protoreflect.FullName
+	// ShortServiceTags uses the short service name (Name()) instead of the full name (FullName()) for OpenAPI tags.
+	ShortServiceTags bool
+	// ShortOperationIds sets the operationId to shortServiceName + "_" + method short name instead of the full method name.
+	ShortOperationIds bool
+	// GenerateImports generates documents for the well-known google files passed to the plugin, like
+	// google/api/annotations.proto, which is set with skip-imports=false. By default, these files are skipped and the
+	// types they define are only included in the documents that reference them.
+	GenerateImports bool
+	// WithConstraintDescriptions appends a human-readable summary of validation constraints to property descriptions.
+	WithConstraintDescriptions bool
+	// WithTraceHeaders documents the traceparent, tracestate and baggage tracing headers on every operation.
+	WithTraceHeaders bool
+	// WithLifecycleHeaders documents the Deprecation and Sunset response headers on deprecated operations, with the
+	// dates from the x-lifecycle extension of their methods.
+	WithLifecycleHeaders bool
+	// WithOpenAPIv2Annotations applies the grpc.gateway.protoc_gen_openapiv2.options annotations of
+	// protoc-gen-openapiv2, so files that were annotated for it don't have to be annotated again.
+	WithOpenAPIv2Annotations bool
+	// WithIdempotencyKey documents an Idempotency-Key header and a 409 response on operations that have side effects.
+	WithIdempotencyKey bool
+	// MaxBodyBytes is the maximum size of request bodies in bytes, which is documented on every operation with a
+	// request body. Methods can set their own limit with the x-max-body-bytes extension.
+	MaxBodyBytes int64
+	// WithRateLimitResponses adds a 429 response with rate limiting headers to every operation.
+	WithRateLimitResponses bool
+	// WithAuthResponses adds 401 and 403 responses to every operation with a security requirement.
+	WithAuthResponses bool
+	// GlobalResponses are responses from components.responses in the base OpenAPI file that are added to every
+	// operation.
+	GlobalResponses []GlobalResponse
+	// SummarySources are the sources of operation summaries, tried in order until one of them has a summary. A
+	// summary from annotations always wins, and the method name is the last resort.
+	SummarySources []SummarySource
+	// WithTagDisplayNames adds a human-friendly x-displayName, made by TagHumanizer, to the tag of every service.
+	WithTagDisplayNames bool
+	// TagHumanizer makes the display names of tags for WithTagDisplayNames. When it's nil, "UserAccountService"
+	// becomes "User Account".
+	TagHumanizer TagHumanizer
+	// JSONSchemaDialect is the jsonSchemaDialect of the document. Schema keywords that the dialect doesn't define are
+	// rewritten or removed.
+	JSONSchemaDialect string
+	// DefaultResponse decides the `default` response of every operation. Defaults to DefaultResponseError.
+	DefaultResponse DefaultResponse
+	// DefaultResponseSchema is the schema in components.schemas that default responses reference with
+	// DefaultResponseEcho.
+	DefaultResponseSchema string
+	// ResponseEnvelope is the name of a schema in components.schemas that wraps every successful JSON response.
+	ResponseEnvelope string
+	// ResponseEnvelopeSlot is the property of ResponseEnvelope that holds the actual response. Defaults to "data".
+	ResponseEnvelopeSlot string
+	// Terse removes descriptions, titles and examples from the output to produce the smallest spec that still
+	// describes the shape of every request and response.
+	Terse bool
+	// SpectralCompat adds placeholders and prunes unused components so the output passes the default Spectral
+	// ruleset without exceptions.
+	SpectralCompat bool
+	// VisibilityLabels are the google.api.visibility restriction labels of the audience the spec is for. Operations
+	// restricted to other labels are marked with x-internal.
+	VisibilityLabels []string
+	// RemoveInternal removes operations marked with x-internal instead of leaving it to documentation portals to hide
+	// them.
+	RemoveInternal bool
+	// StableAnchors renames tags and operationIds to slugs so the deep links of documentation viewers are stable and
+	// URL-safe. Tags keep their original name as x-displayName.
+	StableAnchors bool
+	// Backstage writes a Backstage catalog-info.yaml next to every generated spec.
+	Backstage bool
+	// HTML writes a self-contained HTML page with the spec embedded next to every generated spec.
+	HTML bool
+	// DiffAgainst is the previous version of the spec, which Changelog, StampVersion and VersionBump are based on.
+	DiffAgainst []byte
+	// Changelog is the name of a markdown file with the changes since DiffAgainst. When it's "description", the
+	// changelog is appended to info.description instead.
+	Changelog string
+	// StampVersion sets info.version to the previous version with the recommended bump applied.
+	StampVersion bool
+	// VersionBump is the name of a JSON file with the recommended version bump.
+	VersionBump string
+	// Inventory is the name of an extra CSV or JSON file that lists every operation with its method, path,
+	// idempotency and security. The format follows the extension of the name.
+	Inventory string
+	// RouteTable is the name of an extra JSON file that lists the Connect procedure and the google.api.http rules of
+	// every method with its request and response types, for runtimes like vanguard-go.
+	RouteTable string
+	// DuplicatesReport is the name of an extra JSON file that lists messages with identical or similar schemas.
+	DuplicatesReport string
+	// SplitByTag writes the paths of every tag, with the components they use, to a separate document next to each
+	// output file, which becomes an index whose paths reference them. This keeps large specs small enough for
+	// browser-based viewers.
+	SplitByTag bool
+	// FeaturesReport is the name of an extra JSON file that lists the features of the input that change the output,
+	// like streaming methods or google.api.http rules, with the options that go with them.
+	FeaturesReport string
+	// Why is an element of the generated documents, like components.schemas.connect.error, whose origin is logged:
+	// which stage of the converter, and the option behind it, added, changed or removed it.
+	Why string
+	// Manifest is the name of an extra JSON file that lists every generated file with its SHA-256 hash.
+	Manifest string
+	// PostProcessCmd is a shell command that every generated document is piped through before it's written.
+	PostProcessCmd string
+	// WithConnectValidation adds the protovalidate rules of the request message to every operation as an
+	// x-connect-validation extension.
+	WithConnectValidation bool
+	// WithValidationErrors adds a 400 response with a google.rpc.BadRequest detail to every operation whose request
+	// message has protovalidate rules.
+	WithValidationErrors bool
+	// AIPs are the Google API Improvement Proposals, by number, whose conventions are documented. Available values
+	// are in SupportedAIPs.
+	AIPs map[string]struct{}
+	// WithFileTransfers renders methods whose request or response is a single bytes field as multipart/form-data
+	// uploads and application/octet-stream downloads.
+	WithFileTransfers bool
+	// BufModule is the name of the buf module the spec is generated from, like buf.build/acme/petapis.
+	BufModule string
+	// BufModuleCommit is the commit of BufModule the spec is generated from.
+	BufModuleCommit string
+	// BufModuleInDescription appends the buf module and commit to info.description.
+	BufModuleInDescription bool
+	// PropertyOrder is the order of the properties of message schemas. By default, properties are in declaration
+	// order while oneofs and their fields are sorted by name.
+	PropertyOrder PropertyOrder
+	// EnvoyJWTConfig is an Envoy jwt_authn filter config that security schemes and the security of each operation are
+	// derived from.
+	EnvoyJWTConfig []byte
+	// OIDCIssuer is the issuer URL of an OpenID Connect provider. Its discovery URL becomes the openIdConnect security
+	// scheme that's used by default.
+	OIDCIssuer string
+	// MTLS lists the services and methods that require a TLS client certificate.
+	MTLS []MTLSRequirement
+	// Overlays are OpenAPI Overlay documents that are applied to every generated document, in order.
+	Overlays [][]byte
+	// JSONPatches are RFC 6902 JSON Patches that are applied to every generated document after the overlays.
+	JSONPatches [][]byte
+	// MergePatches are RFC 7386 JSON Merge Patches that are applied to every generated document after the JSON
+	// patches.
+	MergePatches [][]byte
+	// Flavor is the runtime that serves the HTTP/JSON API. Defaults to FlavorConnect.
+	Flavor Flavor
+	// WithCodeSamples adds curl, connect-web and connect-go samples to every operation as an x-codeSamples extension.
+	WithCodeSamples bool
+	// WithGRPCSystemServices includes the gRPC health and reflection services, which are excluded by default.
+	WithGRPCSystemServices bool
+	// OverrideStrategies sets the OverrideStrategy for each category of annotation. Categories that aren't set use
+	// OverrideMerge.
+	OverrideStrategies map[string]OverrideStrategy
+	// Strict makes conflicts between annotations and generated content an error.
+	Strict bool
+	// DocumentTransformers change every generated document, in order, after all other options are applied.
+	DocumentTransformers []DocumentTransformer
+	// SchemaTransformers change the schema of every message, in order, after all annotations are applied.
+	SchemaTransformers []SchemaTransformer
+
+	MessageAnnotator        MessageAnnotator
+	FieldAnnotator          FieldAnnotator
+	FieldReferenceAnnotator FieldReferenceAnnotator
+
+	// conversion is the state of the conversion that uses the options, like the conflicts between annotations and
+	// generated content.
+	conversion *conversion.State
+}
+
+// Conversion returns the state of the conversion that uses the options, or nil outside of a conversion. The state is
+// internal to the converter and its methods can be called on nil.
+func (opts Options) Conversion() *conversion.State {
+	return opts.conversion
+}
+
+// WithConversion returns a copy of the options with the state of a conversion. It's set by the converter.
+func (opts Options) WithConversion(state *conversion.State) Options {
+	opts.conversion = state
+	return opts
+}
+
+// grpcSystemPackages are packages of services that gRPC servers commonly register alongside application services.
+var grpcSystemPackages = []protoreflect.FullName{
+	"grpc.health.v1",
+	"grpc.reflection.v1",
+	"grpc.reflection.v1alpha",
+}
+
+// IsGRPCSystemService returns true if the service is a gRPC health or reflection service.
+func IsGRPCSystemService(serviceName protoreflect.FullName) bool {
+	for _, pkg := range grpcSystemPackages {
+		if serviceName.Parent() == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// PropertyOrder is the order of the properties of a message schema.
+type PropertyOrder string
+
+const (
+	// PropertyOrderDeclaration orders properties, oneofs and their fields like the fields are declared.
+	PropertyOrderDeclaration PropertyOrder = "declaration"
+	// PropertyOrderNumber orders properties, oneofs and their fields by field number.
+	PropertyOrderNumber PropertyOrder = "number"
+)
+
+// SummarySource is a source of the summary of operations that are generated from methods.
+type SummarySource string
+
+const (
+	// SummarySourceComment is the first line of the comments of the method.
+	SummarySourceComment SummarySource = "comment"
+	// SummarySourceHumanized is the humanized method name, like "List books" for ListBooks.
+	SummarySourceHumanized SummarySource = "humanized"
+	// SummarySourceName is the method name.
+	SummarySourceName SummarySource = "name"
+)
+
+// PathCase is the case that the literal segments of paths are converted to, for REST style guides that mandate
+// kebab-case or snake_case URLs.
+type PathCase string
+
+const (
+	// PathCaseAsIs keeps paths like they're written in google.api.http rules and like the Connect protocol routes them.
+	PathCaseAsIs PathCase = "asis"
+	// PathCaseKebab converts "BookService/ListBooks" to "book-service/list-books".
+	PathCaseKebab PathCase = "kebab"
+	// PathCaseSnake converts "BookService/ListBooks" to "book_service/list_books".
+	PathCaseSnake PathCase = "snake"
+	// PathCaseLower converts "BookService/ListBooks" to "bookservice/listbooks".
+	PathCaseLower PathCase = "lower"
+)
+
+// ParsePathCase returns the path case with the given name.
+func ParsePathCase(s string) (PathCase, error) {
+	switch pathCase := PathCase(s); pathCase {
+	case PathCaseAsIs, PathCaseKebab, PathCaseSnake, PathCaseLower:
+		return pathCase, nil
+	}
+	return "", fmt.Errorf("path case should be one of %s, %s, %s or %s, not '%s'", PathCaseAsIs, PathCaseKebab, PathCaseSnake, PathCaseLower, s)
+}
+
+const (
+	// JSONSchemaDialectOAS is the dialect of OpenAPI 3.1, which is JSON Schema 2020-12 with the OpenAPI vocabulary
+	// for keywords like discriminator and example.
+	JSONSchemaDialectOAS = "https://spec.openapis.org/oas/3.1/dialect/base"
+	// JSONSchemaDialect202012 is plain JSON Schema 2020-12.
+	JSONSchemaDialect202012 = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// ParseJSONSchemaDialect returns the URI of a JSON Schema dialect. "oas" and "2020-12" are short for the OpenAPI 3.1
+// dialect and plain JSON Schema 2020-12, anything else must be the absolute URI of a dialect.
+func ParseJSONSchemaDialect(s string) (string, error) {
+	switch s {
+	case "oas":
+		return JSONSchemaDialectOAS, nil
+	case "2020-12":
+		return JSONSchemaDialect202012, nil
+	}
+	if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+		return "", fmt.Errorf("json schema dialect should be oas, 2020-12 or an absolute URI, not '%s'", s)
+	}
+	return s, nil
+}
+
+// ParseOIDCIssuer checks that an OpenID Connect issuer is an absolute http or https URL, which discovery documents are
+// served under.
+func ParseOIDCIssuer(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("oidc issuer should be an http or https URL, not '%s'", s)
+	}
+	return s, nil
+}
+
+// DefaultResponse is what the `default` response of operations documents. Some linters forbid a default response,
+// while others require one.
+type DefaultResponse string
+
+const (
+	// DefaultResponseError references the error schema of the flavor, like connect.error or google.rpc.Status.
+	DefaultResponseError DefaultResponse = "error"
+	// DefaultResponseOff leaves the default response out of every operation.
+	DefaultResponseOff DefaultResponse = "off"
+	// DefaultResponseEcho references the schema configured with DefaultResponseSchema instead of the error schema.
+	DefaultResponseEcho DefaultResponse = "echo"
+)
+
+// ParseDefaultResponse returns the default response mode with the given name.
+func ParseDefaultResponse(s string) (DefaultResponse, error) {
+	switch mode := DefaultResponse(s); mode {
+	case DefaultResponseError, DefaultResponseOff, DefaultResponseEcho:
+		return mode, nil
+	}
+	return "", fmt.Errorf("default response should be one of %s, %s or %s, not '%s'", DefaultResponseOff, DefaultResponseError, DefaultResponseEcho, s)
+}
+
+// Flavor is the runtime in front of a service, which decides how requests and errors look on the wire.
+type Flavor string
+
+const (
+	// FlavorConnect documents the Connect protocol, served by connect-go and friends or by Vanguard.
+	FlavorConnect Flavor = "connect"
+	// FlavorGRPCGateway documents the JSON API served by grpc-gateway.
+	FlavorGRPCGateway Flavor = "grpc-gateway"
+	// FlavorEnvoyJSONTranscoder documents the JSON API served by Envoy's gRPC-JSON transcoder filter.
+	FlavorEnvoyJSONTranscoder Flavor = "envoy-json-transcoder"
+)
+
+// ParseFlavor returns the flavor with the given name.
+func ParseFlavor(s string) (Flavor, error) {
+	switch flavor := Flavor(s); flavor {
+	case FlavorConnect, FlavorGRPCGateway, FlavorEnvoyJSONTranscoder:
+		return flavor, nil
+	}
+	return "", fmt.Errorf("flavor should be one of %s, %s or %s, not '%s'", FlavorConnect, FlavorGRPCGateway, FlavorEnvoyJSONTranscoder, s)
+}
+
+// IsTranscoder returns true for flavors that transcode HTTP/JSON to gRPC. Those return errors as google.rpc.Status
+// with a numeric code, write 64-bit integers as strings and don't know the Connect headers or GET encoding.
+func (f Flavor) IsTranscoder() bool {
+	return f == FlavorGRPCGateway || f == FlavorEnvoyJSONTranscoder
+}
+
+// ErrorSchemaRef returns the reference to the schema of error responses for the flavor.
+func (opts Options) ErrorSchemaRef() string {
+	if opts.Flavor.IsTranscoder() {
+		return "#/components/schemas/google.rpc.Status"
+	}
+	return "#/components/schemas/connect.error"
+}
+
+// TagHumanizer returns the name that documentation viewers show for the tag of a service.
+type TagHumanizer interface {
+	HumanizeTag(service protoreflect.ServiceDescriptor) string
+}
+
+// GlobalResponse adds the response named Response in components.responses to every operation with the status Code.
+type GlobalResponse struct {
+	Code     string
+	Response string
+}
+
+// MTLSRequirement makes the methods of a service, or a single method, require a TLS client certificate. The SPIFFE ID
+// of the certificate can be restricted with a pattern like spiffe://example.com/partners/*.
+type MTLSRequirement struct {
+	// Name is the full name of a service or a method.
+	Name     protoreflect.FullName
+	SPIFFEID string
+}
+
+// SupportedAIPs are the AIPs that can be enabled with the aip option.
+var SupportedAIPs = []string{"132", "133", "134", "135", "154", "155", "157", "158"}
+
+// HasAIP returns true if the conventions of the AIP with the given number are enabled.
+func (opts Options) HasAIP(number string) bool {
+	_, ok := opts.AIPs[number]
+	return ok
+}
+
+func (opts Options) HasService(serviceName protoreflect.FullName) bool {
+	if len(opts.Services) == 0 {
+		return opts.WithGRPCSystemServices || !IsGRPCSystemService(serviceName)
+	}
+	for _, service := range opts.Services {
+		if service == serviceName {
+			return true
+		}
+	}
+	return false
+}
+
+func NewOptions() Options {
+	return Options{
+		Format: "yaml",
+		ContentTypes: map[string]struct{}{
+			"json": {},
+		},
+		ResponseEnvelopeSlot: "data",
+		Flavor:               FlavorConnect,
+	}
+}
+
+// FromString returns the options for the given plugin parameters, which are a comma-separated list like
+// "allow-get,format=json".
+func FromString(s string) (Options, error) {
+	return New(WithParameters(s))
+}
+
+// parameter is a plugin parameter. Flags, like allow-get, don't take a value and the others, like format=json, do.
+type parameter struct {
+	flag bool
+	set  func(p *parameterParser, value string) error
+}
+
+// parameterParser is the state of setParameters, for parameters that are spread over more than one plugin parameter.
+type parameterParser struct {
+	opts         *Options
+	contentTypes map[string]struct{}
+	logParts     []string
+}
+
+func flagParameter(set func(opts *Options)) parameter {
+	return parameter{flag: true, set: func(p *parameterParser, _ string) error {
+		set(p.opts)
+		return nil
+	}}
+}
+
+func valueParameter(set func(opts *Options, value string) error) parameter {
+	return parameter{set: func(p *parameterParser, value string) error {
+		return set(p.opts, value)
+	}}
+}
+
+// parameters are the plugin parameters by name.
+var parameters = map[string]parameter{
+	"allow-unknown-params":       flagParameter(func(opts *Options) { opts.AllowUnknownParams = true }),
+	"keep-going":                 flagParameter(func(opts *Options) { opts.KeepGoing = true }),
+	"debug":                      flagParameter(func(opts *Options) { opts.Debug = true }),
+	"include-number-enum-values": flagParameter(func(opts *Options) { opts.IncludeNumberEnumValues = true }),
+	"inline-enums":               flagParameter(func(opts *Options) { opts.InlineEnums = true }),
+	"enum-extensions":            flagParameter(func(opts *Options) { opts.WithEnumExtensions = true }),
+	"with-struct-docs":           flagParameter(func(opts *Options) { opts.WithStructDocs = true }),
+	"allow-get":                  flagParameter(func(opts *Options) { opts.AllowGET = true }),
+	"infer-get-from-names": flagParameter(func(opts *Options) {
+		opts.AllowGET = true
+		opts.InferGETFromNames = true
+	}),
+	"with-streaming":                flagParameter(func(opts *Options) { opts.WithStreaming = true }),
+	"with-end-stream-frame":         flagParameter(func(opts *Options) { opts.WithEndStreamFrame = true }),
+	"with-proto-names":              flagParameter(func(opts *Options) { opts.WithProtoNames = true }),
+	"with-proto-annotations":        flagParameter(func(opts *Options) { opts.WithProtoAnnotations = true }),
+	"trim-unused-types":             flagParameter(func(opts *Options) { opts.TrimUnusedTypes = true }),
+	"fully-qualified-message-names": flagParameter(func(opts *Options) { opts.FullyQualifiedMessageNames = true }),
+	"without-default-tags":          flagParameter(func(opts *Options) { opts.WithoutDefaultTags = true }),
+	"with-service-descriptions":     flagParameter(func(opts *Options) { opts.WithServiceDescriptions = true }),
+	"ignore-googleapi-http":         flagParameter(func(opts *Options) { opts.IgnoreGoogleapiHTTP = true }),
+	"short-service-tags":            flagParameter(func(opts *Options) { opts.ShortServiceTags = true }),
+	"short-operation-ids":           flagParameter(func(opts *Options) { opts.ShortOperationIds = true }),
+	"with-constraint-descriptions":  flagParameter(func(opts *Options) { opts.WithConstraintDescriptions = true }),
+	"with-lifecycle-headers":        flagParameter(func(opts *Options) { opts.WithLifecycleHeaders = true }),
+	"with-openapiv2-annotations":    flagParameter(func(opts *Options) { opts.WithOpenAPIv2Annotations = true }),
+	"with-trace-headers":            flagParameter(func(opts *Options) { opts.WithTraceHeaders = true }),
+	"with-idempotency-key":          flagParameter(func(opts *Options) { opts.WithIdempotencyKey = true }),
+	"with-rate-limit-responses":     flagParameter(func(opts *Options) { opts.WithRateLimitResponses = true }),
+	"with-auth-responses":           flagParameter(func(opts *Options) { opts.WithAuthResponses = true }),
+	"with-tag-display-names":        flagParameter(func(opts *Options) { opts.WithTagDisplayNames = true }),
+	"strict":                        flagParameter(func(opts *Options) { opts.Strict = true }),
+	"terse":                         flagParameter(func(opts *Options) { opts.Terse = true }),
+	"spectral-compat":               flagParameter(func(opts *Options) { opts.SpectralCompat = true }),
+	"remove-internal":               flagParameter(func(opts *Options) { opts.RemoveInternal = true }),
+	"stable-anchors":                flagParameter(func(opts *Options) { opts.StableAnchors = true }),
+	"backstage":                     flagParameter(func(opts *Options) { opts.Backstage = true }),
+	"html":                          flagParameter(func(opts *Options) { opts.HTML = true }),
+	"split-by-tag":                  flagParameter(func(opts *Options) { opts.SplitByTag = true }),
+	"stamp-version":                 flagParameter(func(opts *Options) { opts.StampVersion = true }),
+	"with-connect-validation":       flagParameter(func(opts *Options) { opts.WithConnectValidation = true }),
+	"with-validation-errors":        flagParameter(func(opts *Options) { opts.WithValidationErrors = true }),
+	"with-file-transfers":           flagParameter(func(opts *Options) { opts.WithFileTransfers = true }),
+	"buf-module-in-description":     flagParameter(func(opts *Options) { opts.BufModuleInDescription = true }),
+	"with-code-samples":             flagParameter(func(opts *Options) { opts.WithCodeSamples = true }),
+	"with-connect-paths":            flagParameter(func(opts *Options) { opts.WithConnectPaths = true }),
+	"with-grpc-system-services":     flagParameter(func(opts *Options) { opts.WithGRPCSystemServices = true }),
+	"content-types": {set: func(p *parameterParser, value string) error {
+		for _, contentType := range strings.Split(value, ";") {
+			contentType = strings.TrimSpace(contentType)
+			if !IsValidContentType(contentType) {
+				return fmt.Errorf("invalid content type: '%s'", contentType)
+			}
+			p.contentTypes[contentType] = struct{}{}
+		}
+		return nil
+	}},
+	"aip": valueParameter(func(opts *Options, value string) error {
+		if opts.AIPs == nil {
+			opts.AIPs = map[string]struct{}{}
+		}
+		for _, aip := range strings.Split(value, ";") {
+			aip = strings.TrimPrefix(strings.TrimSpace(aip), "AIP-")
+			if !slices.Contains(SupportedAIPs, aip) {
+				return fmt.Errorf("aip should be a semicolon-separated list of supported AIPs (%s), not '%s'", strings.Join(SupportedAIPs, ", "), aip)
+			}
+			opts.AIPs[aip] = struct{}{}
+		}
+		return nil
+	}),
+	"global-responses": valueParameter(func(opts *Options, value string) error {
+		for _, globalResponse := range strings.Split(value, ";") {
+			code, response, ok := strings.Cut(strings.TrimSpace(globalResponse), ":")
+			if !ok || code == "" || response == "" {
+				return fmt.Errorf("global responses should be in the form {code}:{response name}, not '%s'", globalResponse)
+			}
+			opts.GlobalResponses = append(opts.GlobalResponses, GlobalResponse{Code: code, Response: response})
+		}
+		return nil
+	}),
+	"mtls": valueParameter(func(opts *Options, value string) error {
+		for _, requirement := range strings.Split(value, ";") {
+			name, spiffeID, _ := strings.Cut(strings.TrimSpace(requirement), ":")
+			if !protoreflect.FullName(name).IsValid() {
+				return fmt.Errorf("mtls should be in the form {service or method}[:{spiffe id}], not '%s'", requirement)
+			}
+			opts.MTLS = append(opts.MTLS, MTLSRequirement{Name: protoreflect.FullName(name), SPIFFEID: spiffeID})
+		}
+		return nil
+	}),
+	"json-schema-dialect": valueParameter(func(opts *Options, value string) error {
+		dialect, err := ParseJSONSchemaDialect(value)
+		if err != nil {
+			return err
+		}
+		opts.JSONSchemaDialect = dialect
+		return nil
+	}),
+	"default-response": valueParameter(func(opts *Options, value string) error {
+		name, schemaName, _ := strings.Cut(value, ":")
+		mode, err := ParseDefaultResponse(name)
+		if err != nil {
+			return err
+		}
+		if (mode == DefaultResponseEcho) != (schemaName != "") {
+			return fmt.Errorf("default response should be in the form off, error or echo:{schema name}, not '%s'", value)
+		}
+		opts.DefaultResponse = mode
+		opts.DefaultResponseSchema = schemaName
+		return nil
+	}),
+	"response-envelope": valueParameter(func(opts *Options, value string) error {
+		envelope, slot, _ := strings.Cut(value, ":")
+		if envelope == "" {
+			return fmt.Errorf("response envelope should be in the form {schema name}[:{property}], not '%s'", value)
+		}
+		opts.ResponseEnvelope = envelope
+		if slot != "" {
+			opts.ResponseEnvelopeSlot = slot
+		}
+		return nil
+	}),
+	"override-strategy": valueParameter(func(opts *Options, value string) error {
+		strategies, err := ParseOverrideStrategies(value)
+		if err != nil {
+			return err
+		}
+		opts.OverrideStrategies = strategies
+		return nil
+	}),
+	"diff-against": valueParameter(func(opts *Options, value string) error {
+		body, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		opts.DiffAgainst = body
+		return nil
+	}),
+	"changelog": valueParameter(func(opts *Options, value string) error {
+		opts.Changelog = value
+		return nil
+	}),
+	"version-bump": valueParameter(func(opts *Options, value string) error {
+		opts.VersionBump = value
+		return nil
+	}),
+	"buf-module": valueParameter(func(opts *Options, value string) error {
+		// Same form as a buf module reference: {name}[:{commit}]
+		name, commit, _ := strings.Cut(value, ":")
+		if name == "" {
+			return fmt.Errorf("buf module should be in the form {name}[:{commit}], not '%s'", value)
+		}
+		opts.BufModule = name
+		opts.BufModuleCommit = commit
+		return nil
+	}),
+	"envoy-jwt-config": valueParameter(func(opts *Options, value string) error {
+		body, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		opts.EnvoyJWTConfig = body
+		return nil
+	}),
+	"oidc-issuer": valueParameter(func(opts *Options, value string) error {
+		issuer, err := ParseOIDCIssuer(value)
+		if err != nil {
+			return err
+		}
+		opts.OIDCIssuer = issuer
+		return nil
+	}),
+	"overlay": valueParameter(func(opts *Options, value string) error {
+		body, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		opts.Overlays = append(opts.Overlays, body)
+		return nil
+	}),
+	"json-patch": valueParameter(func(opts *Options, value string) error {
+		body, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		opts.JSONPatches = append(opts.JSONPatches, body)
+		return nil
+	}),
+	"merge-patch": valueParameter(func(opts *Options, value string) error {
+		body, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		opts.MergePatches = append(opts.MergePatches, body)
+		return nil
+	}),
+	"flavor": valueParameter(func(opts *Options, value string) error {
+		flavor, err := ParseFlavor(value)
+		if err != nil {
+			return err
+		}
+		opts.Flavor = flavor
+		return nil
+	}),
+	"summary-sources": valueParameter(func(opts *Options, value string) error {
+		if err := WithSummarySources(strings.Split(value, ";")...)(opts); err != nil {
+			return err
+		}
+		return nil
+	}),
+	"skip-imports": valueParameter(func(opts *Options, value string) error {
+		skip, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("skip-imports should be true or false, not '%s'", value)
+		}
+		opts.GenerateImports = !skip
+		return nil
+	}),
+	"property-order": valueParameter(func(opts *Options, value string) error {
+		switch order := PropertyOrder(value); order {
+		case PropertyOrderDeclaration, PropertyOrderNumber:
+			opts.PropertyOrder = order
+		default:
+			return fmt.Errorf("property order should be declaration or number, not '%s'", order)
+		}
+		return nil
+	}),
+	"visibility-labels": valueParameter(func(opts *Options, value string) error {
+		for _, label := range strings.Split(value, ";") {
+			if label = strings.TrimSpace(label); label != "" {
+				opts.VisibilityLabels = append(opts.VisibilityLabels, label)
+			}
+		}
+		return nil
+	}),
+	"max-body-bytes": valueParameter(func(opts *Options, value string) error {
+		maxBodyBytes, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || maxBodyBytes <= 0 {
+			return fmt.Errorf("max body bytes should be a positive number of bytes, not '%s'", value)
+		}
+		opts.MaxBodyBytes = maxBodyBytes
+		return nil
+	}),
+	"inline-threshold": valueParameter(func(opts *Options, value string) error {
+		inlineThreshold, err := strconv.Atoi(value)
+		if err != nil || inlineThreshold <= 0 {
+			return fmt.Errorf("inline threshold should be a positive number of properties, not '%s'", value)
+		}
+		opts.InlineThreshold = inlineThreshold
+		return nil
+	}),
+	"log": {set: func(p *parameterParser, value string) error {
+		p.logParts = append(p.logParts, value)
+		return nil
+	}},
+	"description-file": valueParameter(func(opts *Options, value string) error {
+		opts.DescriptionFile = value
+		return nil
+	}),
+	"post-process-cmd": valueParameter(func(opts *Options, value string) error {
+		opts.PostProcessCmd = value
+		return nil
+	}),
+	"inventory": valueParameter(func(opts *Options, value string) error {
+		opts.Inventory = value
+		return nil
+	}),
+	"route-table": valueParameter(func(opts *Options, value string) error {
+		opts.RouteTable = value
+		return nil
+	}),
+	"duplicates-report": valueParameter(func(opts *Options, value string) error {
+		opts.DuplicatesReport = value
+		return nil
+	}),
+	"features-report": valueParameter(func(opts *Options, value string) error {
+		opts.FeaturesReport = value
+		return nil
+	}),
+	"why": valueParameter(func(opts *Options, value string) error {
+		opts.Why = value
+		return nil
+	}),
+	"manifest": valueParameter(func(opts *Options, value string) error {
+		opts.Manifest = value
+		return nil
+	}),
+	"path": valueParameter(func(opts *Options, value string) error {
+		opts.Path = value
+		return nil
+	}),
+	"path-case": valueParameter(func(opts *Options, value string) error {
+		pathCase, err := ParsePathCase(value)
+		if err != nil {
+			return err
+		}
+		opts.PathCase = pathCase
+		return nil
+	}),
+	"path-prefix": valueParameter(func(opts *Options, value string) error {
+		opts.PathPrefix = value
+		return nil
+	}),
+	"format": valueParameter(func(opts *Options, value string) error {
+		format := value
+		switch format {
+		case "yaml":
+			opts.Format = "yaml"
+		case "json":
+			opts.Format = "json"
+		default:
+			return fmt.Errorf("format be yaml or json, not '%s'", format)
+		}
+		return nil
+	}),
+	"base": valueParameter(func(opts *Options, value string) error {
+		basePath := value
+		ext := path.Ext(basePath)
+		switch ext {
+		case ".yaml", ".yml", ".json":
+			body, err := os.ReadFile(basePath)
+			if err != nil {
+				return err
+			}
+			opts.BaseOpenAPI = body
+		default:
+			return fmt.Errorf("the file extension for 'base' should end with yaml or json, not '%s'", ext)
+		}
+		return nil
+	}),
+	"services": valueParameter(func(opts *Options, value string) error {
+		services := strings.Split(value, ",")
+		for _, service := range services {
+			opts.Services = append(opts.Services, protoreflect.FullName(service))
+		}
+		return nil
+	}),
+}
+
+// setParameters applies a comma-separated list of plugin parameters. The options aren't validated.
+func (opts *Options) setParameters(s string) error {
+	p := &parameterParser{opts: opts, contentTypes: map[string]struct{}{}}
+	unknown := []error{}
+	inLog := false
+	for _, param := range strings.Split(s, ",") {
+		continuesLog := inLog
+		inLog = false
+		if param == "" {
+			continue
+		}
+		if continuesLog && isLogPart(param) {
+			p.logParts = append(p.logParts, param)
+			inLog = true
+			continue
+		}
+		name, value, hasValue := strings.Cut(param, "=")
+		known, ok := parameters[name]
+		if !ok || known.flag == hasValue {
+			unknown = append(unknown, unknownParameterError(param))
+			continue
+		}
+		if err := known.set(p, value); err != nil {
+			return err
+		}
+		inLog = name == "log"
+	}
+	if len(p.contentTypes) > 0 {
+		opts.ContentTypes = p.contentTypes
+	}
+	if len(p.logParts) > 0 {
+		if err := WithLog(strings.Join(p.logParts, ";"))(opts); err != nil {
+			return err
+		}
+	}
+	if !opts.AllowUnknownParams {
+		return errors.Join(unknown...)
+	}
+	for _, err := range unknown {
+		slog.Warn("ignoring plugin parameter", slog.Any("error", err))
+	}
+	return nil
+}
+
+// unknownParameterError explains why a plugin parameter isn't known, with the parameter that was probably meant.
+func unknownParameterError(param string) error {
+	name, _, hasValue := strings.Cut(param, "=")
+	if known, ok := parameters[name]; ok {
+		if known.flag {
+			return fmt.Errorf("invalid parameter: %s, %s doesn't take a value", param, name)
+		}
+		return fmt.Errorf("invalid parameter: %s, %s needs a value like %s={value}", param, name, name)
+	}
+	best, bestDistance := "", len(name)/3+2
+	for _, known := range slices.Sorted(maps.Keys(parameters)) {
+		if d := editDistance(name, known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	if best == "" {
+		return fmt.Errorf("invalid parameter: %s", param)
+	}
+	if hasValue && !parameters[best].flag {
+		best += "=" + param[len(name)+1:]
+	}
+	return fmt.Errorf("invalid parameter: %s, did you mean %s?", param, best)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Validate returns an error when the options are invalid or conflict with each other. The errors name the plugin
+// parameters involved. FromString and New validate the options they return.
+func (opts Options) Validate() error {
+	if opts.Format != "yaml" && opts.Format != "json" {
+		return fmt.Errorf("format should be yaml or json, not '%s'", opts.Format)
+	}
+	if len(opts.ContentTypes) == 0 {
+		return fmt.Errorf("content-types needs at least one content type")
+	}
+	for _, contentType := range slices.Sorted(maps.Keys(opts.ContentTypes)) {
+		if !IsValidContentType(contentType) {
+			return fmt.Errorf("invalid content type: '%s'", contentType)
+		}
+	}
+	for _, aip := range slices.Sorted(maps.Keys(opts.AIPs)) {
+		if !slices.Contains(SupportedAIPs, aip) {
+			return fmt.Errorf("aip should be a semicolon-separated list of supported AIPs (%s), not '%s'", strings.Join(SupportedAIPs, ", "), aip)
+		}
+	}
+	if opts.Flavor != "" {
+		if _, err := ParseFlavor(string(opts.Flavor)); err != nil {
+			return err
+		}
+	}
+	if opts.PathCase != "" {
+		if _, err := ParsePathCase(string(opts.PathCase)); err != nil {
+			return err
+		}
+	}
+	switch opts.PropertyOrder {
+	case "", PropertyOrderDeclaration, PropertyOrderNumber:
+	default:
+		return fmt.Errorf("property order should be declaration or number, not '%s'", opts.PropertyOrder)
+	}
+	if opts.KeepGoing && opts.Path != "" {
+		return fmt.Errorf("keep-going can't be used with path, a file that fails could leave part of its content in the merged document")
+	}
+	if opts.DefaultResponse != "" {
+		if _, err := ParseDefaultResponse(string(opts.DefaultResponse)); err != nil {
+			return err
+		}
+	}
+	if (opts.DefaultResponse == DefaultResponseEcho) != (opts.DefaultResponseSchema != "") {
+		return fmt.Errorf("default-response needs a schema name with echo and only with echo, like echo:{schema name}")
+	}
+	if opts.JSONSchemaDialect != "" {
+		if _, err := ParseJSONSchemaDialect(opts.JSONSchemaDialect); err != nil {
+			return err
+		}
+	}
+	for category, strategy := range opts.OverrideStrategies {
+		if _, err := ParseOverrideStrategies(category + ":" + string(strategy)); err != nil {
+			return err
+		}
+	}
+	for _, globalResponse := range opts.GlobalResponses {
+		if globalResponse.Code == "" || globalResponse.Response == "" {
+			return fmt.Errorf("global responses need a code and a response name, not '%s:%s'", globalResponse.Code, globalResponse.Response)
+		}
+	}
+	if opts.InlineThreshold < 0 {
+		return fmt.Errorf("inline threshold should be a positive number of properties, not '%d'", opts.InlineThreshold)
+	}
+	if opts.MaxBodyBytes < 0 {
+		return fmt.Errorf("max body bytes should be a positive number of bytes, not '%d'", opts.MaxBodyBytes)
+	}
+	if opts.ResponseEnvelope != "" && opts.ResponseEnvelopeSlot == "" {
+		return fmt.Errorf("response-envelope needs the property that holds the response, like %s:data", opts.ResponseEnvelope)
+	}
+	if (opts.Changelog != "" || opts.StampVersion || opts.VersionBump != "") && opts.DiffAgainst == nil {
+		return fmt.Errorf("changelog, stamp-version and version-bump need the previous version of the spec with diff-against")
+	}
+	if opts.SplitByTag && opts.DiffAgainst != nil {
+		return fmt.Errorf("split-by-tag can't be used with diff-against")
+	}
+	if opts.BufModuleInDescription && opts.BufModule == "" {
+		return fmt.Errorf("buf-module-in-description needs the module name with buf-module")
+	}
+	if opts.Flavor.IsTranscoder() {
+		if opts.IgnoreGoogleapiHTTP {
+			return fmt.Errorf("ignore-googleapi-http can't be used with flavor=%s, which serves google.api.http routes", opts.Flavor)
+		}
+		if opts.WithConnectPaths {
+			return fmt.Errorf("with-connect-paths can't be used with flavor=%s, which doesn't serve Connect paths", opts.Flavor)
+		}
+		if _, ok := opts.ContentTypes["json"]; !ok || len(opts.ContentTypes) > 1 {
+			return fmt.Errorf("flavor=%s only supports the json content type", opts.Flavor)
+		}
+	}
+	return nil
+}
+
+func IsValidContentType(contentType string) bool {
+	for _, protocol := range Protocols {
+		if protocol.Name == contentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package options
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/conversion"
+)
+
+func TestFromStringKnownParameters(t *testing.T) {
+	for name, known := range parameters {
+		param := name
+		if !known.flag {
+			param += "=x"
+		}
+		_, err := FromString(param)
+		if err != nil {
+			assert.NotContains(t, err.Error(), "invalid parameter", name)
+		}
+	}
+}
+
+func TestFromStringUnknownParameters(t *testing.T) {
+	_, err := FromString("allow-gets")
+	require.EqualError(t, err, "invalid parameter: allow-gets, did you mean allow-get?")
+
+	_, err = FromString("path_prefix=/api")
+	require.EqualError(t, err, "invalid parameter: path_prefix=/api, did you mean path-prefix=/api?")
+
+	_, err = FromString("allow-get=true")
+	require.EqualError(t, err, "invalid parameter: allow-get=true, allow-get doesn't take a value")
+
+	_, err = FromString("format")
+	require.EqualError(t, err, "invalid parameter: format, format needs a value like format={value}")
+
+	_, err = FromString("something-else-entirely")
+	require.EqualError(t, err, "invalid parameter: something-else-entirely")
+
+	_, err = FromString("alow-get,fromat=json")
+	require.EqualError(t, err, "invalid parameter: alow-get, did you mean allow-get?\ninvalid parameter: fromat=json, did you mean format=json?")
+
+	opts, err := FromString("alow-get,allow-unknown-params,format=json")
+	require.NoError(t, err)
+	assert.True(t, opts.AllowUnknownParams)
+	assert.False(t, opts.AllowGET)
+	assert.Equal(t, "json", opts.Format)
+}
+
+func TestFromStringInvalidValues(t *testing.T) {
+	for _, tc := range []struct {
+		parameter string
+		errMsg    string
+	}{
+		{parameter: "json-schema-dialect=draft-7", errMsg: "json schema dialect should be oas, 2020-12 or an absolute URI"},
+		{parameter: "default-response=echo", errMsg: "default response should be in the form off, error or echo:{schema name}"},
+		{parameter: "default-response=sometimes", errMsg: "default response should be one of off, error or echo"},
+		{parameter: "override-strategy=paths:merge", errMsg: "override strategy category should be one of schema, operation"},
+		{parameter: "override-strategy=overwrite", errMsg: "override strategy should be merge, replace or generated-wins"},
+		{parameter: "changelog=CHANGELOG.md", errMsg: "diff-against"},
+		{parameter: "stamp-version", errMsg: "diff-against"},
+		{parameter: "max-body-bytes=4MB", errMsg: "max body bytes should be a positive number of bytes"},
+		{parameter: "path-case=camel", errMsg: "path case should be one of asis, kebab, snake or lower"},
+		{parameter: "flavor=nginx", errMsg: "flavor should be one of"},
+		{parameter: "flavor=envoy-json-transcoder,ignore-googleapi-http", errMsg: "ignore-googleapi-http"},
+		{parameter: "flavor=grpc-gateway,content-types=json;proto", errMsg: "only supports the json content type"},
+		{parameter: "oidc-issuer=auth.example.com", errMsg: "oidc issuer should be an http or https URL"},
+		{parameter: "mtls=:spiffe://example.com", errMsg: "mtls should be in the form"},
+		{parameter: "buf-module-in-description", errMsg: "buf-module"},
+		{parameter: "property-order=alphabetical", errMsg: "property order should be declaration or number"},
+		{parameter: "aip=999", errMsg: "aip should be"},
+		{parameter: "aip=132;160", errMsg: "aip should be"},
+		{parameter: "summary-sources=comment;title", errMsg: "summary source should be comment, humanized or name, not 'title'"},
+		{parameter: "skip-imports=maybe", errMsg: "skip-imports should be true or false, not 'maybe'"},
+		{parameter: "inline-threshold=none", errMsg: "inline threshold should be a positive number of properties, not 'none'"},
+	} {
+		t.Run(tc.parameter, func(t *testing.T) {
+			_, err := FromString(tc.parameter)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
+func TestFromStringFlavor(t *testing.T) {
+	opts, err := FromString("flavor=grpc-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, FlavorGRPCGateway, opts.Flavor)
+
+	opts, err = FromString("")
+	require.NoError(t, err)
+	assert.Equal(t, FlavorConnect, opts.Flavor)
+}
+
+func TestFromStringSkipImports(t *testing.T) {
+	opts, err := FromString("")
+	require.NoError(t, err)
+	assert.False(t, opts.GenerateImports)
+
+	opts, err = FromString("skip-imports=false")
+	require.NoError(t, err)
+	assert.True(t, opts.GenerateImports)
+
+	opts, err = FromString("skip-imports=true")
+	require.NoError(t, err)
+	assert.False(t, opts.GenerateImports)
+}
+
+func TestNew(t *testing.T) {
+	opts, err := New()
+	require.NoError(t, err)
+	assert.Equal(t, NewOptions(), opts)
+
+	opts, err = New(
+		WithFormat("json"),
+		WithContentTypes("json", "proto"),
+		WithFlavor("connect"),
+		WithParameters("allow-get,path-prefix=/api"),
+		WithResponseEnvelope("Envelope", ""),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "json", opts.Format)
+	assert.Equal(t, map[string]struct{}{"json": {}, "proto": {}}, opts.ContentTypes)
+	assert.True(t, opts.AllowGET)
+	assert.Equal(t, "/api", opts.PathPrefix)
+	assert.Equal(t, "data", opts.ResponseEnvelopeSlot)
+
+	_, err = New(WithFormat("toml"))
+	require.EqualError(t, err, "format should be yaml or json, not 'toml'")
+
+	_, err = New(WithContentTypes("xml"))
+	require.EqualError(t, err, "unknown content type: 'xml'")
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   []Option
+		errMsg string
+	}{
+		{
+			name:   "version bump without diff-against",
+			opts:   []Option{WithVersionBump("bump.json")},
+			errMsg: "need the previous version of the spec with diff-against",
+		},
+		{
+			name:   "split-by-tag with diff-against",
+			opts:   []Option{WithChangelog([]byte("openapi: 3.1.0"), "CHANGELOG.md"), WithSplitByTag(true)},
+			errMsg: "split-by-tag can't be used with diff-against",
+		},
+		{
+			name:   "buf-module-in-description without buf-module",
+			opts:   []Option{WithBufModule("", "", true)},
+			errMsg: "buf-module-in-description needs the module name with buf-module",
+		},
+		{
+			name:   "transcoder with ignore-googleapi-http",
+			opts:   []Option{WithFlavor("grpc-gateway"), WithIgnoreGoogleapiHTTP(true)},
+			errMsg: "ignore-googleapi-http can't be used with flavor=grpc-gateway",
+		},
+		{
+			name:   "transcoder with connect paths",
+			opts:   []Option{WithFlavor("grpc-gateway"), WithConnectPaths(true)},
+			errMsg: "with-connect-paths can't be used with flavor=grpc-gateway",
+		},
+		{
+			name:   "transcoder with proto",
+			opts:   []Option{WithFlavor("envoy-json-transcoder"), WithContentTypes("json", "proto")},
+			errMsg: "flavor=envoy-json-transcoder only supports the json content type",
+		},
+		{
+			name:   "keep-going with path",
+			opts:   []Option{WithKeepGoing(true), WithPath("openapi.yaml")},
+			errMsg: "keep-going can't be used with path",
+		},
+		{
+			name:   "echo without schema",
+			opts:   []Option{WithDefaultResponse("echo", "")},
+			errMsg: "default-response needs a schema name with echo",
+		},
+		{
+			name:   "no content types",
+			opts:   []Option{WithContentTypes()},
+			errMsg: "content-types needs at least one content type",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.opts...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+
+	opts := NewOptions()
+	opts.Flavor = "grpc"
+	err := opts.Validate()
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "flavor should be one of"))
+}
+
+func TestLogParameter(t *testing.T) {
+	opts, err := FromString("log=level:debug,format:json,file:gen.log,allow-get")
+	require.NoError(t, err)
+	require.NotNil(t, opts.Log)
+	assert.Equal(t, LogConfig{Level: slog.LevelDebug, Format: LogFormatJSON, File: "gen.log"}, *opts.Log)
+	assert.True(t, opts.Debug)
+	assert.True(t, opts.AllowGET)
+
+	opts, err = FromString("log=level:warn;file:gen.log")
+	require.NoError(t, err)
+	assert.Equal(t, LogConfig{Level: slog.LevelWarn, Format: LogFormatText, File: "gen.log"}, *opts.Log)
+	assert.False(t, opts.Debug)
+
+	_, err = FromString("log=level:loud")
+	require.EqualError(t, err, "log level should be debug, info, warn or error, not 'loud'")
+
+	_, err = FromString("log=colour:red")
+	require.EqualError(t, err, "log key should be one of level, format, file, not 'colour'")
+
+	_, err = FromString("allow-get,format:json")
+	require.Error(t, err)
+}
+
+func TestConversion(t *testing.T) {
+	opts := NewOptions()
+	assert.Nil(t, opts.Conversion())
+	state := &conversion.State{}
+	assert.Same(t, state, opts.WithConversion(state).Conversion())
+	assert.Nil(t, opts.Conversion())
+}
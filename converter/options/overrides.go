@@ -0,0 +1,71 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OverrideStrategy controls what happens when an annotation sets a value that was also generated.
+type OverrideStrategy string
+
+const (
+	// OverrideMerge lets annotated values win and merges collections like properties, required fields, tags and
+	// responses with the generated ones. This is the default.
+	OverrideMerge OverrideStrategy = "merge"
+	// OverrideReplace lets annotated values win and replaces generated collections wholesale.
+	OverrideReplace OverrideStrategy = "replace"
+	// OverrideGeneratedWins only uses annotated values for things that weren't generated.
+	OverrideGeneratedWins OverrideStrategy = "generated-wins"
+)
+
+const (
+	// OverrideCategorySchema covers (gnostic.openapi.v3.schema) and (gnostic.openapi.v3.property) annotations.
+	OverrideCategorySchema = "schema"
+	// OverrideCategoryOperation covers (gnostic.openapi.v3.operation) annotations.
+	OverrideCategoryOperation = "operation"
+)
+
+var overrideCategories = []string{OverrideCategorySchema, OverrideCategoryOperation}
+
+// OverrideStrategy returns the strategy to use for the given category.
+func (opts Options) OverrideStrategy(category string) OverrideStrategy {
+	if strategy, ok := opts.OverrideStrategies[category]; ok {
+		return strategy
+	}
+	return OverrideMerge
+}
+
+// ParseOverrideStrategies parses either a single strategy for every category, like "replace", or a
+// semicolon-separated list of categories and strategies, like "schema:replace;operation:generated-wins".
+func ParseOverrideStrategies(s string) (map[string]OverrideStrategy, error) {
+	res := map[string]OverrideStrategy{}
+	for _, part := range strings.Split(s, ";") {
+		category, strategy, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			strategy, category = category, ""
+		}
+		switch OverrideStrategy(strategy) {
+		case OverrideMerge, OverrideReplace, OverrideGeneratedWins:
+		default:
+			return nil, fmt.Errorf("override strategy should be merge, replace or generated-wins, not '%s'", strategy)
+		}
+		if category == "" {
+			for _, category := range overrideCategories {
+				res[category] = OverrideStrategy(strategy)
+			}
+			continue
+		}
+		found := false
+		for _, known := range overrideCategories {
+			if known == category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("override strategy category should be one of %s, not '%s'", strings.Join(overrideCategories, ", "), category)
+		}
+		res[category] = OverrideStrategy(strategy)
+	}
+	return res, nil
+}
@@ -0,0 +1,76 @@
+package options
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DocumentTransformer changes a generated document after every option is applied, right before it's written.
+type DocumentTransformer interface {
+	TransformDocument(doc *v3.Document) error
+}
+
+// SchemaTransformer changes the schema of a message after every annotation is applied. It can be called more than
+// once for the same message.
+type SchemaTransformer interface {
+	TransformSchema(schema *base.Schema, desc protoreflect.MessageDescriptor) *base.Schema
+}
+
+var (
+	registeredTransformersMu sync.Mutex
+	registeredTransformers   []any
+)
+
+// RegisterTransformer adds a transformer that is used by every conversion. It's meant to be called from init
+// functions of programs that wrap the plugin. It panics if the transformer implements neither DocumentTransformer nor
+// SchemaTransformer.
+func RegisterTransformer(transformer any) {
+	if !isTransformer(transformer) {
+		panic(fmt.Sprintf("options: RegisterTransformer called with %T, which is not a DocumentTransformer or SchemaTransformer", transformer))
+	}
+	registeredTransformersMu.Lock()
+	defer registeredTransformersMu.Unlock()
+	registeredTransformers = append(registeredTransformers, transformer)
+}
+
+// WithRegisteredTransformers returns the options with the registered transformers added before the transformers that
+// are already set.
+func (opts Options) WithRegisteredTransformers() Options {
+	registeredTransformersMu.Lock()
+	transformers := append([]any{}, registeredTransformers...)
+	registeredTransformersMu.Unlock()
+
+	documentTransformers, schemaTransformers := opts.DocumentTransformers, opts.SchemaTransformers
+	opts.DocumentTransformers, opts.SchemaTransformers = nil, nil
+	for _, transformer := range transformers {
+		_ = opts.AddTransformer(transformer)
+	}
+	opts.DocumentTransformers = append(opts.DocumentTransformers, documentTransformers...)
+	opts.SchemaTransformers = append(opts.SchemaTransformers, schemaTransformers...)
+	return opts
+}
+
+// AddTransformer adds a transformer for every interface it implements. It returns an error if it implements neither
+// DocumentTransformer nor SchemaTransformer.
+func (opts *Options) AddTransformer(transformer any) error {
+	if !isTransformer(transformer) {
+		return fmt.Errorf("%T is not a DocumentTransformer or SchemaTransformer", transformer)
+	}
+	if t, ok := transformer.(DocumentTransformer); ok {
+		opts.DocumentTransformers = append(opts.DocumentTransformers, t)
+	}
+	if t, ok := transformer.(SchemaTransformer); ok {
+		opts.SchemaTransformers = append(opts.SchemaTransformers, t)
+	}
+	return nil
+}
+
+func isTransformer(transformer any) bool {
+	_, isDocument := transformer.(DocumentTransformer)
+	_, isSchema := transformer.(SchemaTransformer)
+	return isDocument || isSchema
+}
@@ -10,9 +10,11 @@ require (
 	buf.build/gen/go/connectrpc/eliza/protocolbuffers/go v1.36.5-20230913231627-233fca715f49.1
 	buf.build/go/protovalidate v0.12.0
 	github.com/google/gnostic v0.7.0
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.26.3
 	github.com/lmittmann/tint v1.0.7
 	github.com/pb33f/libopenapi v0.21.10
 	github.com/pb33f/libopenapi-validator v0.4.0
+	github.com/speakeasy-api/jsonpath v0.6.1
 	github.com/stretchr/testify v1.10.0
 	google.golang.org/genproto/googleapis/api v0.0.0-20250505200425-f936aa4a68b2
 	google.golang.org/protobuf v1.36.6
@@ -29,12 +31,11 @@ require (
 	github.com/dprotaso/go-yit v0.0.0-20240618133044-5a0af90af097 // indirect
 	github.com/google/cel-go v0.25.0 // indirect
 	github.com/google/gnostic-models v0.6.9 // indirect
-	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/santhosh-tekuri/jsonschema/v6 v6.0.1 // indirect
 	github.com/sergi/go-diff v1.3.1 // indirect
-	github.com/speakeasy-api/jsonpath v0.6.1 // indirect
 	github.com/stoewer/go-strcase v1.3.0 // indirect
 	github.com/vmware-labs/yaml-jsonpath v0.3.2 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.9-0.20240815153524-6ea36470d1bd // indirect
@@ -0,0 +1,219 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// PaginationExtension is an operation extension on paginated methods with the names of the page size, page token
+// and next page token fields, and the repeated field with the results, for clients that iterate over pages.
+const PaginationExtension = "x-pagination"
+
+// IdempotencyFieldExtension marks the request_id field of a request, which makes retries of the request safe.
+const IdempotencyFieldExtension = "x-idempotency-field"
+
+const requestIDDescription = "A unique identifier for this request, like a UUID. If a request with the same request_id " +
+	"was already received, the service returns the result of that request instead of applying it again, so requests " +
+	"can be retried safely."
+
+// aipPass applies the conventions of an AIP to an operation of a method. Passes only document what the method
+// already looks like, so they can be combined in any order.
+type aipPass func(opts options.Options, method protoreflect.MethodDescriptor, httpMethod string, op *v3.Operation, isStreaming bool)
+
+// aipPasses are the passes of the AIPs in options.SupportedAIPs. AIPs that change schemas have no pass: AIP-155 marks
+// fields of the finished document with applyAIP155 and AIP-157 adds schemas to it with applyAIP157.
+var aipPasses = map[string]aipPass{
+	"132": applyAIP132,
+	"133": applyAIP133,
+	"134": applyAIP134,
+	"135": applyAIP135,
+	"154": applyETags,
+	"158": applyAIP158,
+}
+
+// applyAIPs runs the passes of the AIPs enabled with the aip option, in the order of options.SupportedAIPs.
+func applyAIPs(opts options.Options, method protoreflect.MethodDescriptor, httpMethod string, op *v3.Operation, isStreaming bool) {
+	if isStreaming {
+		return
+	}
+	for _, number := range options.SupportedAIPs {
+		if pass, ok := aipPasses[number]; ok && opts.HasAIP(number) {
+			pass(opts, method, httpMethod, op, isStreaming)
+		}
+	}
+}
+
+// applyAIP132 documents the filter, order_by and show_deleted fields of standard List methods.
+func applyAIP132(opts options.Options, method protoreflect.MethodDescriptor, _ string, op *v3.Operation, _ bool) {
+	if !isStandardMethod(method, "List") {
+		return
+	}
+	describeParameter(opts, method, op, "filter", "A filter expression that restricts the results, in the syntax of AIP-160.")
+	describeParameter(opts, method, op, "order_by", "A comma-separated list of fields to order the results by. Add \" desc\" after a field to sort it in descending order.")
+	describeParameter(opts, method, op, "show_deleted", "Also return resources that were soft-deleted.")
+	describeSuccess(op, "A page of "+strings.ToLower(resourceName(method, "List"))+".")
+}
+
+// applyAIP133 documents the ID and validate_only fields of standard Create methods.
+func applyAIP133(opts options.Options, method protoreflect.MethodDescriptor, _ string, op *v3.Operation, _ bool) {
+	if !isStandardMethod(method, "Create") {
+		return
+	}
+	describeParameter(opts, method, op, util.SnakeCase(resourceName(method, "Create"))+"_id", "The ID to use for the resource, which becomes the final component of its name.")
+	describeParameter(opts, method, op, "validate_only", "Validate the request without creating the resource.")
+	describeSuccess(op, "The created resource.")
+}
+
+// applyAIP134 documents the update_mask, allow_missing and validate_only fields of standard Update methods.
+func applyAIP134(opts options.Options, method protoreflect.MethodDescriptor, _ string, op *v3.Operation, _ bool) {
+	if !isStandardMethod(method, "Update") {
+		return
+	}
+	describeParameter(opts, method, op, "update_mask", "The fields to update. A single * updates every field, like a full replacement of the resource.")
+	describeParameter(opts, method, op, "allow_missing", "Create the resource if it doesn't exist.")
+	describeParameter(opts, method, op, "validate_only", "Validate the request without updating the resource.")
+	describeSuccess(op, "The updated resource.")
+}
+
+// applyAIP135 documents the force, allow_missing and validate_only fields of standard Delete methods.
+func applyAIP135(opts options.Options, method protoreflect.MethodDescriptor, _ string, op *v3.Operation, _ bool) {
+	if !isStandardMethod(method, "Delete") {
+		return
+	}
+	describeParameter(opts, method, op, "force", "Also delete the child resources of the resource.")
+	describeParameter(opts, method, op, "allow_missing", "Succeed without doing anything if the resource doesn't exist.")
+	describeParameter(opts, method, op, "validate_only", "Validate the request without deleting the resource.")
+	if method.Output().FullName() == "google.protobuf.Empty" {
+		describeSuccess(op, "The resource was deleted.")
+	} else {
+		describeSuccess(op, "The deleted resource.")
+	}
+}
+
+// applyAIP158 documents the page_size and page_token fields of paginated methods and adds an x-pagination
+// extension with the fields that clients need to iterate over the pages.
+func applyAIP158(opts options.Options, method protoreflect.MethodDescriptor, _ string, op *v3.Operation, _ bool) {
+	input, output := method.Input(), method.Output()
+	pageSize, pageToken := input.Fields().ByName("page_size"), input.Fields().ByName("page_token")
+	nextPageToken := output.Fields().ByName("next_page_token")
+	if pageSize == nil || pageToken == nil || nextPageToken == nil {
+		return
+	}
+	describeParameter(opts, method, op, "page_size", "The maximum number of results to return. The service may return fewer, and uses a default when this is unset or 0.")
+	describeParameter(opts, method, op, "page_token", "The nextPageToken of a previous response, to get the next page of results.")
+
+	pagination := struct {
+		PageSize      string `yaml:"pageSize"`
+		PageToken     string `yaml:"pageToken"`
+		NextPageToken string `yaml:"nextPageToken"`
+		Results       string `yaml:"results,omitempty"`
+	}{
+		PageSize:      util.MakeFieldName(opts, pageSize),
+		PageToken:     util.MakeFieldName(opts, pageToken),
+		NextPageToken: util.MakeFieldName(opts, nextPageToken),
+	}
+	for i := 0; i < output.Fields().Len(); i++ {
+		if field := output.Fields().Get(i); field.IsList() {
+			if pagination.Results != "" {
+				// The results are ambiguous, like in responses that also list unreachable locations
+				pagination.Results = ""
+				break
+			}
+			pagination.Results = util.MakeFieldName(opts, field)
+		}
+	}
+	node, err := util.ExtensionNode(pagination)
+	if err != nil {
+		return
+	}
+	op.Extensions = util.SetExtension(op.Extensions, PaginationExtension, node)
+}
+
+// applyAIP155 marks the request_id field of the requests of mutating operations like AIP-155 describes it, with
+// IdempotencyFieldExtension and a description of the retry semantics. Requests of methods without side effects don't
+// need it. The pass works on the finished document, since the schema of a message is shared by every method.
+func applyAIP155(opts options.Options, spec *v3.Document) {
+	if spec.Paths == nil || spec.Components == nil || spec.Components.Schemas == nil {
+		return
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for verb, op := range item.GetOperations().FromOldest() {
+			if op == nil {
+				continue
+			}
+			method, ok := opts.Conversion().Route(path, verb)
+			if !ok || !isMutatingOperation(opts, verb, method) {
+				continue
+			}
+			field := method.Input().Fields().ByName("request_id")
+			if field == nil || field.Kind() != protoreflect.StringKind || field.IsList() {
+				continue
+			}
+			input, ok := spec.Components.Schemas.Get(string(method.Input().FullName()))
+			if !ok || input.Schema() == nil || input.Schema().Properties == nil {
+				continue
+			}
+			property, ok := input.Schema().Properties.Get(util.MakeFieldName(opts, field))
+			if !ok || property.Schema() == nil {
+				continue
+			}
+			s := property.Schema()
+			s.Extensions = util.SetExtension(s.Extensions, IdempotencyFieldExtension, util.BoolNode(true))
+			switch {
+			case s.Description == "":
+				s.Description = requestIDDescription
+			case !strings.Contains(s.Description, requestIDDescription):
+				s.Description += "\n\n" + requestIDDescription
+			}
+		}
+	}
+}
+
+// isStandardMethod returns true if a method is named like the standard method verb, like ListBooks for List.
+func isStandardMethod(method protoreflect.MethodDescriptor, verb string) bool {
+	resource := resourceName(method, verb)
+	return resource != "" && unicode.IsUpper(rune(resource[0]))
+}
+
+// resourceName returns the name of the method without the standard method verb, like Books for ListBooks.
+func resourceName(method protoreflect.MethodDescriptor, verb string) string {
+	name, ok := strings.CutPrefix(string(method.Name()), verb)
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+// describeParameter sets the description of the query parameter of a field of the request, unless the field
+// already has a comment. Message fields, like update_mask, are matched by the parameters of their fields.
+func describeParameter(opts options.Options, method protoreflect.MethodDescriptor, op *v3.Operation, fieldName, description string) {
+	field := method.Input().Fields().ByName(protoreflect.Name(fieldName))
+	if field == nil {
+		return
+	}
+	name := util.MakeFieldName(opts, field)
+	for _, param := range op.Parameters {
+		if param == nil || param.In != "query" || param.Description != "" {
+			continue
+		}
+		if param.Name == name || strings.HasPrefix(param.Name, name+".") {
+			param.Description = description
+		}
+	}
+}
+
+// describeSuccess replaces the generic description of the 200 response.
+func describeSuccess(op *v3.Operation, description string) {
+	if op.Responses == nil || op.Responses.Codes == nil {
+		return
+	}
+	if response, ok := op.Responses.Codes.Get("200"); ok && response != nil && response.Description == "Success" {
+		response.Description = description
+	}
+}
@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"strconv"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// displayNameExtension is the name that Redoc and Stoplight show for a tag instead of the tag name.
+const displayNameExtension = "x-displayName"
+
+// slugger hands out slugs that are unique within one namespace, like tag names or operationIds.
+type slugger struct {
+	assigned map[string]string
+	used     map[string]struct{}
+}
+
+func newSlugger() *slugger {
+	return &slugger{assigned: map[string]string{}, used: map[string]struct{}{}}
+}
+
+// slug returns the slug of a name. The same name always gets the same slug, and a name whose slug is taken by
+// another name gets a numeric suffix, like "books-2".
+func (s *slugger) slug(name string) string {
+	if slug, ok := s.assigned[name]; ok {
+		return slug
+	}
+	base := util.Slugify(name)
+	if base == "" {
+		base = "default"
+	}
+	slug := base
+	for i := 2; ; i++ {
+		if _, ok := s.used[slug]; !ok {
+			break
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+	s.assigned[name] = slug
+	s.used[slug] = struct{}{}
+	return slug
+}
+
+// applyStableAnchors renames tags and operationIds to slugs, so the deep links that Redoc and Stoplight build from
+// them, like #tag/acme-v1-book-service/operation/acme-v1-book-service-list-books, only use characters that are safe
+// in a URL fragment. Tags keep their original name as x-displayName. Names are slugged in document order, so the
+// slugs are stable as long as the names are.
+func applyStableAnchors(spec *v3.Document) {
+	tags := newSlugger()
+	for _, tag := range spec.Tags {
+		if tag.Extensions == nil || tag.Extensions.GetOrZero(displayNameExtension) == nil {
+			tag.Extensions = util.SetExtension(tag.Extensions, displayNameExtension, util.StringNode(tag.Name))
+		}
+		tag.Name = tags.slug(tag.Name)
+	}
+
+	operationIds := newSlugger()
+	operations := []*v3.Operation{}
+	if spec.Paths != nil {
+		for item := range spec.Paths.PathItems.ValuesFromOldest() {
+			for op := range item.GetOperations().ValuesFromOldest() {
+				operations = append(operations, op)
+			}
+		}
+	}
+	for _, op := range operations {
+		for i, tag := range op.Tags {
+			op.Tags[i] = tags.slug(tag)
+		}
+		if op.OperationId != "" {
+			op.OperationId = operationIds.slug(op.OperationId)
+		}
+	}
+
+	// Fix everything that refers to an operationId or a tag by name
+	rename := func(node *yaml.Node, slugs *slugger) {
+		if node == nil || node.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, item := range node.Content {
+			if slug, ok := slugs.assigned[item.Value]; ok && item.Kind == yaml.ScalarNode {
+				item.Value = slug
+			}
+		}
+	}
+	renameLinks := func(links *orderedmap.Map[string, *v3.Link]) {
+		for link := range links.ValuesFromOldest() {
+			if slug, ok := operationIds.assigned[link.OperationId]; ok {
+				link.OperationId = slug
+			}
+		}
+	}
+	for _, op := range operations {
+		if op.Extensions != nil {
+			if node, ok := op.Extensions.Get(AlternateOperationsExtension); ok {
+				rename(node, operationIds)
+			}
+			if node, ok := op.Extensions.Get(gnostic.ReplacedByExtension); ok && node.Kind == yaml.ScalarNode {
+				if slug, ok := operationIds.assigned[node.Value]; ok {
+					node.Value = slug
+				}
+			}
+		}
+		if op.Responses == nil {
+			continue
+		}
+		for response := range op.Responses.Codes.ValuesFromOldest() {
+			renameLinks(response.Links)
+		}
+		if op.Responses.Default != nil {
+			renameLinks(op.Responses.Default.Links)
+		}
+	}
+	if spec.Components != nil {
+		renameLinks(spec.Components.Links)
+	}
+	if spec.Extensions != nil {
+		if groups, ok := spec.Extensions.Get("x-tagGroups"); ok && groups.Kind == yaml.SequenceNode {
+			for _, group := range groups.Content {
+				rename(tagGroupTags(group), tags)
+			}
+		}
+	}
+}
+
+// tagGroupTags returns the tags list of an x-tagGroups entry.
+func tagGroupTags(group *yaml.Node) *yaml.Node {
+	if group.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(group.Content); i += 2 {
+		if group.Content[i].Value == "tags" {
+			return group.Content[i+1]
+		}
+	}
+	return nil
+}
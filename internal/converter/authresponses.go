@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// authResponse is a response that addAuthResponses documents on secured operations.
+type authResponse struct {
+	status      string
+	description string
+	code        string
+	grpcCode    int64
+	message     string
+}
+
+var authResponses = []authResponse{
+	{
+		status:      "401",
+		description: "Unauthenticated: the request doesn't have valid credentials",
+		code:        "unauthenticated",
+		grpcCode:    16,
+		message:     "missing or invalid credentials",
+	},
+	{
+		status:      "403",
+		description: "Permission denied: the credentials aren't allowed to call this operation",
+		code:        "permission_denied",
+		grpcCode:    7,
+		message:     "permission denied",
+	},
+}
+
+// addAuthResponses adds 401 and 403 responses to every operation that needs credentials, for the
+// `with-auth-responses` option. Operations use the security requirements of the document unless they have their own,
+// and operations where the empty requirement `{}` is an alternative don't need credentials. The responses have the
+// content types of the default response of the operation, since it's the error response too.
+func addAuthResponses(opts options.Options, spec *v3.Document) {
+	for item := range spec.Paths.PathItems.ValuesFromOldest() {
+		for op := range item.GetOperations().ValuesFromOldest() {
+			if op == nil || !requiresCredentials(spec, op) {
+				continue
+			}
+			for _, auth := range authResponses {
+				setResponse(op, auth.status, &v3.Response{
+					Description: auth.description,
+					Content:     authResponseContent(opts, op, auth),
+				})
+			}
+		}
+	}
+}
+
+// requiresCredentials returns true if an operation has security requirements and none of them is empty.
+func requiresCredentials(spec *v3.Document, op *v3.Operation) bool {
+	requirements := spec.Security
+	if op.Security != nil {
+		requirements = op.Security
+	}
+	if len(requirements) == 0 {
+		return false
+	}
+	for _, requirement := range requirements {
+		if requirement == nil || requirement.ContainsEmptyRequirement || orderedmap.Len(requirement.Requirements) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func authResponseContent(opts options.Options, op *v3.Operation, auth authResponse) *orderedmap.Map[string, *v3.MediaType] {
+	errorSchema := base.CreateSchemaProxyRef(opts.ErrorSchemaRef())
+	content := orderedmap.New[string, *v3.MediaType]()
+	if op.Responses != nil && op.Responses.Default != nil && orderedmap.Len(op.Responses.Default.Content) > 0 {
+		for contentType := range op.Responses.Default.Content.KeysFromOldest() {
+			content.Set(contentType, &v3.MediaType{Schema: errorSchema})
+		}
+	} else {
+		content = util.MakeMediaTypes(opts, errorSchema, false, false)
+	}
+	for contentType, mediaType := range content.FromOldest() {
+		if strings.Contains(contentType, "json") {
+			mediaType.Example = authResponseExample(opts, auth)
+		}
+	}
+	return content
+}
+
+// authResponseExample is the error of an auth response. google.rpc.Status has the numeric gRPC code.
+func authResponseExample(opts options.Options, auth authResponse) *yaml.Node {
+	example := struct {
+		Code    any    `yaml:"code"`
+		Message string `yaml:"message"`
+	}{Code: auth.code, Message: auth.message}
+	if opts.Flavor.IsTranscoder() {
+		example.Code = auth.grpcCode
+	}
+	node, err := util.ExtensionNode(example)
+	if err != nil {
+		return nil
+	}
+	return node
+}
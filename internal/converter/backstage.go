@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+	"gopkg.in/yaml.v3"
+)
+
+// BackstageExtension is the document extension with the catalog metadata for a spec. It can be set in the base
+// file or with the (gnostic.openapi.v3.document) option of a proto file:
+//
+//	x-backstage:
+//	  owner: team-books
+//	  system: library
+//	  lifecycle: experimental
+const BackstageExtension = "x-backstage"
+
+// backstageMetadata is the catalog metadata that can be set with BackstageExtension.
+type backstageMetadata struct {
+	Name      string   `yaml:"name"`
+	Namespace string   `yaml:"namespace"`
+	Owner     string   `yaml:"owner"`
+	System    string   `yaml:"system"`
+	Lifecycle string   `yaml:"lifecycle"`
+	Tags      []string `yaml:"tags"`
+}
+
+type backstageEntity struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   backstageEntityMD `yaml:"metadata"`
+	Spec       backstageAPISpec  `yaml:"spec"`
+}
+
+type backstageEntityMD struct {
+	Name        string   `yaml:"name"`
+	Namespace   string   `yaml:"namespace,omitempty"`
+	Title       string   `yaml:"title,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+}
+
+type backstageAPISpec struct {
+	Type       string            `yaml:"type"`
+	Lifecycle  string            `yaml:"lifecycle"`
+	Owner      string            `yaml:"owner"`
+	System     string            `yaml:"system,omitempty"`
+	Definition map[string]string `yaml:"definition"`
+}
+
+// invalidBackstageNameChars matches the characters that aren't allowed in the name of a Backstage entity.
+var invalidBackstageNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// siblingPath returns the path of a file next to a spec, with the .openapi.{format} suffix of the spec replaced.
+func siblingPath(specPath, suffix string) string {
+	ext := path.Ext(specPath)
+	base := strings.TrimSuffix(specPath, ".openapi"+ext)
+	if base == specPath {
+		base = strings.TrimSuffix(specPath, ext)
+	}
+	return base + suffix
+}
+
+// backstageCatalogFile makes a Backstage API entity that points to the generated spec.
+func backstageCatalogFile(specPath string, spec *v3.Document) (*pluginpb.CodeGeneratorResponse_File, error) {
+	md := backstageMetadata{}
+	if spec.Extensions != nil {
+		if node, ok := spec.Extensions.Get(BackstageExtension); ok && node != nil {
+			if err := node.Decode(&md); err != nil {
+				return nil, fmt.Errorf("%s: invalid %s extension: %w", specPath, BackstageExtension, err)
+			}
+		}
+	}
+
+	entity := backstageEntity{
+		APIVersion: "backstage.io/v1alpha1",
+		Kind:       "API",
+		Metadata: backstageEntityMD{
+			Name:      md.Name,
+			Namespace: md.Namespace,
+			Tags:      md.Tags,
+		},
+		Spec: backstageAPISpec{
+			Type:      "openapi",
+			Lifecycle: md.Lifecycle,
+			Owner:     md.Owner,
+			System:    md.System,
+			Definition: map[string]string{
+				"$text": "./" + path.Base(specPath),
+			},
+		},
+	}
+	if spec.Info != nil {
+		entity.Metadata.Title = spec.Info.Title
+		entity.Metadata.Description = spec.Info.Description
+	}
+	if entity.Metadata.Name == "" {
+		entity.Metadata.Name = backstageName(entity.Metadata.Title)
+	}
+	if entity.Metadata.Name == "" {
+		entity.Metadata.Name = backstageName(strings.TrimSuffix(path.Base(specPath), path.Ext(specPath)))
+	}
+	if entity.Spec.Lifecycle == "" {
+		entity.Spec.Lifecycle = "production"
+	}
+	if entity.Spec.Owner == "" {
+		slog.Warn("no owner set for the Backstage catalog, set it with the x-backstage extension", slog.String("path", specPath))
+		entity.Spec.Owner = "unknown"
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(entity); err != nil {
+		return nil, err
+	}
+	name := siblingPath(specPath, ".catalog-info.yaml")
+	content := buf.String()
+	return newFile(name, content), nil
+}
+
+// backstageName turns a title into a valid entity name: letters, digits and separators, up to 63 characters.
+func backstageName(title string) string {
+	name := strings.Trim(invalidBackstageNameChars.ReplaceAllString(title, "-"), "-_.")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-_.")
+	}
+	return name
+}
@@ -0,0 +1,56 @@
+package converter
+
+import (
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// BufModuleExtension is the document extension with the buf module that the spec was generated from:
+//
+//	x-buf-module:
+//	  name: buf.build/acme/petapis
+//	  commit: 7a2b9c8d1e3f4a5b6c7d8e9f0a1b2c3d
+//	  url: https://buf.build/acme/petapis/docs/7a2b9c8d1e3f4a5b6c7d8e9f0a1b2c3d
+const BufModuleExtension = "x-buf-module"
+
+type bufModule struct {
+	Name   string `yaml:"name"`
+	Commit string `yaml:"commit,omitempty"`
+	URL    string `yaml:"url,omitempty"`
+}
+
+// applyBufModule records the buf module of the spec as an extension and, if enabled, in info.description.
+func applyBufModule(opts options.Options, spec *v3.Document) error {
+	module := bufModule{Name: opts.BufModule, Commit: opts.BufModuleCommit}
+	// Modules on the Buf Schema Registry have generated docs for every commit
+	if strings.HasPrefix(module.Name, "buf.build/") {
+		ref := module.Commit
+		if ref == "" {
+			ref = "main"
+		}
+		module.URL = "https://" + module.Name + "/docs/" + ref
+	}
+
+	node, err := util.ExtensionNode(module)
+	if err != nil {
+		return err
+	}
+	spec.Extensions = util.SetExtension(spec.Extensions, BufModuleExtension, node)
+
+	if opts.BufModuleInDescription && spec.Info != nil {
+		line := "Generated from `" + module.Name + "`"
+		if module.Commit != "" {
+			line += " at commit `" + module.Commit + "`"
+		}
+		line += "."
+		if spec.Info.Description != "" {
+			spec.Info.Description += "\n\n"
+		}
+		spec.Info.Description += line
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/types/descriptorpb"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/diff"
+)
+
+// ChangelogInDescription is the value of the changelog option that appends the changelog to info.description
+// instead of writing a separate file.
+const ChangelogInDescription = "description"
+
+// VersionBump is the content of the file written by the version-bump option.
+type VersionBump struct {
+	Bump            string `json:"bump"`
+	Breaking        bool   `json:"breaking"`
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	NextVersion     string `json:"nextVersion,omitempty"`
+}
+
+// applyDiff compares the generated spec with the previous version from the diff-against option. Depending on the
+// options, info.version is stamped with the recommended version, a changelog is added and the version bump is
+// written to a file. The spec is rendered again when it changed.
+func applyDiff(opts options.Options, spec *v3.Document, content string) (string, []*pluginpb.CodeGeneratorResponse_File, error) {
+	report, err := diff.Compare(opts.DiffAgainst, []byte(content))
+	if err != nil {
+		return "", nil, fmt.Errorf("diff-against: %w", err)
+	}
+	bump := report.Bump()
+	nextVersion := ""
+	if report.PreviousVersion != "" {
+		// A previous version that isn't semver just means there's no next version to suggest
+		nextVersion, _ = diff.NextVersion(report.PreviousVersion, bump)
+	}
+
+	rerender := false
+	if opts.StampVersion {
+		if nextVersion == "" {
+			return "", nil, fmt.Errorf("stamp-version: the previous spec needs a semantic version in info.version, not '%s'", report.PreviousVersion)
+		}
+		if spec.Info == nil {
+			spec.Info = &base.Info{}
+		}
+		spec.Info.Version = nextVersion
+		rerender = true
+	}
+
+	files := []*pluginpb.CodeGeneratorResponse_File{}
+	if opts.Changelog != "" {
+		title := "Changelog"
+		if spec.Info != nil && spec.Info.Version != "" {
+			title += " for " + spec.Info.Version
+		}
+		markdown := report.Markdown(title)
+		if opts.Changelog == ChangelogInDescription {
+			if spec.Info == nil {
+				spec.Info = &base.Info{}
+			}
+			if spec.Info.Description != "" {
+				spec.Info.Description += "\n\n"
+			}
+			spec.Info.Description += markdown
+			rerender = true
+		} else {
+			files = append(files, newFile(opts.Changelog, markdown))
+		}
+	}
+
+	if opts.VersionBump != "" {
+		b, err := json.MarshalIndent(VersionBump{
+			Bump:            string(bump),
+			Breaking:        report.HasBreakingChanges(),
+			PreviousVersion: report.PreviousVersion,
+			NextVersion:     nextVersion,
+		}, "", "  ")
+		if err != nil {
+			return "", nil, err
+		}
+		files = append(files, newFile(opts.VersionBump, string(b)+"\n"))
+	}
+
+	if rerender {
+		content, err = specToFile(opts, spec)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return content, files, nil
+}
+
+func newFile(name, content string) *pluginpb.CodeGeneratorResponse_File {
+	return &pluginpb.CodeGeneratorResponse_File{
+		Name:              &name,
+		Content:           &content,
+		GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+	}
+}
@@ -0,0 +1,518 @@
+package converter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/googleapi"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// CodeSamplesExtension is the operation extension with code samples, which Redoc shows next to each operation.
+const CodeSamplesExtension = "x-codeSamples"
+
+// defaultCodeSampleURL is used in code samples when the spec doesn't have any servers.
+const defaultCodeSampleURL = "https://api.example.com"
+
+// sampleMaxDepth limits how deep nested messages are filled in example payloads.
+const sampleMaxDepth = 3
+
+type codeSample struct {
+	Lang   string `yaml:"lang"`
+	Label  string `yaml:"label"`
+	Source string `yaml:"source"`
+}
+
+// codeSampleBaseURL returns the URL of the first server of the spec.
+func codeSampleBaseURL(spec *v3.Document) string {
+	if len(spec.Servers) > 0 && spec.Servers[0].URL != "" {
+		return strings.TrimSuffix(spec.Servers[0].URL, "/")
+	}
+	return defaultCodeSampleURL
+}
+
+// addCodeSamples adds curl, connect-web and connect-go samples to every operation of a path item. The request
+// payload is an example built from the fields of the request message. Streaming methods only get samples for the
+// clients that support them.
+func addCodeSamples(opts options.Options, method protoreflect.MethodDescriptor, baseURL, path string, item *v3.PathItem) {
+	for pair := item.GetOperations().First(); pair != nil; pair = pair.Next() {
+		op := pair.Value()
+		if op == nil {
+			continue
+		}
+		samples := []codeSample{}
+		if curl := curlSample(opts, method, baseURL, path, strings.ToUpper(pair.Key()), op); curl != "" {
+			samples = append(samples, codeSample{Lang: "Shell", Label: "curl", Source: curl})
+		}
+		if js := connectWebSample(method, baseURL); js != "" {
+			samples = append(samples, codeSample{Lang: "JavaScript", Label: "connect-web", Source: js})
+		}
+		if goSample := connectGoSample(method, baseURL); goSample != "" {
+			samples = append(samples, codeSample{Lang: "Go", Label: "connect-go", Source: goSample})
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		node, err := util.ExtensionNode(samples)
+		if err != nil {
+			continue
+		}
+		op.Extensions = util.SetExtension(op.Extensions, CodeSamplesExtension, node)
+	}
+}
+
+func curlSample(opts options.Options, method protoreflect.MethodDescriptor, baseURL, path, httpMethod string, op *v3.Operation) string {
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return ""
+	}
+	isConnectPath := strings.HasSuffix(path, "/"+string(method.Parent().FullName())+"/"+string(method.Name()))
+	body := renderSample(exampleMessage(opts, method.Input(), 0, map[protoreflect.FullName]bool{}), "", true)
+
+	lines := []string{}
+	switch {
+	case isConnectPath && httpMethod == http.MethodGet:
+		lines = append(lines,
+			"curl --get "+baseURL+path,
+			"  --data-urlencode 'encoding=json'",
+			"  --data-urlencode 'message="+compactJSON(body)+"'",
+		)
+	case isConnectPath:
+		lines = append(lines, "curl -X POST "+baseURL+path, "  -H 'Content-Type: application/json'")
+		if !opts.Flavor.IsTranscoder() {
+			lines = append(lines, "  -H 'Connect-Protocol-Version: 1'")
+		}
+		lines = append(lines, "  -d '"+body+"'")
+	default:
+		if op.Extensions != nil {
+			// Methods that OpenAPI can't describe are documented as POST, but curl can send them as they are
+			if override, ok := op.Extensions.Get(googleapi.MethodOverrideExtension); ok {
+				httpMethod = override.Value
+			}
+		}
+		lines = append(lines, "curl -X "+httpMethod+" '"+baseURL+restSamplePath(opts, method, path, op)+"'")
+		if op.RequestBody != nil {
+			lines = append(lines, "  -H 'Content-Type: application/json'", "  -d '"+requestBodySample(opts, method, op)+"'")
+		}
+	}
+	return strings.Join(lines, " \\\n") + "\n"
+}
+
+// restSamplePath fills in the path parameters of an operation that was made from a google.api.http annotation with
+// example values and adds its query parameters.
+func restSamplePath(opts options.Options, method protoreflect.MethodDescriptor, path string, op *v3.Operation) string {
+	query := []string{}
+	for _, param := range op.Parameters {
+		if param == nil {
+			continue
+		}
+		switch param.In {
+		case "path":
+			value := parameterSample(opts, method.Input(), param.Name)
+			if param.Schema != nil && !param.Schema.IsReference() && param.Schema.Schema() != nil {
+				// Transcoders bind a path variable like {name=shelves/*} to a single parameter with a pattern
+				if pattern := param.Schema.Schema().Pattern; strings.Contains(pattern, "[^/]+") {
+					value = patternSample(pattern)
+				}
+			}
+			if value == "" {
+				value = "1"
+			}
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", strings.Join(segments, "/"))
+		case "query":
+			if value := parameterSample(opts, method.Input(), param.Name); value != "" {
+				query = append(query, url.QueryEscape(param.Name)+"="+url.QueryEscape(value))
+			}
+		}
+	}
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + strings.Join(query, "&")
+}
+
+// parameterSample returns an example value for the field that a path or query parameter like "book.name" is bound
+// to, or "" when the parameter isn't bound to a scalar field.
+func parameterSample(opts options.Options, msg protoreflect.MessageDescriptor, name string) string {
+	var field protoreflect.FieldDescriptor
+	for _, part := range strings.Split(name, ".") {
+		if msg == nil {
+			return ""
+		}
+		fields := msg.Fields()
+		if field = fields.ByName(protoreflect.Name(part)); field == nil {
+			field = fields.ByJSONName(part)
+		}
+		if field == nil {
+			return ""
+		}
+		msg = field.Message()
+	}
+	value := exampleValue(opts, field, sampleMaxDepth, map[protoreflect.FullName]bool{})
+	if list, ok := value.([]any); ok && len(list) > 0 {
+		value = list[0]
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case rawSample:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// patternSample returns an example for a parameter with a pattern made from a path variable like
+// {name=shelves/*}, like shelves/1.
+func patternSample(pattern string) string {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	pattern = strings.NewReplacer("[^/]+", "1", ".+", "1").Replace(pattern)
+	return strings.ReplaceAll(pattern, `\`, "")
+}
+
+// requestBodySample returns the example payload for the request body of an operation that was made from a
+// google.api.http annotation, which can be a single field of the request message.
+func requestBodySample(opts options.Options, method protoreflect.MethodDescriptor, op *v3.Operation) string {
+	if content := op.RequestBody.Content; content != nil {
+		if mt, ok := content.Get("application/json"); ok && mt.Schema != nil && !mt.Schema.IsReference() {
+			// The schema of a body field is titled with the name of the field
+			if field := method.Input().Fields().ByName(protoreflect.Name(mt.Schema.Schema().Title)); field != nil {
+				return renderSample(exampleValue(opts, field, 0, map[protoreflect.FullName]bool{}), "", true)
+			}
+		}
+	}
+	return renderSample(exampleMessage(opts, method.Input(), 0, map[protoreflect.FullName]bool{}), "", true)
+}
+
+func connectWebSample(method protoreflect.MethodDescriptor, baseURL string) string {
+	if method.IsStreamingClient() {
+		// Browsers can't stream requests
+		return ""
+	}
+	service := method.Parent().(protoreflect.ServiceDescriptor)
+	importPath := "./gen/" + strings.TrimSuffix(service.ParentFile().Path(), ".proto") + "_pb"
+	request := renderSample(scalarFields(method.Input(), jsFieldName, jsValue), "", false)
+	call := "client." + jsFieldName(string(method.Name())) + "(" + request + ")"
+
+	var b strings.Builder
+	b.WriteString(`import { createClient } from "@connectrpc/connect";` + "\n")
+	b.WriteString(`import { createConnectTransport } from "@connectrpc/connect-web";` + "\n")
+	b.WriteString(fmt.Sprintf("import { %s } from %q;\n\n", service.Name(), importPath))
+	b.WriteString(fmt.Sprintf("const transport = createConnectTransport({ baseUrl: %q });\n", baseURL))
+	b.WriteString(fmt.Sprintf("const client = createClient(%s, transport);\n", service.Name()))
+	if method.IsStreamingServer() {
+		b.WriteString("for await (const res of " + call + ") {\n  console.log(res);\n}\n")
+	} else {
+		b.WriteString("const res = await " + call + ";\nconsole.log(res);\n")
+	}
+	return b.String()
+}
+
+func connectGoSample(method protoreflect.MethodDescriptor, baseURL string) string {
+	if method.IsStreamingClient() {
+		return ""
+	}
+	service := method.Parent().(protoreflect.ServiceDescriptor)
+	connectPkg := goPackageName(service.ParentFile()) + "connect"
+	request := "&" + goPackageName(method.Input().ParentFile()) + "." + goMessageName(method.Input())
+	fields := scalarFields(method.Input(), goCamelCase, goValue)
+	if len(fields) == 0 {
+		request += "{}"
+	} else {
+		request += "{\n"
+		for _, field := range fields {
+			request += "\t" + field.name + ": " + fmt.Sprint(field.value) + ",\n"
+		}
+		request += "}"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("client := %s.New%sClient(http.DefaultClient, %q)\n", connectPkg, service.Name(), baseURL))
+	if method.IsStreamingServer() {
+		b.WriteString(fmt.Sprintf("stream, err := client.%s(context.Background(), connect.NewRequest(%s))\n", method.Name(), request))
+		b.WriteString("if err != nil {\n\tlog.Fatal(err)\n}\nfor stream.Receive() {\n\tlog.Println(stream.Msg())\n}\n")
+		b.WriteString("if err := stream.Err(); err != nil {\n\tlog.Fatal(err)\n}\n")
+	} else {
+		b.WriteString(fmt.Sprintf("res, err := client.%s(context.Background(), connect.NewRequest(%s))\n", method.Name(), request))
+		b.WriteString("if err != nil {\n\tlog.Fatal(err)\n}\nlog.Println(res.Msg)\n")
+	}
+	return b.String()
+}
+
+// goPackageName returns the name of the Go package generated for a file, like protoc-gen-go does.
+func goPackageName(fd protoreflect.FileDescriptor) string {
+	goPackage := fd.Options().(*descriptorpb.FileOptions).GetGoPackage()
+	if _, name, ok := strings.Cut(goPackage, ";"); ok {
+		return name
+	}
+	if goPackage != "" {
+		return strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(goPackage))
+	}
+	return strings.ReplaceAll(string(fd.Package()), ".", "")
+}
+
+// goMessageName returns the name of the Go type of a message, which includes the names of enclosing messages.
+func goMessageName(msg protoreflect.MessageDescriptor) string {
+	name := goCamelCase(string(msg.Name()))
+	if parent, ok := msg.Parent().(protoreflect.MessageDescriptor); ok {
+		return goMessageName(parent) + "_" + name
+	}
+	return name
+}
+
+// goCamelCase converts a proto name to the name protoc-gen-go uses for it, with the same rules as the
+// GoCamelCase function of google.golang.org/protobuf/internal/strs.
+func goCamelCase(s string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip the dot, the next letter is capitalized
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip the underscore, the next letter is capitalized
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func jsFieldName(name string) string {
+	camel := goCamelCase(name)
+	return strings.ToLower(camel[:1]) + camel[1:]
+}
+
+// sampleField is a field of an example payload. Fields are kept in the order of the message.
+type sampleField struct {
+	name  string
+	value any
+}
+
+type sampleObject []sampleField
+
+// rawSample is written to samples as-is.
+type rawSample string
+
+// exampleMessage returns an example of the JSON form of a message. Only the first field of each oneof is set and
+// messages that are already being filled in are skipped, so recursive messages end.
+func exampleMessage(opts options.Options, msg protoreflect.MessageDescriptor, depth int, seen map[protoreflect.FullName]bool) sampleObject {
+	obj := sampleObject{}
+	if depth >= sampleMaxDepth || seen[msg.FullName()] {
+		return obj
+	}
+	seen[msg.FullName()] = true
+	defer delete(seen, msg.FullName())
+
+	setOneofs := map[protoreflect.FullName]bool{}
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			if setOneofs[oneof.FullName()] {
+				continue
+			}
+			setOneofs[oneof.FullName()] = true
+		}
+		value := exampleValue(opts, field, depth, seen)
+		if value == nil {
+			continue
+		}
+		obj = append(obj, sampleField{name: util.MakeFieldName(opts, field), value: value})
+	}
+	return obj
+}
+
+func exampleValue(opts options.Options, field protoreflect.FieldDescriptor, depth int, seen map[protoreflect.FullName]bool) any {
+	if field.IsMap() {
+		value := exampleSingular(opts, field.MapValue(), depth, seen)
+		if value == nil {
+			return nil
+		}
+		return sampleObject{{name: "key", value: value}}
+	}
+	value := exampleSingular(opts, field, depth, seen)
+	if value == nil {
+		return nil
+	}
+	if field.IsList() {
+		return []any{value}
+	}
+	return value
+}
+
+func exampleSingular(opts options.Options, field protoreflect.FieldDescriptor, depth int, seen map[protoreflect.FullName]bool) any {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch field.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			return "2024-01-01T00:00:00Z"
+		case "google.protobuf.Duration":
+			return "1s"
+		case "google.protobuf.StringValue":
+			return "string"
+		case "google.protobuf.BoolValue":
+			return true
+		}
+		if util.IsWellKnown(field.Message()) {
+			return nil
+		}
+		obj := exampleMessage(opts, field.Message(), depth+1, seen)
+		if len(obj) == 0 {
+			return nil
+		}
+		return obj
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			if values.Get(i).Number() != 0 {
+				return string(values.Get(i).Name())
+			}
+		}
+		if values.Len() > 0 {
+			return string(values.Get(0).Name())
+		}
+		return nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "0"
+	}
+	return scalarExample(field)
+}
+
+// scalarExample returns an example for fields with a string, number, bool or bytes type, or nil for other fields.
+func scalarExample(field protoreflect.FieldDescriptor) any {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return ""
+	case protoreflect.BoolKind:
+		return true
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return rawSample("0")
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return rawSample("0.0")
+	}
+	return nil
+}
+
+// scalarFields returns examples for the singular scalar fields of a message, which can be written the same way in
+// every language. Names and values are converted with the given functions.
+func scalarFields(msg protoreflect.MessageDescriptor, name func(string) string, value func(protoreflect.FieldDescriptor) any) sampleObject {
+	obj := sampleObject{}
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.IsList() || field.IsMap() {
+			continue
+		}
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			continue
+		}
+		if v := value(field); v != nil {
+			obj = append(obj, sampleField{name: name(string(field.Name())), value: v})
+		}
+	}
+	return obj
+}
+
+func jsValue(field protoreflect.FieldDescriptor) any {
+	switch field.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return rawSample("0n")
+	case protoreflect.BytesKind:
+		return rawSample("new Uint8Array()")
+	}
+	return scalarExample(field)
+}
+
+func goValue(field protoreflect.FieldDescriptor) any {
+	if field.HasPresence() && field.Kind() != protoreflect.MessageKind {
+		// Fields with presence are pointers in Go
+		return nil
+	}
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return rawSample(`"string"`)
+	case protoreflect.BytesKind:
+		return rawSample(`[]byte("")`)
+	}
+	return scalarExample(field)
+}
+
+// renderSample writes an example value as JSON, or as a JavaScript object literal when quoteKeys is false.
+func renderSample(value any, indent string, quoteKeys bool) string {
+	switch v := value.(type) {
+	case sampleObject:
+		if len(v) == 0 {
+			return "{}"
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for i, field := range v {
+			name := field.name
+			if quoteKeys {
+				name = strconv.Quote(name)
+			}
+			b.WriteString(indent + "  " + name + ": " + renderSample(field.value, indent+"  ", quoteKeys))
+			if i < len(v)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = renderSample(item, indent, quoteKeys)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case string:
+		return strconv.Quote(v)
+	case rawSample:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// compactJSON puts a rendered JSON sample on a single line.
+func compactJSON(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "")
+}
@@ -11,11 +11,13 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/yaml.v3"
 
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 )
 
-func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor) (*highv3.Components, error) {
+// fileToComponents returns the components of a file. The schemas of messages and enums in built are skipped, since
+// they're already in the document.
+func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor, built map[protoreflect.FullName]struct{}) (*highv3.Components, error) {
 	// Add schema from messages/enums
 	components := &highv3.Components{
 		Schemas:         orderedmap.New[string, *base.SchemaProxy](),
@@ -30,6 +32,7 @@ func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor) (*hi
 		Extensions:      orderedmap.New[string, *yaml.Node](),
 	}
 	st := NewState(opts)
+	st.Built = built
 	slog.Debug("start collection")
 	st.CollectFile(fd)
 	slog.Debug("collection complete", slog.String("file", string(fd.Name())), slog.Int("messages", len(st.Messages)), slog.Int("enum", len(st.Enums)))
@@ -37,6 +40,7 @@ func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor) (*hi
 
 	hasGetRequests := false
 	hasMethods := false
+	hasStreaming := false
 
 	// Add requestBodies and responses for methods
 	services := fd.Services()
@@ -49,6 +53,9 @@ func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor) (*hi
 			if hasGet {
 				hasGetRequests = true
 			}
+			if method.IsStreamingClient() || method.IsStreamingServer() {
+				hasStreaming = true
+			}
 			hasMethods = true
 		}
 	}
@@ -86,7 +93,7 @@ func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor) (*hi
 			},
 		}))
 	}
-	if hasMethods {
+	if hasMethods && !opts.Flavor.IsTranscoder() {
 		components.Schemas.Set("connect-protocol-version", base.CreateSchemaProxy(&base.Schema{
 			Title:       "Connect-Protocol-Version",
 			Description: "Define the version of the Connect protocol",
@@ -136,9 +143,69 @@ func fileToComponents(opts options.Options, fd protoreflect.FileDescriptor) (*hi
 			Type:                 []string{"object"},
 			AdditionalProperties: &base.DynamicValue[*base.SchemaProxy, bool]{N: 1, B: true},
 		}))
+	}
+	if hasStreaming && opts.WithStreaming && opts.WithEndStreamFrame && !opts.Flavor.IsTranscoder() {
+		components.Schemas.Set("connect.metadata", base.CreateSchemaProxy(&base.Schema{
+			Title:       "Connect Metadata",
+			Description: "Metadata sent at the end of a stream, like trailers in gRPC. Keys are header names and each key can have several values.",
+			Type:        []string{"object"},
+			AdditionalProperties: &base.DynamicValue[*base.SchemaProxy, bool]{
+				A: base.CreateSchemaProxy(&base.Schema{
+					Type:  []string{"array"},
+					Items: &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}})},
+				}),
+			},
+		}))
+		endStreamProps := orderedmap.New[string, *base.SchemaProxy]()
+		endStreamProps.Set("error", base.CreateSchemaProxyRef("#/components/schemas/connect.error"))
+		endStreamProps.Set("metadata", base.CreateSchemaProxyRef("#/components/schemas/connect.metadata"))
+		components.Schemas.Set("connect.end-stream", base.CreateSchemaProxy(&base.Schema{
+			Title:                "Connect End-of-Stream",
+			Description:          "The last frame of a Connect streaming response, which is always JSON. It has the error if the RPC failed and the trailing metadata of the RPC: https://connectrpc.com/docs/protocol/#error-end-stream",
+			Type:                 []string{"object"},
+			Properties:           endStreamProps,
+			AdditionalProperties: &base.DynamicValue[*base.SchemaProxy, bool]{N: 1, B: false},
+		}))
+	}
+	if hasMethods && opts.Flavor.IsTranscoder() {
+		components.Schemas.Set("google.rpc.Status", base.CreateSchemaProxy(rpcStatusSchema()))
+	}
+	if hasMethods {
+		if opts.WithValidationErrors {
+			badRequest, violation := badRequestSchemas()
+			components.Schemas.Set("google.rpc.BadRequest", base.CreateSchemaProxy(badRequest))
+			components.Schemas.Set("google.rpc.BadRequest.FieldViolation", base.CreateSchemaProxy(violation))
+		}
 		anyPair := util.NewGoogleAny()
 		components.Schemas.Set(anyPair.ID, base.CreateSchemaProxy(anyPair.Schema))
 	}
 
 	return components, nil
 }
+
+// rpcStatusSchema is the error returned by grpc-gateway and the Envoy gRPC-JSON transcoder: google.rpc.Status in its
+// JSON form, with the numeric status code.
+func rpcStatusSchema() *base.Schema {
+	props := orderedmap.New[string, *base.SchemaProxy]()
+	props.Set("code", base.CreateSchemaProxy(&base.Schema{
+		Description: "The status code, which should be an enum value of [google.rpc.Code][google.rpc.Code].",
+		Type:        []string{"integer"},
+		Format:      "int32",
+		Examples:    []*yaml.Node{utils.CreateIntNode("5")},
+	}))
+	props.Set("message", base.CreateSchemaProxy(&base.Schema{
+		Description: "A developer-facing error message, which should be in English. Any user-facing error message should be localized and sent in the [google.rpc.Status.details][google.rpc.Status.details] field, or localized by the client.",
+		Type:        []string{"string"},
+	}))
+	props.Set("details", base.CreateSchemaProxy(&base.Schema{
+		Description: "A list of messages that carry the error details.",
+		Type:        []string{"array"},
+		Items:       &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxyRef("#/components/schemas/google.protobuf.Any")},
+	}))
+	return &base.Schema{
+		Title:       "Status",
+		Description: "The `Status` type defines a logical error model that is suitable for different programming environments, including REST APIs and RPC APIs. It is used by [gRPC](https://github.com/grpc).",
+		Type:        []string{"object"},
+		Properties:  props,
+	}
+}
@@ -0,0 +1,122 @@
+// Package conversion has the state that's collected while a conversion runs, like the method behind every operation.
+// The state travels with the options.Options of the conversion, which return it with their Conversion method, but it
+// isn't part of their public API.
+package conversion
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// State is the state of a single conversion. Its methods can be called on a nil State, which doesn't collect
+// anything, like outside of a conversion.
+type State struct {
+	mu         sync.Mutex
+	conflicts  []string
+	routes     map[string]protoreflect.MethodDescriptor
+	extensions map[protoreflect.FullName][]protoreflect.ExtensionDescriptor
+}
+
+// ReportConflict records that an annotation conflicted with a generated value.
+func (s *State) ReportConflict(location, field string, generated, annotated any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conflicts = append(s.conflicts, fmt.Sprintf("%s: %s is %v but the annotation sets %v", location, field, generated, annotated))
+}
+
+// Conflicts returns every conflict, sorted and without duplicates.
+func (s *State) Conflicts() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := map[string]struct{}{}
+	res := []string{}
+	for _, conflict := range s.conflicts {
+		if _, ok := seen[conflict]; ok {
+			continue
+		}
+		seen[conflict] = struct{}{}
+		res = append(res, conflict)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// AddRoute records the method behind a path and HTTP method, so outputs that are made from the finished documents
+// can refer back to the protos.
+func (s *State) AddRoute(path, httpMethod string, method protoreflect.MethodDescriptor) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.routes == nil {
+		s.routes = map[string]protoreflect.MethodDescriptor{}
+	}
+	s.routes[strings.ToUpper(httpMethod)+" "+path] = method
+}
+
+// Route returns the method that was added for a path and HTTP method.
+func (s *State) Route(path, httpMethod string) (protoreflect.MethodDescriptor, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	method, ok := s.routes[strings.ToUpper(httpMethod)+" "+path]
+	return method, ok
+}
+
+// AddExtensions records the proto2 extension fields that are declared in a file, at the top level or nested in its
+// messages, so the schemas of the messages they extend can have a property for every known extension.
+func (s *State) AddExtensions(fd protoreflect.FileDescriptor) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.extensions == nil {
+		s.extensions = map[protoreflect.FullName][]protoreflect.ExtensionDescriptor{}
+	}
+	s.addExtensions(fd.Extensions())
+	s.addMessages(fd.Messages())
+}
+
+func (s *State) addMessages(messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		s.addExtensions(messages.Get(i).Extensions())
+		s.addMessages(messages.Get(i).Messages())
+	}
+}
+
+func (s *State) addExtensions(extensions protoreflect.ExtensionDescriptors) {
+	for i := 0; i < extensions.Len(); i++ {
+		ext := extensions.Get(i)
+		name := ext.ContainingMessage().FullName()
+		s.extensions[name] = append(s.extensions[name], ext)
+	}
+}
+
+// Extensions returns the extensions of a message, by field number.
+func (s *State) Extensions(message protoreflect.FullName) []protoreflect.ExtensionDescriptor {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fields := slices.Clone(s.extensions[message])
+	slices.SortFunc(fields, func(a, b protoreflect.ExtensionDescriptor) int {
+		return int(a.Number() - b.Number())
+	})
+	return fields
+}
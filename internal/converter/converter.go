@@ -5,11 +5,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"github.com/lmittmann/tint"
 	"github.com/pb33f/libopenapi"
 	base "github.com/pb33f/libopenapi/datamodel/high/base"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
@@ -22,8 +21,11 @@ import (
 	pluginpb "google.golang.org/protobuf/types/pluginpb"
 	"gopkg.in/yaml.v3"
 
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/conversion"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/openapiv2"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/schema"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 )
 
@@ -42,6 +44,73 @@ func ConvertFrom(rd io.Reader) (*pluginpb.CodeGeneratorResponse, error) {
 	return Convert(req)
 }
 
+// ConvertFileDescriptorSet reads a serialized FileDescriptorSet, like the output of `buf build -o -` or
+// `protoc --include_imports --descriptor_set_out`, and converts it the same way the plugin would. The parameter
+// uses the same format as the plugin parameter. When files is empty, every file in the set that isn't imported by
+// another file in the set is generated. When merge is true and no path is given, everything is merged into a single
+// document named "openapi.{format}".
+func ConvertFileDescriptorSet(rd io.Reader, parameter string, files []string, merge bool) (*pluginpb.CodeGeneratorResponse, error) {
+	input, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(input, fds); err != nil {
+		return nil, fmt.Errorf("can't unmarshal descriptor set: %w", err)
+	}
+
+	opts, err := options.FromString(parameter)
+	if err != nil {
+		return nil, err
+	}
+	if merge && opts.Path == "" {
+		opts.Path = "openapi." + opts.Format
+	}
+
+	if len(files) == 0 {
+		files = RootFiles(fds)
+	}
+	return ConvertWithOptions(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: files,
+		Parameter:      proto.String(parameter),
+		ProtoFile:      fds.GetFile(),
+	}, opts)
+}
+
+// RootFiles returns the names of the files in the set that aren't imported by any other file in the set.
+func RootFiles(fds *descriptorpb.FileDescriptorSet) []string {
+	imported := map[string]struct{}{}
+	for _, file := range fds.GetFile() {
+		for _, dep := range file.GetDependency() {
+			imported[dep] = struct{}{}
+		}
+	}
+	files := []string{}
+	for _, file := range fds.GetFile() {
+		if _, ok := imported[file.GetName()]; !ok {
+			files = append(files, file.GetName())
+		}
+	}
+	return files
+}
+
+// ServiceFile returns the name of the file in the set that defines a service, like "foo.v1.UserService".
+func ServiceFile(fds *descriptorpb.FileDescriptorSet, service string) (string, error) {
+	for _, file := range fds.GetFile() {
+		for _, sd := range file.GetService() {
+			name := sd.GetName()
+			if file.GetPackage() != "" {
+				name = file.GetPackage() + "." + name
+			}
+			if name == service {
+				return file.GetName(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("service '%s' isn't defined in the descriptor set", service)
+}
+
 // Convert is the primary entrypoint for the protoc plugin. It takes a *pluginpb.CodeGeneratorRequest
 // and returns a *pluginpb.CodeGeneratorResponse.
 func Convert(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
@@ -53,24 +122,13 @@ func Convert(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRespons
 }
 
 func ConvertWithOptions(req *pluginpb.CodeGeneratorRequest, opts options.Options) (*pluginpb.CodeGeneratorResponse, error) {
-	annotator := &annotator{}
-	if opts.MessageAnnotator == nil {
-		opts.MessageAnnotator = annotator
-	}
-	if opts.FieldAnnotator == nil {
-		opts.FieldAnnotator = annotator
-	}
-	if opts.FieldReferenceAnnotator == nil {
-		opts.FieldReferenceAnnotator = annotator
-	}
+	opts = withDefaultAnnotators(opts)
 
-	if opts.Debug {
-		slog.SetDefault(slog.New(
-			tint.NewHandler(os.Stderr, &tint.Options{
-				Level: slog.LevelDebug,
-			}),
-		))
+	restoreLogging, err := setupLogging(opts)
+	if err != nil {
+		return nil, err
 	}
+	defer restoreLogging()
 
 	files := []*pluginpb.CodeGeneratorResponse_File{}
 	genFiles := make(map[string]struct{}, len(req.FileToGenerate))
@@ -85,6 +143,12 @@ func ConvertWithOptions(req *pluginpb.CodeGeneratorRequest, opts options.Options
 	if err != nil {
 		return nil, err
 	}
+	state := &conversion.State{}
+	opts = opts.WithConversion(state)
+	resolver.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		state.AddExtensions(fd)
+		return true
+	})
 
 	newSpec := func() (*v3.Document, error) {
 		model := &v3.Document{}
@@ -111,16 +175,41 @@ func ConvertWithOptions(req *pluginpb.CodeGeneratorRequest, opts options.Options
 		}
 	}
 
+	why := newWhyTracer(opts)
+	defer why.done()
+	newSpecStage := "new documents"
+	if len(opts.BaseOpenAPI) > 0 {
+		newSpecStage = "the base document (base)"
+	}
+
 	spec, err := newSpec()
 	if err != nil {
 		return nil, err
 	}
+	if opts.Path != "" {
+		why.trace(spec, newSpecStage)
+	}
 	outFiles := map[string]*v3.Document{}
+	// A merged document has the description file of every package once, in the order the packages come up
+	descriptions := []string{}
+	seenDescriptionFiles := map[string]struct{}{}
+	reportMessages := map[protoreflect.FullName]protoreflect.MessageDescriptor{}
+	routes := []Route{}
+	usedFeatures := featureUses{}
+	// Messages and enums that are already in the merged document don't need to be built again
+	var built map[protoreflect.FullName]struct{}
+	if opts.Path != "" {
+		built = map[protoreflect.FullName]struct{}{}
+	}
 
 	for _, fileDesc := range req.GetProtoFile() {
 		if _, ok := genFiles[fileDesc.GetName()]; !ok {
 			continue
 		}
+		if !opts.GenerateImports && util.IsWellKnownImport(fileDesc.GetName()) {
+			slog.Debug("skipping well-known import", slog.String("name", fileDesc.GetName()))
+			continue
+		}
 
 		slog.Debug("generating file", slog.String("name", fileDesc.GetName()))
 
@@ -138,10 +227,45 @@ func ConvertWithOptions(req *pluginpb.CodeGeneratorRequest, opts options.Options
 			}
 			spec.Info.Title = string(fd.FullName())
 			spec.Info.Description = util.FormatComments(fd.SourceLocations().ByDescriptor(fd))
+			why.trace(spec, newSpecStage)
+		}
+		if opts.DescriptionFile != "" {
+			name := descriptionFilePath(opts, fd)
+			if _, ok := seenDescriptionFiles[name]; !ok || opts.Path == "" {
+				seenDescriptionFiles[name] = struct{}{}
+				description, err := readDescriptionFile(name)
+				if err != nil {
+					return nil, err
+				}
+				if opts.Path == "" {
+					prependDescription(spec, description)
+				} else if description != "" {
+					descriptions = append(descriptions, description)
+				}
+			}
 		}
 
-		if err := appendToSpec(opts, spec, fd); err != nil {
-			return nil, err
+		if err := convertFile(fileDesc.GetName(), func() error {
+			return appendToSpec(opts, spec, fd, built, why)
+		}); err != nil {
+			// A file that fails can leave part of its content in the merged document, so it can only be skipped
+			// when it has its own.
+			if !opts.KeepGoing || opts.Path != "" {
+				return nil, err
+			}
+			slog.Error("skipping file", slog.Any("error", err))
+			continue
+		}
+		if opts.RouteTable != "" {
+			routes = append(routes, routeTableRoutes(opts, fd)...)
+		}
+		if opts.FeaturesReport != "" {
+			collectFeatures(usedFeatures, fd)
+		}
+		if opts.DuplicatesReport != "" {
+			for i := 0; i < fd.Messages().Len(); i++ {
+				collectReportMessages(reportMessages, fd.Messages().Get(i))
+			}
 		}
 
 		if opts.Path == "" {
@@ -154,21 +278,150 @@ func ConvertWithOptions(req *pluginpb.CodeGeneratorRequest, opts options.Options
 	}
 
 	if opts.Path != "" {
+		prependDescription(spec, strings.Join(descriptions, "\n\n"))
 		outFiles[opts.Path] = spec
 	}
 
-	for path, spec := range outFiles {
+	if err := checkOverrideConflicts(opts); err != nil {
+		return nil, err
+	}
+
+	if opts.DiffAgainst != nil && len(outFiles) > 1 {
+		return nil, fmt.Errorf("diff-against needs a single output file, use the path option to merge the files")
+	}
+
+	// Output files are sorted so the response is deterministic
+	paths := make([]string, 0, len(outFiles))
+	for path := range outFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	inventory := []InventoryEntry{}
+	for _, path := range paths {
 		path := path
-		spec := spec
-		content, err := specToFile(opts, spec)
+		spec := outFiles[path]
+		if err := convertFile(path, func() error {
+			return finalizeSpec(opts, spec, why)
+		}); err != nil {
+			if !opts.KeepGoing {
+				return nil, err
+			}
+			slog.Error("skipping file", slog.Any("error", err))
+			continue
+		}
+		// The catalog metadata only configures the catalog file, so it's removed from the spec.
+		var catalog *pluginpb.CodeGeneratorResponse_File
+		if opts.Backstage {
+			catalog, err = backstageCatalogFile(path, spec)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if spec.Extensions != nil {
+			spec.Extensions.Delete(BackstageExtension)
+		}
+		written := spec
+		var parts []specPart
+		if opts.SplitByTag {
+			written, parts, err = splitByTag(path, spec)
+			if err != nil {
+				return nil, err
+			}
+		}
+		content, err := specToFile(opts, written)
 		if err != nil {
 			return nil, err
 		}
+		var diffFiles []*pluginpb.CodeGeneratorResponse_File
+		if opts.DiffAgainst != nil {
+			content, diffFiles, err = applyDiff(opts, spec, content)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if opts.PostProcessCmd != "" {
+			content, err = postProcess(opts, path, content)
+			if err != nil {
+				return nil, err
+			}
+		}
 		files = append(files, &pluginpb.CodeGeneratorResponse_File{
 			Name:              &path,
 			Content:           &content,
 			GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
 		})
+		files = append(files, diffFiles...)
+		for _, part := range parts {
+			part := part
+			content, err := specToFile(opts, part.spec)
+			if err != nil {
+				return nil, err
+			}
+			if opts.PostProcessCmd != "" {
+				content, err = postProcess(opts, part.path, content)
+				if err != nil {
+					return nil, err
+				}
+			}
+			files = append(files, &pluginpb.CodeGeneratorResponse_File{
+				Name:              &part.path,
+				Content:           &content,
+				GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+			})
+		}
+		if opts.Inventory != "" {
+			inventory = append(inventory, inventoryEntries(opts, path, spec)...)
+		}
+		if catalog != nil {
+			files = append(files, catalog)
+		}
+		if opts.HTML {
+			page, err := htmlFile(path, spec)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, page)
+		}
+	}
+
+	if opts.Inventory != "" {
+		file, err := inventoryFile(opts.Inventory, inventory)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if opts.RouteTable != "" {
+		file, err := routeTableFile(opts.RouteTable, routes)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if opts.DuplicatesReport != "" {
+		file, err := duplicatesReportFile(opts.DuplicatesReport, duplicatesReport(opts, reportMessages))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if opts.FeaturesReport != "" {
+		file, err := featuresReportFile(opts.FeaturesReport, featuresReport(usedFeatures))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if opts.Manifest != "" {
+		manifest, err := manifestFile(opts.Manifest, files)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, manifest)
 	}
 
 	features := uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL | pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
@@ -180,6 +433,32 @@ func ConvertWithOptions(req *pluginpb.CodeGeneratorRequest, opts options.Options
 	}, nil
 }
 
+// withDefaultAnnotators fills in the annotators that options.NewOptions leaves unset, and adds the registered
+// transformers.
+func withDefaultAnnotators(opts options.Options) options.Options {
+	annotator := &annotator{}
+	if opts.MessageAnnotator == nil {
+		opts.MessageAnnotator = annotator
+	}
+	if opts.FieldAnnotator == nil {
+		opts.FieldAnnotator = annotator
+	}
+	if opts.FieldReferenceAnnotator == nil {
+		opts.FieldReferenceAnnotator = annotator
+	}
+	return opts.WithRegisteredTransformers()
+}
+
+// MessageToSchema returns the schema of a single message like it's built for components.schemas, before the options
+// that change the finished document run. References to other messages and enums point to #/components/schemas.
+func MessageToSchema(opts options.Options, desc protoreflect.MessageDescriptor) (*base.Schema, error) {
+	_, s := schema.MessageToSchema(withDefaultAnnotators(opts), desc)
+	if s == nil {
+		return nil, fmt.Errorf("%s has no schema", desc.FullName())
+	}
+	return s, nil
+}
+
 func mergeTags(tags []*base.Tag) []*base.Tag {
 
 	if len(tags) == 0 {
@@ -205,13 +484,133 @@ func mergeTags(tags []*base.Tag) []*base.Tag {
 		}
 
 		if tag.Extensions != nil {
-			found[tag.Name].Extensions = tag.Extensions
+			// Extensions are merged, so an x-displayName from the base file wins over a generated one
+			if found[tag.Name].Extensions == nil {
+				found[tag.Name].Extensions = orderedmap.New[string, *yaml.Node]()
+			}
+			for key, value := range tag.Extensions.FromOldest() {
+				if _, ok := found[tag.Name].Extensions.Get(key); !ok {
+					found[tag.Name].Extensions.Set(key, value)
+				}
+			}
 		}
 	}
 
 	return res
 }
 
+// finalizeSpec applies options that operate on the whole document. This runs once for each output file, after
+// all proto files have been added to it.
+func finalizeSpec(opts options.Options, spec *v3.Document, why *whyTracer) error {
+	if err := applyDefaultResponses(opts, spec); err != nil {
+		return err
+	}
+	why.trace(spec, "the default responses (default-response)")
+	if opts.HasAIP("155") {
+		applyAIP155(opts, spec)
+		why.trace(spec, "aip=155")
+	}
+	if opts.HasAIP("157") {
+		applyAIP157(opts, spec)
+		why.trace(spec, "aip=157")
+	}
+	if err := wrapResponseEnvelopes(opts, spec); err != nil {
+		return err
+	}
+	why.trace(spec, "response-envelope")
+	if opts.BufModule != "" {
+		if err := applyBufModule(opts, spec); err != nil {
+			return err
+		}
+		why.trace(spec, "buf-module")
+	}
+	if opts.EnvoyJWTConfig != nil {
+		if err := applyEnvoyJWTConfig(spec, opts.EnvoyJWTConfig); err != nil {
+			return err
+		}
+		why.trace(spec, "envoy-jwt-config")
+	}
+	if opts.OIDCIssuer != "" {
+		applyOIDCIssuer(opts, spec)
+		why.trace(spec, "oidc-issuer")
+	}
+	if len(opts.MTLS) > 0 {
+		applyMTLS(opts, spec)
+		why.trace(spec, "mtls")
+	}
+	if opts.WithAuthResponses {
+		addAuthResponses(opts, spec)
+		why.trace(spec, "with-auth-responses")
+	}
+	if opts.RemoveInternal {
+		removeInternalOperations(spec)
+		why.trace(spec, "remove-internal")
+	}
+	if opts.InlineThreshold > 0 {
+		inlineSmallSchemas(opts.InlineThreshold, spec)
+		why.trace(spec, "inline-threshold")
+	}
+	if opts.Terse {
+		stripDocumentation(spec)
+		why.trace(spec, "terse")
+	}
+	if opts.SpectralCompat {
+		applySpectralCompat(spec)
+		why.trace(spec, "spectral-compat")
+	}
+	if opts.StableAnchors {
+		applyStableAnchors(spec)
+		why.trace(spec, "stable-anchors")
+	}
+	if opts.JSONSchemaDialect != "" {
+		if err := applyJSONSchemaDialect(opts, spec); err != nil {
+			return err
+		}
+		why.trace(spec, "json-schema-dialect")
+	}
+	for i, content := range opts.Overlays {
+		if err := applyOverlay(spec, content); err != nil {
+			return err
+		}
+		why.trace(spec, fmt.Sprintf("overlay #%d", i+1))
+	}
+	for i, content := range opts.JSONPatches {
+		if err := applyJSONPatch(spec, content); err != nil {
+			return err
+		}
+		why.trace(spec, fmt.Sprintf("json-patch #%d", i+1))
+	}
+	for i, content := range opts.MergePatches {
+		if err := applyMergePatch(spec, content); err != nil {
+			return err
+		}
+		why.trace(spec, fmt.Sprintf("merge-patch #%d", i+1))
+	}
+	for i, transformer := range opts.DocumentTransformers {
+		if err := transformer.TransformDocument(spec); err != nil {
+			return fmt.Errorf("transforming document: %w", err)
+		}
+		why.trace(spec, fmt.Sprintf("document transformer #%d (%T)", i+1, transformer))
+	}
+	return nil
+}
+
+// checkOverrideConflicts logs every conflict between annotations and generated content. In strict mode, conflicts
+// are returned as an error instead.
+func checkOverrideConflicts(opts options.Options) error {
+	conflicts := opts.Conversion().Conflicts()
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if opts.Strict {
+		return fmt.Errorf("annotations conflict with generated content:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+	for _, conflict := range conflicts {
+		slog.Debug("annotation conflict", slog.String("conflict", conflict))
+	}
+	return nil
+}
+
 func specToFile(opts options.Options, spec *v3.Document) (string, error) {
 	switch opts.Format {
 	case "yaml":
@@ -227,9 +626,12 @@ func specToFile(opts options.Options, spec *v3.Document) (string, error) {
 	}
 }
 
-func appendToSpec(opts options.Options, spec *v3.Document, fd protoreflect.FileDescriptor) error {
+func appendToSpec(opts options.Options, spec *v3.Document, fd protoreflect.FileDescriptor, built map[protoreflect.FullName]struct{}, why *whyTracer) error {
+	openapiv2.SpecWithFileAnnotations(opts, spec, fd)
+	why.trace(spec, "the openapiv2 file annotations of "+fd.Path())
 	gnostic.SpecWithFileAnnotations(spec, fd)
-	components, err := fileToComponents(opts, fd)
+	why.trace(spec, "the gnostic file annotations of "+fd.Path())
+	components, err := fileToComponents(opts, fd, built)
 	if err != nil {
 		return err
 	}
@@ -237,12 +639,20 @@ func appendToSpec(opts options.Options, spec *v3.Document, fd protoreflect.FileD
 	initializeDoc(spec)
 	initializeComponents(components)
 	appendServiceDocs(opts, spec, fd)
+	appendFileDeprecation(opts, spec, fd)
 	util.AppendComponents(spec, components)
+	why.trace(spec, "the messages, enums and Connect schemas of "+fd.Path())
 
-	if err := addPathItemsFromFile(opts, fd, spec.Paths); err != nil {
+	if err := addPathItemsFromFile(opts, fd, spec); err != nil {
+		return err
+	}
+	why.trace(spec, "the methods of "+fd.Path())
+	if err := addGlobalResponses(opts, spec); err != nil {
 		return err
 	}
+	why.trace(spec, "global-responses")
 	spec.Tags = append(spec.Tags, fileToTags(opts, fd)...)
+	why.trace(spec, "the services of "+fd.Path())
 	return nil
 }
 
@@ -277,6 +687,22 @@ func appendServiceDocs(opts options.Options, spec *v3.Document, fd protoreflect.
 	spec.Info.Description = strings.TrimSpace(builder.String())
 }
 
+// appendFileDeprecation starts the description of the document with a notice when a file with services is
+// deprecated, since the operations of all of its services are deprecated.
+func appendFileDeprecation(opts options.Options, spec *v3.Document, fd protoreflect.FileDescriptor) {
+	if !util.IsFileDeprecated(fd) {
+		return
+	}
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		if opts.HasService(services.Get(i).FullName()) {
+			notice := fmt.Sprintf("Deprecated: the services of %s are deprecated.", fd.Path())
+			spec.Info.Description = strings.TrimSpace(notice + "\n\n" + spec.Info.Description)
+			return
+		}
+	}
+}
+
 func initializeDoc(doc *v3.Document) {
 	slog.Debug("initializeDoc")
 	if doc.Version == "" {
@@ -2,23 +2,28 @@ package converter_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/pb33f/libopenapi"
 	validator "github.com/pb33f/libopenapi-validator"
 	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
 	"gopkg.in/yaml.v3"
@@ -26,6 +31,7 @@ import (
 
 var scenarios = []Scenario{
 	{Name: "standard", Options: "allow-get,with-streaming,with-service-descriptions"},
+	{Name: "end_stream_frame", Options: "with-streaming,with-end-stream-frame"},
 	{Name: "proto_names", Options: "with-proto-names"},
 	{Name: "path_prefix", Options: "path-prefix=/testing/1234"},
 	{Name: "with_proto_annotations", Options: "with-proto-annotations"},
@@ -34,38 +40,156 @@ var scenarios = []Scenario{
 	{Name: "with_base", Options: "base=testdata/with_base/base.yaml,trim-unused-types"},
 	{Name: "with_specification_extensions", Options: "base=testdata/with_specification_extensions/base.yaml,trim-unused-types"},
 	{Name: "additional_bindings"},
+	{Name: "grpc_system_services"},
+	{Name: "with_grpc_system_services", Dir: "grpc_system_services", Options: "with-grpc-system-services"},
+	{Name: "trace_headers", Options: "with-trace-headers"},
+	{Name: "idempotency_key", Options: "with-idempotency-key"},
+	{Name: "rate_limit_responses", Options: "with-rate-limit-responses"},
+	{Name: "auth_responses", Options: "with-auth-responses,base=testdata/auth_responses/base.yaml"},
+	{Name: "auth_responses_optional", Dir: "auth_responses", Options: "with-auth-responses,base=testdata/auth_responses_optional/base.yaml"},
+	{Name: "global_responses", Options: "global-responses=503:Maintenance,base=testdata/global_responses/base.yaml"},
+	{Name: "global_responses_default", Dir: "global_responses", Options: "global-responses=default:Maintenance,base=testdata/global_responses/base.yaml"},
+	{Name: "response_envelope", Options: "response-envelope=Envelope,base=testdata/response_envelope/base.yaml"},
+	{Name: "json_schema_dialect"},
+	{Name: "json_schema_dialect_oas", Dir: "json_schema_dialect", Options: "json-schema-dialect=oas"},
+	{Name: "json_schema_dialect_2020_12", Dir: "json_schema_dialect", Options: "json-schema-dialect=2020-12,base=testdata/json_schema_dialect_2020_12/base.yaml"},
+	{Name: "default_response", Options: "default-response=off"},
+	{Name: "default_response_echo", Dir: "default_response", Options: "default-response=echo:Problem,base=testdata/default_response_echo/base.yaml"},
+	{Name: "response_media_types", Options: "trim-unused-types"},
+	{Name: "terse", Options: "with-rate-limit-responses,terse,base=testdata/terse/base.yaml"},
+	{Name: "manifest", Options: "manifest=openapi.manifest.json", Formats: []string{"yaml"}},
+	{Name: "inventory", Options: "inventory=operations.csv", Formats: []string{"yaml"}},
+	{Name: "inventory_json", Dir: "inventory", Options: "inventory=operations.json", Formats: []string{"yaml"}},
+	{Name: "route_table", Options: "route-table=routes.json,path-prefix=/api", Formats: []string{"yaml"}},
+	{Name: "route_table_services", Dir: "route_table", Options: "route-table=routes.json,services=route_table.TestService", Formats: []string{"yaml"}},
+	{Name: "route_table_grpc_system_services", Dir: "grpc_system_services", Options: "route-table=routes.json", Formats: []string{"yaml"}},
+	{Name: "duplicates_report", Options: "duplicates-report=duplicates.json", Formats: []string{"yaml"}},
+	{Name: "override_strategy"},
+	{Name: "override_strategy_generated_wins", Dir: "override_strategy", Options: "override-strategy=operation:generated-wins"},
+	{Name: "google_types", Options: "with-proto-names"},
+	{Name: "google_types_base", Dir: "google_types", Options: "base=testdata/google_types_base/base.yaml"},
+	{Name: "spectral_compat", Options: "spectral-compat"},
+	{Name: "backstage", Options: "backstage", Formats: []string{"yaml"}},
+	{Name: "backstage_base", Dir: "backstage", Options: "backstage,path=apis/books.openapi.yaml,base=testdata/backstage_base/base.yaml", Formats: []string{"yaml"}},
+	{Name: "changelog", Options: "diff-against=testdata/changelog/previous.yaml,changelog=CHANGELOG.md", Formats: []string{"yaml"}},
+	{Name: "changelog_description", Dir: "changelog", Options: "diff-against=testdata/changelog/previous.yaml,changelog=description"},
+	{Name: "version_bump", Options: "diff-against=testdata/version_bump/previous.yaml,stamp-version,version-bump=version.json", Formats: []string{"yaml"}},
+	{Name: "connect_validation", Options: "with-connect-validation"},
+	{Name: "validation_errors", Options: "with-validation-errors"},
+	{Name: "validation_errors_grpc_gateway", Dir: "validation_errors", Options: "with-validation-errors,flavor=grpc-gateway"},
+	{Name: "max_body_bytes", Options: "max-body-bytes=4194304"},
+	{Name: "tag_display_names", Options: "with-tag-display-names"},
+	{Name: "tag_display_names_stable_anchors", Dir: "tag_display_names", Options: "with-tag-display-names,stable-anchors"},
+	{Name: "description_file", Options: "description-file=testdata/description_file/{package_dir}/README.md"},
+	{Name: "enum_extensions", Options: "enum-extensions"},
+	{Name: "inline_enums", Dir: "enum_extensions", Options: "inline-enums"},
+	{Name: "enum_extensions_inline", Dir: "enum_extensions", Options: "enum-extensions,inline-enums"},
+	{Name: "path_case", Options: "path-case=kebab,path-prefix=/Api"},
+	{Name: "file_transfers"},
+	{Name: "with_file_transfers", Dir: "file_transfers", Options: "with-file-transfers"},
+	{Name: "flavor", Options: "allow-get"},
+	{Name: "flavor_grpc_gateway", Dir: "flavor", Options: "flavor=grpc-gateway,allow-get"},
+	{Name: "flavor_envoy_json_transcoder", Dir: "flavor", Options: "flavor=envoy-json-transcoder,allow-get"},
+	{Name: "envoy_jwt_config", Options: "envoy-jwt-config=testdata/envoy_jwt_config/jwt.yaml"},
+	{Name: "oidc_issuer", Options: "oidc-issuer=https://auth.example.com/"},
+	{Name: "oidc_issuer_base", Dir: "oidc_issuer", Options: "oidc-issuer=https://auth.example.com,base=testdata/oidc_issuer_base/base.yaml"},
+	{Name: "mtls", Options: "mtls=mtls.TestService:spiffe://partners.example.com/*;mtls.TestService.GetTest"},
+	{Name: "mtls_base", Dir: "mtls", Options: "mtls=mtls.TestService,base=testdata/mtls_base/base.yaml"},
+	{Name: "mtls_other_service", Dir: "mtls", Options: "mtls=mtls.OtherService"},
+	{Name: "code_samples", Options: "with-code-samples"},
+	{Name: "buf_module", Options: "buf-module=buf.build/acme/petapis:7a2b9c8d,buf-module-in-description"},
+	{Name: "buf_module_github", Dir: "buf_module", Options: "buf-module=github.com/acme/protos"},
+	{Name: "html", Options: "html,path=books.openapi.yaml,base=testdata/html/base.yaml", Formats: []string{"yaml"}},
+	{Name: "property_order"},
+	{Name: "property_order_declaration", Dir: "property_order", Options: "property-order=declaration"},
+	{Name: "property_order_number", Dir: "property_order", Options: "property-order=number"},
+	{Name: "overlay", Options: "overlay=testdata/overlay/patches.overlay.yaml,html", Formats: []string{"yaml"}},
+	{Name: "json_patch", Options: "json-patch=testdata/json_patch/patch.json,merge-patch=testdata/json_patch/merge.yaml"},
+	{Name: "json_patch_root", Dir: "json_patch", Options: "json-patch=testdata/json_patch/root.json"},
+	{Name: "infer_get_from_names", Options: "infer-get-from-names,with-idempotency-key"},
+	{Name: "custom_http_methods", Options: "with-code-samples"},
+	{Name: "with_connect_paths", Options: "allow-get,with-connect-paths"},
+	{Name: "visibility"},
+	{Name: "visibility_labels", Dir: "visibility", Options: "visibility-labels=preview"},
+	{Name: "remove_internal", Dir: "visibility", Options: "visibility-labels=PREVIEW,remove-internal"},
+	{Name: "stable_anchors", Options: "stable-anchors"},
+	{Name: "aip_154", Options: "allow-get,aip=154"},
+	{Name: "aip_132", Options: "aip=132;158"},
+	{Name: "aip_158", Dir: "aip_132", Options: "aip=158"},
+	{Name: "aip_155", Options: "aip=155"},
+	{Name: "aip_157", Options: "aip=157"},
+	{Name: "aip_157_spectral_compat", Dir: "aip_157", Options: "aip=157,spectral-compat"},
+	{Name: "split_by_tag", Options: "split-by-tag", Formats: []string{"yaml"}, SkipValidation: true},
+	{Name: "features_report", Options: "features-report=features.json", Formats: []string{"yaml"}},
+	{Name: "inline_threshold", Options: "inline-threshold=2"},
+	{Name: "replaced_by"},
+	{Name: "replaced_by_short_operation_ids", Dir: "replaced_by", Options: "short-operation-ids"},
+	{Name: "lifecycle_headers", Options: "with-lifecycle-headers"},
+	{Name: "openapiv2_annotations", Options: "with-openapiv2-annotations"},
+	{Name: "struct_docs", Options: "with-struct-docs"},
+	{Name: "summary_sources", Options: "summary-sources=comment;humanized"},
 }
 
 type Scenario struct {
 	Name    string
 	Options string
+	// Dir is the directory in testdata with the protos of the scenario, when they're the protos of another scenario
+	// with other options. It defaults to the name of the scenario.
+	Dir string
+	// Formats are the formats of the generated documents, yaml and json by default. Scenarios with other outputs
+	// than the documents, like reports, only use yaml since the outputs of both formats would have the same name.
+	Formats []string
+	// SkipValidation skips the validation of the generated documents, for documents with references to the other
+	// generated documents that can't be resolved on their own.
+	SkipValidation bool
 }
 
-func generateAndCheckResult(t *testing.T, options, format, protofile string) string {
-	relPath := strings.TrimPrefix(protofile, "testdata/")
+func (s Scenario) dir() string {
+	if s.Dir != "" {
+		return s.Dir
+	}
+	return s.Name
+}
 
-	// Load descriptor set
+func (s Scenario) formats() []string {
+	if len(s.Formats) > 0 {
+		return s.Formats
+	}
+	return []string{"yaml", "json"}
+}
+
+// loadRequest returns a request to generate the given files of the descriptor set in testdata.
+func loadRequest(t testing.TB, files ...string) *pluginpb.CodeGeneratorRequest {
 	f, err := os.ReadFile(filepath.Join("testdata", "fileset.binpb"))
 	require.NoError(t, err)
 
 	pf := new(descriptorpb.FileDescriptorSet)
 	require.NoError(t, proto.Unmarshal(f, pf))
 
-	// Make Generation Request
 	req := new(pluginpb.CodeGeneratorRequest)
 	req.ProtoFile = pf.GetFile()
-
 	for _, f := range req.GetProtoFile() {
-		if relPath == f.GetName() {
-			req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+		for _, name := range files {
+			if name == f.GetName() {
+				req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+			}
 		}
 	}
+	require.Len(t, req.FileToGenerate, len(files))
+	return req
+}
+
+func generateAndCheckResult(t *testing.T, scenario Scenario, format, protofile string) string {
+	relPath := strings.TrimPrefix(protofile, "testdata/")
+
+	// Make Generation Request
+	req := loadRequest(t, relPath)
 	var sb strings.Builder
 	sb.WriteString("debug,format=")
 	sb.WriteString(format)
-	if len(options) > 0 {
+	if len(scenario.Options) > 0 {
 		sb.WriteString(",")
-		sb.WriteString(options)
+		sb.WriteString(scenario.Options)
 	}
 	req.Parameter = proto.String(sb.String())
 
@@ -76,26 +200,36 @@ func generateAndCheckResult(t *testing.T, options, format, protofile string) str
 	resp, err := converter.ConvertFrom(bytes.NewBuffer(b))
 	require.NoError(t, err)
 	require.Nil(t, resp.Error)
-	require.Len(t, resp.File, 1)
+	require.NotEmpty(t, resp.File)
 	file := resp.File[0]
 	assert.NotNil(t, file.Name)
-	assert.Equal(t, strings.TrimSuffix(relPath, filepath.Ext(relPath))+".openapi."+format, file.GetName())
+	opts, err := options.FromString(scenario.Options)
+	require.NoError(t, err)
+	if opts.Path == "" {
+		assert.Equal(t, strings.TrimSuffix(relPath, filepath.Ext(relPath))+".openapi."+format, file.GetName())
+	}
 
-	// Load in our expected output and compare it against what we actually got
-	outputPath := makeOutputPath(protofile, format)
+	// Load in our expected outputs and compare them against what we actually got
+	for _, file := range resp.File {
+		checkGoldenFile(t, filepath.Join("testdata", scenario.Name, "output", path.Base(file.GetName())), file.GetContent())
+	}
+	return file.GetContent()
+}
+
+// checkGoldenFile compares content with the golden file at outputPath, and writes the golden file when it's missing.
+func checkGoldenFile(t *testing.T, outputPath, content string) {
 	_, statErr := os.Stat(outputPath)
 	switch {
 	case errors.Is(statErr, os.ErrNotExist):
 		assert.NoError(t, os.MkdirAll(filepath.Dir(outputPath), 0755))
-		assert.NoError(t, os.WriteFile(outputPath, []byte(file.GetContent()), 0644))
+		assert.NoError(t, os.WriteFile(outputPath, []byte(content), 0644))
 	case statErr != nil:
 		require.NoError(t, statErr)
 	default:
 		expectedFile, err := os.ReadFile(outputPath)
 		require.NoError(t, err)
-		assert.Equal(t, string(expectedFile), file.GetContent())
+		assert.Equal(t, string(expectedFile), content)
 	}
-	return file.GetContent()
 }
 
 func validateOpenAPISpec(t *testing.T, protofile string, spec string) {
@@ -167,17 +301,18 @@ func validateOpenAPISpec(t *testing.T, protofile string, spec string) {
 func TestConvert(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Run(scenario.Name, func(t *testing.T) {
-			paths, err := filepath.Glob("testdata/" + scenario.Name + "/**.proto")
+			paths, err := filepath.Glob("testdata/" + scenario.dir() + "/**.proto")
 			require.NoError(t, err)
 			for _, protofile := range paths {
 				protofile := protofile
 
-				formats := []string{"yaml", "json"}
-
-				for _, format := range formats {
+				for _, format := range scenario.formats() {
 					format := format
 					t.Run(path.Base(protofile)+"→"+format, func(t *testing.T) {
-						spec := generateAndCheckResult(t, scenario.Options, format, protofile)
+						spec := generateAndCheckResult(t, scenario, format, protofile)
+						if scenario.SkipValidation {
+							return
+						}
 						// Validate
 						t.Run("validate", func(tt *testing.T) {
 							validateOpenAPISpec(t, protofile, spec)
@@ -203,11 +338,6 @@ type TestCase struct {
 	Errors  []string          `yaml:"errors"`
 }
 
-func makeOutputPath(protofile, format string) string {
-	dir, file := filepath.Split(strings.TrimSuffix(protofile, filepath.Ext(protofile)) + ".openapi." + format)
-	return filepath.Join(dir, "output", file)
-}
-
 func TestConvertWithOptions(t *testing.T) {
 	t.Run("with base file", func(t *testing.T) {
 		baseYAML := `
@@ -273,3 +403,245 @@ paths:
 		assert.Contains(t, content, "TestMessage")
 	})
 }
+
+func TestConvertErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		options string
+		err     string
+	}{
+		{
+			name:    "missing global response",
+			file:    "global_responses/global_responses.proto",
+			options: "global-responses=503:Missing,base=testdata/global_responses/base.yaml",
+			err:     "global response 'Missing' not found",
+		},
+		{
+			name:    "strict override conflict",
+			file:    "override_strategy/override_strategy.proto",
+			options: "strict",
+			err:     "override_strategy.TestService.CreateTest: operationId is override_strategy.TestService.CreateTest but the annotation sets createTest",
+		},
+		{
+			name:    "json schema dialect on OpenAPI 3.0",
+			file:    "json_schema_dialect/json_schema_dialect.proto",
+			options: "json-schema-dialect=2020-12,base=testdata/json_schema_dialect/base30.yaml",
+			err:     "json-schema-dialect needs OpenAPI 3.1",
+		},
+		{
+			name:    "invalid overlay",
+			file:    "overlay/overlay.proto",
+			options: "overlay=testdata/overlay/invalid.overlay.yaml",
+			err:     "invalid overlay",
+		},
+		{
+			name:    "JSON patch of a missing path",
+			file:    "json_patch/json_patch.proto",
+			options: "json-patch=testdata/json_patch/missing.json",
+			err:     "JSON patch operation 0 (replace /paths/~1json_patch.TestService~1Removed/post/summary): /paths/~1json_patch.TestService~1Removed does not exist",
+		},
+		{
+			name:    "failed JSON patch test",
+			file:    "json_patch/json_patch.proto",
+			options: "json-patch=testdata/json_patch/failed_test.json",
+			err:     "test failed",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := loadRequest(t, tc.file)
+			req.Parameter = proto.String(tc.options)
+			_, err := converter.Convert(req)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestDescriptionFileMerged(t *testing.T) {
+	req := loadRequest(t, "description_file/description_file.proto", "grpc_system_services/health.proto")
+	req.Parameter = proto.String("description-file=testdata/description_file/description_file/README.md,path=all.openapi.yaml,with-grpc-system-services")
+	resp, err := converter.Convert(req)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 1)
+	// Files that share a description file have it once in the merged document
+	assert.Equal(t, 1, strings.Count(resp.File[0].GetContent(), "Everything about tests."))
+}
+
+// fileDescriptorSet returns the given files of the descriptor set in testdata, marshalled as a descriptor set.
+func fileDescriptorSet(t *testing.T, files ...string) []byte {
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, file := range loadRequest(t, files...).GetProtoFile() {
+		if slices.Contains(files, file.GetName()) {
+			fds.File = append(fds.File, file)
+		}
+	}
+	b, err := proto.Marshal(fds)
+	require.NoError(t, err)
+	return b
+}
+
+func TestConvertFileDescriptorSet(t *testing.T) {
+	b := fileDescriptorSet(t, "file_descriptor_set/dep.proto", "file_descriptor_set/service.proto")
+
+	resp, err := converter.ConvertFileDescriptorSet(bytes.NewReader(b), "format=json", nil, true)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 1)
+	assert.Equal(t, "openapi.json", resp.File[0].GetName())
+	assert.Contains(t, resp.File[0].GetContent(), `"/file_descriptor_set.TestService/CreateTest"`)
+	assert.NotContains(t, resp.File[0].GetContent(), "file_descriptor_set.DepMessage")
+
+	resp, err = converter.ConvertFileDescriptorSet(bytes.NewReader(b), "", []string{"file_descriptor_set/dep.proto", "file_descriptor_set/service.proto"}, true)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 1)
+	assert.Contains(t, resp.File[0].GetContent(), "file_descriptor_set.DepMessage")
+
+	resp, err = converter.ConvertFileDescriptorSet(bytes.NewReader(b), "", []string{"file_descriptor_set/dep.proto", "file_descriptor_set/service.proto"}, false)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 2)
+	assert.Equal(t, "file_descriptor_set/dep.openapi.yaml", resp.File[0].GetName())
+	assert.Equal(t, "file_descriptor_set/service.openapi.yaml", resp.File[1].GetName())
+}
+
+func TestServiceFile(t *testing.T) {
+	b := fileDescriptorSet(t, "file_descriptor_set/dep.proto", "file_descriptor_set/service.proto")
+	fds := &descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(b, fds))
+
+	file, err := converter.ServiceFile(fds, "file_descriptor_set.TestService")
+	require.NoError(t, err)
+	assert.Equal(t, "file_descriptor_set/service.proto", file)
+	_, err = converter.ServiceFile(fds, "file_descriptor_set.MissingService")
+	assert.EqualError(t, err, "service 'file_descriptor_set.MissingService' isn't defined in the descriptor set")
+
+	// The types of filtered out services are trimmed too
+	resp, err := converter.ConvertFileDescriptorSet(bytes.NewReader(b), "services=file_descriptor_set.TestService,trim-unused-types", []string{file}, true)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 1)
+	assert.Contains(t, resp.File[0].GetContent(), "file_descriptor_set.TestMessage")
+	assert.NotContains(t, resp.File[0].GetContent(), "OtherService")
+	assert.NotContains(t, resp.File[0].GetContent(), "file_descriptor_set.OtherMessage")
+}
+
+func TestPostProcessCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands in this test need a POSIX shell")
+	}
+	convert := func(parameter string) (*pluginpb.CodeGeneratorResponse, error) {
+		req := loadRequest(t, "standard/helloworld.proto")
+		req.Parameter = proto.String(parameter)
+		return converter.Convert(req)
+	}
+
+	resp, err := convert(`post-process-cmd=sed "s/title: .*/title: Patched $OPENAPI_FORMAT $OPENAPI_PATH/",path=test.openapi.yaml`)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 1)
+	assert.Contains(t, resp.File[0].GetContent(), "title: Patched yaml test.openapi.yaml")
+	assert.Contains(t, resp.File[0].GetContent(), "/helloworld.Greeter/WriteHello")
+
+	_, err = convert(`post-process-cmd=echo not allowed >&2; exit 3`)
+	assert.ErrorContains(t, err, "post-process-cmd failed for standard/helloworld.openapi.yaml")
+	assert.ErrorContains(t, err, "not allowed")
+
+	_, err = convert(`post-process-cmd=cat >/dev/null`)
+	assert.ErrorContains(t, err, "post-process-cmd wrote nothing")
+}
+
+func BenchmarkConvert(b *testing.B) {
+	f, err := os.ReadFile(filepath.Join("testdata", "fileset.binpb"))
+	require.NoError(b, err)
+	pf := new(descriptorpb.FileDescriptorSet)
+	require.NoError(b, proto.Unmarshal(f, pf))
+	req := &pluginpb.CodeGeneratorRequest{ProtoFile: pf.GetFile()}
+	for _, f := range pf.GetFile() {
+		if strings.HasPrefix(f.GetName(), "standard/") {
+			req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+		}
+	}
+	opts, err := options.FromString("path=all.openapi.yaml,allow-get,with-streaming")
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := converter.ConvertWithOptions(req, opts)
+		require.NoError(b, err)
+	}
+}
+
+func TestLogOption(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "gen.log")
+	req := loadRequest(t, "standard/helloworld.proto")
+	req.Parameter = proto.String("log=level:debug,format:json,file:" + logFile)
+	_, err := converter.Convert(req)
+	require.NoError(t, err)
+
+	body, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		assert.Contains(t, entry, "level")
+	}
+	assert.Contains(t, string(body), `"msg":"messageToSchema"`)
+}
+
+type panickingTransformer struct {
+	pkg protoreflect.FullName
+}
+
+func (p panickingTransformer) TransformSchema(schema *base.Schema, desc protoreflect.MessageDescriptor) *base.Schema {
+	if desc.ParentFile().Package() == p.pkg {
+		panic("unexpected message")
+	}
+	return schema
+}
+
+func TestConvertPanics(t *testing.T) {
+	req := loadRequest(t, "standard/helloworld.proto", "trace_headers/trace_headers.proto")
+
+	opts := options.NewOptions()
+	require.NoError(t, opts.AddTransformer(panickingTransformer{pkg: "helloworld"}))
+	_, err := converter.ConvertWithOptions(req, opts)
+	require.ErrorContains(t, err, "standard/helloworld.proto: panic: unexpected message")
+
+	opts.KeepGoing = true
+	resp, err := converter.ConvertWithOptions(req, opts)
+	require.NoError(t, err)
+	require.Len(t, resp.File, 1)
+	assert.Equal(t, "trace_headers/trace_headers.openapi.yaml", resp.File[0].GetName())
+
+	// A merged document can't skip a file, since the file could have added part of its content.
+	opts.Path = "merged.openapi.yaml"
+	_, err = converter.ConvertWithOptions(req, opts)
+	require.ErrorContains(t, err, "standard/helloworld.proto: panic: unexpected message")
+}
+
+func TestWhy(t *testing.T) {
+	why := func(params string) string {
+		logFile := filepath.Join(t.TempDir(), "gen.log")
+		req := loadRequest(t, "standard/helloworld.proto")
+		req.Parameter = proto.String(params + ",log=level:info;file:" + logFile)
+		_, err := converter.Convert(req)
+		require.NoError(t, err)
+		body, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	logs := why("why=components.schemas.connect.error")
+	assert.Contains(t, logs, "why: components.schemas.connect.error was added by the messages, enums and Connect schemas of standard/helloworld.proto")
+
+	logs = why("why=/components/schemas/helloworld.HelloRequest,inline-threshold=5")
+	assert.Contains(t, logs, "why: /components/schemas/helloworld.HelloRequest was added by the messages, enums and Connect schemas of standard/helloworld.proto")
+	assert.Contains(t, logs, "why: /components/schemas/helloworld.HelloRequest was removed by inline-threshold")
+
+	logs = why("why=paths./helloworld.Greeter/WriteHello.post.responses.default,default-response=off")
+	assert.Contains(t, logs, "why: paths./helloworld.Greeter/WriteHello.post.responses.default was added by the methods of standard/helloworld.proto")
+	assert.Contains(t, logs, "why: paths./helloworld.Greeter/WriteHello.post.responses.default was removed by the default responses (default-response)")
+
+	logs = why("why=components.schemas.missing")
+	assert.Contains(t, logs, "why: components.schemas.missing isn't in any generated document")
+}
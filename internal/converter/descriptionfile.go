@@ -0,0 +1,46 @@
+package converter
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// descriptionFilePath returns the description-file option for a proto file, with {package_dir} replaced by the
+// directory of the file and {package} by its package, like acme/books/v1/README.md.
+func descriptionFilePath(opts options.Options, fd protoreflect.FileDescriptor) string {
+	return strings.NewReplacer(
+		"{package_dir}", path.Dir(fd.Path()),
+		"{package}", string(fd.Package()),
+	).Replace(opts.DescriptionFile)
+}
+
+// readDescriptionFile reads a description file. Packages don't need to have one, so a missing file is empty.
+func readDescriptionFile(name string) (string, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// prependDescription puts markdown at the head of info.description, before the comments of the file.
+func prependDescription(spec *v3.Document, markdown string) {
+	if markdown == "" {
+		return
+	}
+	if spec.Info.Description != "" {
+		markdown += "\n\n" + spec.Info.Description
+	}
+	spec.Info.Description = markdown
+}
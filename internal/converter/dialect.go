@@ -0,0 +1,222 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// applyJSONSchemaDialect sets jsonSchemaDialect and rewrites the keywords of every schema, webhooks included, that the
+// dialect doesn't define. nullable isn't part of any OpenAPI 3.1 dialect, so it becomes a "null" type. The plain JSON Schema 2020-12
+// dialect also has no example, discriminator, xml or externalDocs keywords: example becomes examples and the others
+// are removed.
+func applyJSONSchemaDialect(opts options.Options, spec *v3.Document) error {
+	if !strings.HasPrefix(spec.Version, "3.1") {
+		return fmt.Errorf("json-schema-dialect needs OpenAPI 3.1, not %s", spec.Version)
+	}
+	spec.JsonSchemaDialect = opts.JSONSchemaDialect
+	strict := opts.JSONSchemaDialect == options.JSONSchemaDialect202012
+	forEachSchema(spec, func(s *base.Schema) {
+		if s.Nullable != nil {
+			if *s.Nullable && len(s.Type) > 0 && !slices.Contains(s.Type, "null") {
+				s.Type = append(s.Type, "null")
+			}
+			s.Nullable = nil
+		}
+		if !strict {
+			return
+		}
+		if s.Example != nil {
+			s.Examples = append([]*yaml.Node{s.Example}, s.Examples...)
+			s.Example = nil
+		}
+		s.Discriminator = nil
+		s.XML = nil
+		s.ExternalDocs = nil
+	})
+	return nil
+}
+
+// forEachSchema calls fn once for every schema in the spec, including the subschemas of other schemas.
+func forEachSchema(spec *v3.Document, fn func(s *base.Schema)) {
+	walkSchemas(spec, nil, fn)
+}
+
+// walkSchemas calls fn once for every schema in the spec, including the subschemas of other schemas. When replaceRef
+// isn't nil, every reference is replaced with the schema proxy it returns, which is walked in turn.
+func walkSchemas(spec *v3.Document, replaceRef func(ref *base.SchemaProxy) *base.SchemaProxy, fn func(s *base.Schema)) {
+	newSchemaWalker(replaceRef, fn).document(spec)
+}
+
+// schemaWalker walks the schemas of a spec, or of a part of it, visiting every schema once. When element isn't nil,
+// it's also called with every path item, operation, parameter, header, request body and media type on the way.
+type schemaWalker struct {
+	replaceRef func(ref *base.SchemaProxy) *base.SchemaProxy
+	fn         func(s *base.Schema)
+	element    func(element any)
+	seen       map[*base.Schema]struct{}
+}
+
+func newSchemaWalker(replaceRef func(ref *base.SchemaProxy) *base.SchemaProxy, fn func(s *base.Schema)) *schemaWalker {
+	return &schemaWalker{replaceRef: replaceRef, fn: fn, seen: map[*base.Schema]struct{}{}}
+}
+
+func (w *schemaWalker) document(spec *v3.Document) {
+	if spec.Paths != nil {
+		for item := range spec.Paths.PathItems.ValuesFromOldest() {
+			w.pathItem(item)
+		}
+	}
+	for item := range spec.Webhooks.ValuesFromOldest() {
+		w.pathItem(item)
+	}
+	if components := spec.Components; components != nil {
+		w.schemas(components.Schemas)
+		for param := range components.Parameters.ValuesFromOldest() {
+			w.parameter(param)
+		}
+		for header := range components.Headers.ValuesFromOldest() {
+			w.header(header)
+		}
+		for response := range components.Responses.ValuesFromOldest() {
+			w.response(response)
+		}
+		for body := range components.RequestBodies.ValuesFromOldest() {
+			w.requestBody(body)
+		}
+	}
+}
+
+func (w *schemaWalker) pathItem(item *v3.PathItem) {
+	if item == nil {
+		return
+	}
+	w.visit(item)
+	for _, param := range item.Parameters {
+		w.parameter(param)
+	}
+	for op := range item.GetOperations().ValuesFromOldest() {
+		if op == nil {
+			continue
+		}
+		w.visit(op)
+		for _, param := range op.Parameters {
+			w.parameter(param)
+		}
+		w.requestBody(op.RequestBody)
+		if op.Responses != nil {
+			w.response(op.Responses.Default)
+			for response := range op.Responses.Codes.ValuesFromOldest() {
+				w.response(response)
+			}
+		}
+	}
+}
+
+func (w *schemaWalker) parameter(param *v3.Parameter) {
+	if param != nil {
+		w.visit(param)
+		param.Schema = w.schema(param.Schema)
+		w.content(param.Content)
+	}
+}
+
+func (w *schemaWalker) header(header *v3.Header) {
+	if header != nil {
+		w.visit(header)
+		header.Schema = w.schema(header.Schema)
+		w.content(header.Content)
+	}
+}
+
+func (w *schemaWalker) response(response *v3.Response) {
+	if response == nil {
+		return
+	}
+	for header := range response.Headers.ValuesFromOldest() {
+		w.header(header)
+	}
+	w.content(response.Content)
+}
+
+func (w *schemaWalker) requestBody(body *v3.RequestBody) {
+	if body != nil {
+		w.visit(body)
+		w.content(body.Content)
+	}
+}
+
+func (w *schemaWalker) content(content *orderedmap.Map[string, *v3.MediaType]) {
+	for mediaType := range content.ValuesFromOldest() {
+		if mediaType != nil {
+			w.visit(mediaType)
+			mediaType.Schema = w.schema(mediaType.Schema)
+		}
+	}
+}
+
+func (w *schemaWalker) visit(element any) {
+	if w.element != nil {
+		w.element(element)
+	}
+}
+
+func (w *schemaWalker) schemas(m *orderedmap.Map[string, *base.SchemaProxy]) {
+	for name, sub := range m.FromOldest() {
+		m.Set(name, w.schema(sub))
+	}
+}
+
+func (w *schemaWalker) schema(proxy *base.SchemaProxy) *base.SchemaProxy {
+	if proxy == nil {
+		return nil
+	}
+	if proxy.IsReference() {
+		if w.replaceRef == nil {
+			return proxy
+		}
+		if proxy = w.replaceRef(proxy); proxy == nil || proxy.IsReference() {
+			return proxy
+		}
+	}
+	s := proxy.Schema()
+	if s == nil {
+		return proxy
+	}
+	if _, ok := w.seen[s]; ok {
+		return proxy
+	}
+	w.seen[s] = struct{}{}
+	if w.fn != nil {
+		w.fn(s)
+	}
+
+	for _, proxies := range [][]*base.SchemaProxy{s.AllOf, s.OneOf, s.AnyOf, s.PrefixItems} {
+		for i, sub := range proxies {
+			proxies[i] = w.schema(sub)
+		}
+	}
+	s.Not = w.schema(s.Not)
+	s.Contains = w.schema(s.Contains)
+	s.If = w.schema(s.If)
+	s.Then = w.schema(s.Then)
+	s.Else = w.schema(s.Else)
+	s.PropertyNames = w.schema(s.PropertyNames)
+	w.schemas(s.Properties)
+	w.schemas(s.PatternProperties)
+	w.schemas(s.DependentSchemas)
+	if s.Items != nil && s.Items.IsA() {
+		s.Items.A = w.schema(s.Items.A)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.IsA() {
+		s.AdditionalProperties.A = w.schema(s.AdditionalProperties.A)
+	}
+	return proxy
+}
@@ -0,0 +1,202 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// DuplicatesReport lists messages whose schemas are the same or almost the same, so teams can find duplicated DTOs
+// to consolidate.
+type DuplicatesReport struct {
+	// Identical groups messages with the same structure: the same properties with the same types. Referenced
+	// messages are compared by structure too, so two Address messages in different packages are the same type.
+	Identical []DuplicateGroup `json:"identical"`
+	// Similar groups messages with the same property names whose types differ. Messages that are identical are only
+	// listed in Identical.
+	Similar []DuplicateGroup `json:"similar"`
+}
+
+// DuplicateGroup is a set of messages that have the same hash.
+type DuplicateGroup struct {
+	Hash       string   `json:"hash"`
+	Properties []string `json:"properties"`
+	Messages   []string `json:"messages"`
+}
+
+// structureHasher hashes the structure of messages. Hashes are remembered, so every message is only walked once.
+type structureHasher struct {
+	opts    options.Options
+	hashes  map[protoreflect.FullName]string
+	walking map[protoreflect.FullName]struct{}
+}
+
+func newStructureHasher(opts options.Options) *structureHasher {
+	return &structureHasher{
+		opts:    opts,
+		hashes:  map[protoreflect.FullName]string{},
+		walking: map[protoreflect.FullName]struct{}{},
+	}
+}
+
+// hash returns a hash of the properties of a message, their types and whether they're lists or maps.
+func (h *structureHasher) hash(md protoreflect.MessageDescriptor) string {
+	if hash, ok := h.hashes[md.FullName()]; ok {
+		return hash
+	}
+	// Recursive messages refer to themselves by name, which is the best we can do without unrolling them
+	if _, ok := h.walking[md.FullName()]; ok {
+		return "recursive:" + string(md.FullName())
+	}
+	h.walking[md.FullName()] = struct{}{}
+	defer delete(h.walking, md.FullName())
+
+	props := []string{}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		props = append(props, util.MakeFieldName(h.opts, field)+"="+h.fieldType(field))
+	}
+	sort.Strings(props)
+	sum := sha256.Sum256([]byte(strings.Join(props, "\n")))
+	hash := hex.EncodeToString(sum[:8])
+	h.hashes[md.FullName()] = hash
+	return hash
+}
+
+func (h *structureHasher) fieldType(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return "map<" + h.fieldType(field.MapKey()) + "," + h.fieldType(field.MapValue()) + ">"
+	case field.IsList():
+		return "repeated " + h.singularType(field)
+	}
+	return h.singularType(field)
+}
+
+func (h *structureHasher) singularType(field protoreflect.FieldDescriptor) string {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// Well-known types have a special JSON representation, so they're only the same as themselves
+		if util.IsWellKnown(field.Message()) {
+			return string(field.Message().FullName())
+		}
+		return "message " + h.hash(field.Message())
+	case protoreflect.EnumKind:
+		values := []string{}
+		for i := 0; i < field.Enum().Values().Len(); i++ {
+			values = append(values, string(field.Enum().Values().Get(i).Name()))
+		}
+		sort.Strings(values)
+		return "enum(" + strings.Join(values, "|") + ")"
+	}
+	return field.Kind().String()
+}
+
+// propertyNames returns the sorted property names of a message.
+func propertyNames(opts options.Options, md protoreflect.MessageDescriptor) []string {
+	names := []string{}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		names = append(names, util.MakeFieldName(opts, fields.Get(i)))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectReportMessages adds a message and its nested messages to the messages of the duplicates report. Map entries
+// and messages without fields, like requests with no parameters, are left out.
+func collectReportMessages(messages map[protoreflect.FullName]protoreflect.MessageDescriptor, md protoreflect.MessageDescriptor) {
+	if md.IsMapEntry() {
+		return
+	}
+	if md.Fields().Len() > 0 {
+		messages[md.FullName()] = md
+	}
+	for i := 0; i < md.Messages().Len(); i++ {
+		collectReportMessages(messages, md.Messages().Get(i))
+	}
+}
+
+func duplicatesReport(opts options.Options, messages map[protoreflect.FullName]protoreflect.MessageDescriptor) DuplicatesReport {
+	names := make([]string, 0, len(messages))
+	for name := range messages {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	hasher := newStructureHasher(opts)
+	identical := map[string][]string{}
+	similar := map[string][]string{}
+	properties := map[string][]string{}
+	for _, name := range names {
+		md := messages[protoreflect.FullName(name)]
+		hash := hasher.hash(md)
+		identical[hash] = append(identical[hash], name)
+		props := propertyNames(opts, md)
+		sum := sha256.Sum256([]byte(strings.Join(props, "\n")))
+		namesHash := hex.EncodeToString(sum[:8])
+		similar[namesHash] = append(similar[namesHash], name)
+		properties[hash] = props
+		properties[namesHash] = props
+	}
+
+	report := DuplicatesReport{Identical: []DuplicateGroup{}, Similar: []DuplicateGroup{}}
+	inIdentical := map[string]struct{}{}
+	for _, hash := range sortedGroupKeys(identical) {
+		report.Identical = append(report.Identical, DuplicateGroup{Hash: hash, Properties: properties[hash], Messages: identical[hash]})
+		for _, name := range identical[hash] {
+			inIdentical[name] = struct{}{}
+		}
+	}
+	for _, hash := range sortedGroupKeys(similar) {
+		// A group of similar messages is only interesting if it isn't a single group of identical ones
+		if len(similar[hash]) < 2 || groupIsIdentical(similar[hash], hasher, messages) {
+			continue
+		}
+		report.Similar = append(report.Similar, DuplicateGroup{Hash: hash, Properties: properties[hash], Messages: similar[hash]})
+	}
+	return report
+}
+
+// sortedGroupKeys returns the keys of the groups with more than one message, ordered by their first message.
+func sortedGroupKeys(groups map[string][]string) []string {
+	keys := []string{}
+	for key, names := range groups {
+		if len(names) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return groups[keys[i]][0] < groups[keys[j]][0]
+	})
+	return keys
+}
+
+func groupIsIdentical(names []string, hasher *structureHasher, messages map[protoreflect.FullName]protoreflect.MessageDescriptor) bool {
+	first := hasher.hash(messages[protoreflect.FullName(names[0])])
+	for _, name := range names[1:] {
+		if hasher.hash(messages[protoreflect.FullName(name)]) != first {
+			return false
+		}
+	}
+	return true
+}
+
+func duplicatesReportFile(name string, report DuplicatesReport) (*pluginpb.CodeGeneratorResponse_File, error) {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("duplicates report: %w", err)
+	}
+	content := string(b) + "\n"
+	return newFile(name, content), nil
+}
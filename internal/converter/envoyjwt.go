@@ -0,0 +1,238 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// envoyJWTConfig is the part of the envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication config that
+// describes authentication. The config can be given on its own or as the whole HTTP filter with a typed_config.
+type envoyJWTConfig struct {
+	TypedConfig    *envoyJWTConfig                `yaml:"typed_config"`
+	Providers      map[string]envoyJWTProvider    `yaml:"providers"`
+	Rules          []envoyJWTRule                 `yaml:"rules"`
+	RequirementMap map[string]envoyJWTRequirement `yaml:"requirement_map"`
+}
+
+type envoyJWTProvider struct {
+	Issuer     string   `yaml:"issuer"`
+	Audiences  []string `yaml:"audiences"`
+	RemoteJWKS struct {
+		HTTPURI struct {
+			URI string `yaml:"uri"`
+		} `yaml:"http_uri"`
+	} `yaml:"remote_jwks"`
+	FromHeaders []struct {
+		Name        string `yaml:"name"`
+		ValuePrefix string `yaml:"value_prefix"`
+	} `yaml:"from_headers"`
+	FromParams []string `yaml:"from_params"`
+}
+
+type envoyJWTRule struct {
+	Match struct {
+		Prefix              string `yaml:"prefix"`
+		Path                string `yaml:"path"`
+		PathSeparatedPrefix string `yaml:"path_separated_prefix"`
+		SafeRegex           *struct {
+			Regex string `yaml:"regex"`
+		} `yaml:"safe_regex"`
+	} `yaml:"match"`
+	Requires        *envoyJWTRequirement `yaml:"requires"`
+	RequirementName string               `yaml:"requirement_name"`
+}
+
+type envoyJWTRequirement struct {
+	ProviderName         string `yaml:"provider_name"`
+	ProviderAndAudiences *struct {
+		ProviderName string `yaml:"provider_name"`
+	} `yaml:"provider_and_audiences"`
+	RequiresAny *struct {
+		Requirements []envoyJWTRequirement `yaml:"requirements"`
+	} `yaml:"requires_any"`
+	RequiresAll *struct {
+		Requirements []envoyJWTRequirement `yaml:"requirements"`
+	} `yaml:"requires_all"`
+	AllowMissing         *struct{} `yaml:"allow_missing"`
+	AllowMissingOrFailed *struct{} `yaml:"allow_missing_or_failed"`
+}
+
+// applyEnvoyJWTConfig adds a security scheme for every provider of an Envoy jwt_authn filter config and sets the
+// security of each operation from the first rule that matches its path. Path templates are matched by treating each
+// path parameter as a single segment. Schemes and operation security that are already set are kept.
+func applyEnvoyJWTConfig(spec *v3.Document, content []byte) error {
+	config := &envoyJWTConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return fmt.Errorf("unable to parse the Envoy JWT config: %w", err)
+	}
+	if config.TypedConfig != nil {
+		config = config.TypedConfig
+	}
+
+	if spec.Components == nil {
+		spec.Components = &v3.Components{}
+	}
+	if spec.Components.SecuritySchemes == nil {
+		spec.Components.SecuritySchemes = orderedmap.New[string, *v3.SecurityScheme]()
+	}
+	names := make([]string, 0, len(config.Providers))
+	for name := range config.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := spec.Components.SecuritySchemes.Get(name); ok {
+			continue
+		}
+		spec.Components.SecuritySchemes.Set(name, envoyJWTSecurityScheme(config.Providers[name]))
+	}
+
+	rules := make([]envoyJWTRouteRule, 0, len(config.Rules))
+	for i, rule := range config.Rules {
+		matcher, err := envoyJWTMatcher(rule)
+		if err != nil {
+			return fmt.Errorf("rule %d of the Envoy JWT config: %w", i, err)
+		}
+		requires := rule.Requires
+		if rule.RequirementName != "" {
+			req, ok := config.RequirementMap[rule.RequirementName]
+			if !ok {
+				return fmt.Errorf("rule %d of the Envoy JWT config: unknown requirement_name '%s'", i, rule.RequirementName)
+			}
+			requires = &req
+		}
+		security := []*base.SecurityRequirement{}
+		if requires != nil {
+			for _, providers := range envoyJWTAlternatives(*requires) {
+				reqs := orderedmap.New[string, []string]()
+				for _, provider := range providers {
+					reqs.Set(provider, []string{})
+				}
+				security = append(security, &base.SecurityRequirement{
+					Requirements:             reqs,
+					ContainsEmptyRequirement: len(providers) == 0,
+				})
+			}
+		}
+		rules = append(rules, envoyJWTRouteRule{matches: matcher, security: security})
+	}
+
+	if spec.Paths == nil {
+		return nil
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		sample := pathParamPattern.ReplaceAllString(path, "x")
+		for op := range item.GetOperations().ValuesFromOldest() {
+			if op == nil || op.Security != nil {
+				continue
+			}
+			for _, rule := range rules {
+				if rule.matches(sample) {
+					op.Security = rule.security
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pathParamPattern matches the parameters of an OpenAPI path template.
+var pathParamPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+type envoyJWTRouteRule struct {
+	matches  func(path string) bool
+	security []*base.SecurityRequirement
+}
+
+func envoyJWTMatcher(rule envoyJWTRule) (func(string) bool, error) {
+	match := rule.Match
+	switch {
+	case match.Path != "":
+		return func(path string) bool { return path == match.Path }, nil
+	case match.PathSeparatedPrefix != "":
+		return func(path string) bool {
+			return path == match.PathSeparatedPrefix || strings.HasPrefix(path, strings.TrimSuffix(match.PathSeparatedPrefix, "/")+"/")
+		}, nil
+	case match.SafeRegex != nil:
+		re, err := regexp.Compile("^(?:" + match.SafeRegex.Regex + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	default:
+		return func(path string) bool { return strings.HasPrefix(path, match.Prefix) }, nil
+	}
+}
+
+// envoyJWTAlternatives returns the sets of providers that satisfy a requirement. An empty set means a request
+// without a token is allowed.
+func envoyJWTAlternatives(req envoyJWTRequirement) [][]string {
+	switch {
+	case req.ProviderName != "":
+		return [][]string{{req.ProviderName}}
+	case req.ProviderAndAudiences != nil:
+		return [][]string{{req.ProviderAndAudiences.ProviderName}}
+	case req.RequiresAny != nil:
+		res := [][]string{}
+		for _, r := range req.RequiresAny.Requirements {
+			res = append(res, envoyJWTAlternatives(r)...)
+		}
+		return res
+	case req.RequiresAll != nil:
+		res := [][]string{{}}
+		for _, r := range req.RequiresAll.Requirements {
+			combined := [][]string{}
+			for _, prefix := range res {
+				for _, alt := range envoyJWTAlternatives(r) {
+					combined = append(combined, append(append([]string{}, prefix...), alt...))
+				}
+			}
+			res = combined
+		}
+		return res
+	case req.AllowMissing != nil, req.AllowMissingOrFailed != nil:
+		return [][]string{{}}
+	}
+	return nil
+}
+
+// envoyJWTSecurityScheme describes where a provider reads tokens from. Providers with an issuer use OpenID Connect
+// discovery, which also publishes the JWKS URI.
+func envoyJWTSecurityScheme(provider envoyJWTProvider) *v3.SecurityScheme {
+	var details []string
+	if provider.Issuer != "" {
+		details = append(details, "Issuer: "+provider.Issuer)
+	}
+	if uri := provider.RemoteJWKS.HTTPURI.URI; uri != "" {
+		details = append(details, "JWKS: "+uri)
+	}
+	if len(provider.Audiences) > 0 {
+		details = append(details, "Audiences: "+strings.Join(provider.Audiences, ", "))
+	}
+	description := strings.Join(details, "\n\n")
+
+	for _, header := range provider.FromHeaders {
+		if !strings.EqualFold(header.Name, "Authorization") {
+			return &v3.SecurityScheme{Type: "apiKey", In: "header", Name: header.Name, Description: description}
+		}
+	}
+	if len(provider.FromHeaders) == 0 && len(provider.FromParams) > 0 {
+		return &v3.SecurityScheme{Type: "apiKey", In: "query", Name: provider.FromParams[0], Description: description}
+	}
+	if provider.Issuer != "" && strings.HasPrefix(provider.Issuer, "https://") {
+		return &v3.SecurityScheme{
+			Type:             "openIdConnect",
+			OpenIdConnectUrl: oidcDiscoveryURL(provider.Issuer),
+			Description:      description,
+		}
+	}
+	return &v3.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: description}
+}
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// applyETags documents the conditional requests of AIP-154 for resources with an etag field. Reads of a resource
+// with an etag return it in the ETag header and accept If-None-Match, which results in a 304 when the resource
+// hasn't changed. Writes whose request has an etag, directly or on the resource, accept If-Match and fail with a
+// 412 when the resource has changed since it was read.
+func applyETags(opts options.Options, method protoreflect.MethodDescriptor, httpMethod string, op *v3.Operation, isStreaming bool) {
+	if isStreaming {
+		return
+	}
+	if hasETag(method.Output()) && op.Responses != nil && op.Responses.Codes != nil {
+		if response, ok := op.Responses.Codes.Get("200"); ok && response != nil {
+			if response.Headers == nil {
+				response.Headers = orderedmap.New[string, *v3.Header]()
+			}
+			response.Headers.Set("ETag", &v3.Header{
+				Description: "The etag of the resource, for conditional requests with If-Match and If-None-Match.",
+				Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+			})
+		}
+		if strings.ToUpper(httpMethod) == http.MethodGet {
+			op.Parameters = append(op.Parameters, &v3.Parameter{
+				Name:        "If-None-Match",
+				In:          "header",
+				Description: "Only return the resource if its etag doesn't match this value.",
+				Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+			})
+			setResponse(op, "304", &v3.Response{
+				Description: "Not Modified: the etag of the resource matches If-None-Match",
+			})
+		}
+	}
+	if isMutatingOperation(opts, httpMethod, method) && requestHasETag(method.Input()) {
+		op.Parameters = append(op.Parameters, &v3.Parameter{
+			Name:        "If-Match",
+			In:          "header",
+			Description: "Only apply the change if the etag of the resource matches this value.",
+			Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+		})
+		setResponse(op, "412", &v3.Response{
+			Description: "Precondition Failed: the etag does not match the current etag of the resource",
+			Content: util.MakeMediaTypes(
+				opts,
+				base.CreateSchemaProxyRef(opts.ErrorSchemaRef()),
+				false,
+				isStreaming,
+			),
+		})
+	}
+}
+
+// hasETag returns true if a message has a string field named etag, like AIP-154 resources.
+func hasETag(msg protoreflect.MessageDescriptor) bool {
+	field := msg.Fields().ByName("etag")
+	return field != nil && field.Kind() == protoreflect.StringKind && !field.IsList()
+}
+
+// requestHasETag returns true if a request has an etag, like delete requests, or has a resource with an etag, like
+// update requests.
+func requestHasETag(msg protoreflect.MessageDescriptor) bool {
+	if hasETag(msg) {
+		return true
+	}
+	for i := 0; i < msg.Fields().Len(); i++ {
+		field := msg.Fields().Get(i)
+		if field.Message() == nil || field.IsList() || field.IsMap() || util.IsWellKnown(field.Message()) {
+			continue
+		}
+		if hasETag(field.Message()) {
+			return true
+		}
+	}
+	return false
+}
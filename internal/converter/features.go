@@ -0,0 +1,149 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/googleapi"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/protovalidate"
+)
+
+// FeaturesReport lists the features of the input that change the output, with the options that go with them, so
+// users know which options they need before they read the generated documents.
+type FeaturesReport struct {
+	Features []Feature `json:"features"`
+}
+
+// Feature is a feature of the input that is used, like streaming methods or google.api.http rules.
+type Feature struct {
+	Name string `json:"name"`
+	// Advice explains how the feature is documented and what the options change.
+	Advice string `json:"advice"`
+	// Options are the plugin parameters that change how the feature is documented.
+	Options []string `json:"options"`
+	// Uses are the full names of the files, messages, fields or methods that use the feature.
+	Uses []string `json:"uses"`
+}
+
+// featureAdvice describes every feature that the report detects, in the order they're reported.
+var featureAdvice = []Feature{
+	{
+		Name:    "streaming-methods",
+		Advice:  "Streaming methods are left out unless with-streaming is set, which documents them with the Connect streaming content types.",
+		Options: []string{"with-streaming"},
+	},
+	{
+		Name:    "side-effect-free-methods",
+		Advice:  "Methods with idempotency_level = NO_SIDE_EFFECTS can be called with GET requests, which allow-get documents.",
+		Options: []string{"allow-get"},
+	},
+	{
+		Name:    "google-api-http",
+		Advice:  "Methods with google.api.http rules are documented with their HTTP paths instead of their Connect paths. Set flavor to the runtime that serves the rules, with-connect-paths to document both, or ignore-googleapi-http to only document the Connect paths.",
+		Options: []string{"flavor", "with-connect-paths", "ignore-googleapi-http"},
+	},
+	{
+		Name:    "any-fields",
+		Advice:  "google.protobuf.Any fields are documented as objects with a @type property and any other properties, since the type of their value isn't known. The x-any-types property extension restricts a field to the message types it can hold.",
+		Options: []string{},
+	},
+	{
+		Name:    "protovalidate",
+		Advice:  "protovalidate rules become JSON Schema constraints. Descriptions of the constraints, the 400 response of invalid requests and the raw rules can be added too.",
+		Options: []string{"with-constraint-descriptions", "with-validation-errors", "with-connect-validation"},
+	},
+	{
+		Name:    "editions",
+		Advice:  "Files that use editions decide field presence with features, which changes which properties are optional.",
+		Options: []string{},
+	},
+}
+
+// featureUses collects the uses of every feature, by feature name.
+type featureUses map[string]map[string]struct{}
+
+func (u featureUses) add(feature, name string) {
+	if u[feature] == nil {
+		u[feature] = map[string]struct{}{}
+	}
+	u[feature][name] = struct{}{}
+}
+
+// collectFeatures adds the uses of features in a file.
+func collectFeatures(uses featureUses, fd protoreflect.FileDescriptor) {
+	if fd.Syntax() == protoreflect.Editions {
+		uses.add("editions", fd.Path())
+	}
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			method := methods.Get(j)
+			if method.IsStreamingClient() || method.IsStreamingServer() {
+				uses.add("streaming-methods", string(method.FullName()))
+			}
+			if options, ok := method.Options().(*descriptorpb.MethodOptions); ok && options.GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS {
+				uses.add("side-effect-free-methods", string(method.FullName()))
+			}
+			if len(googleapi.HTTPRules(method)) > 0 {
+				uses.add("google-api-http", string(method.FullName()))
+			}
+		}
+	}
+	messages := fd.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		collectMessageFeatures(uses, messages.Get(i))
+	}
+}
+
+func collectMessageFeatures(uses featureUses, md protoreflect.MessageDescriptor) {
+	if md.IsMapEntry() {
+		return
+	}
+	if paths, _ := protovalidate.ViolationFieldPaths(md); len(paths) > 0 {
+		uses.add("protovalidate", string(md.FullName()))
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.IsMap() {
+			field = field.MapValue()
+		}
+		if field.Message() != nil && field.Message().FullName() == "google.protobuf.Any" {
+			uses.add("any-fields", string(fields.Get(i).FullName()))
+		}
+	}
+	for i := 0; i < md.Messages().Len(); i++ {
+		collectMessageFeatures(uses, md.Messages().Get(i))
+	}
+}
+
+func featuresReport(uses featureUses) FeaturesReport {
+	report := FeaturesReport{Features: []Feature{}}
+	for _, feature := range featureAdvice {
+		if len(uses[feature.Name]) == 0 {
+			continue
+		}
+		feature.Uses = make([]string, 0, len(uses[feature.Name]))
+		for name := range uses[feature.Name] {
+			feature.Uses = append(feature.Uses, name)
+		}
+		sort.Strings(feature.Uses)
+		report.Features = append(report.Features, feature)
+	}
+	return report
+}
+
+func featuresReportFile(name string, report FeaturesReport) (*pluginpb.CodeGeneratorResponse_File, error) {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("features report: %w", err)
+	}
+	content := string(b) + "\n"
+	return newFile(name, content), nil
+}
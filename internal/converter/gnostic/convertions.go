@@ -1,15 +1,12 @@
 package gnostic
 
 import (
-	"strconv"
-
 	goa3 "github.com/google/gnostic/openapiv3"
 	base "github.com/pb33f/libopenapi/datamodel/high/base"
 	highv3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/libopenapi/orderedmap"
-	"github.com/pb33f/libopenapi/utils"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 	"gopkg.in/yaml.v3"
 )
@@ -102,6 +99,9 @@ func toRequestBodiesMap(bodies *goa3.RequestBodiesOrReferences) *orderedmap.Map[
 }
 
 func toRequestBody(rbody *goa3.RequestBody) *v3.RequestBody {
+	if rbody == nil {
+		return nil
+	}
 	return &v3.RequestBody{
 		Description: rbody.Description,
 		Content:     toMediaTypes(rbody.GetContent()),
@@ -277,7 +277,7 @@ func toSchema(s *goa3.Schema) *base.Schema {
 	if s == nil {
 		return nil
 	}
-	return schemaWithAnnotations(&base.Schema{}, s)
+	return schemaWithAnnotations(merger{strategy: options.OverrideMerge}, &base.Schema{}, s)
 }
 
 func toDefault(dt *goa3.DefaultType) *yaml.Node {
@@ -286,14 +286,11 @@ func toDefault(dt *goa3.DefaultType) *yaml.Node {
 	}
 	switch dt.GetOneof().(type) {
 	case *goa3.DefaultType_Number:
-		return utils.CreateStringNode(strconv.FormatFloat(dt.GetNumber(), 'f', -1, 64))
+		return util.FloatNode(dt.GetNumber())
 	case *goa3.DefaultType_String_:
-		return utils.CreateStringNode(dt.GetString_())
+		return util.StringNode(dt.GetString_())
 	case *goa3.DefaultType_Boolean:
-		if dt.GetBoolean() {
-			return utils.CreateStringNode("true")
-		}
-		return utils.CreateStringNode("false")
+		return util.BoolNode(dt.GetBoolean())
 	default:
 		return nil
 	}
@@ -498,22 +495,7 @@ func toCallbacks(cbs *goa3.CallbacksOrReferences) *orderedmap.Map[string, *v3.Ca
 		callback := item.Value.GetCallback()
 		expressions := orderedmap.New[string, *v3.PathItem]()
 		for _, item := range callback.GetPath() {
-			expr := item.Value
-			expressions.Set(item.Name, &v3.PathItem{
-				Description: expr.Description,
-				Summary:     expr.Summary,
-				Get:         toOperation(expr.Get),
-				Put:         toOperation(expr.Put),
-				Post:        toOperation(expr.Post),
-				Delete:      toOperation(expr.Delete),
-				Options:     toOperation(expr.Options),
-				Head:        toOperation(expr.Head),
-				Patch:       toOperation(expr.Patch),
-				Trace:       toOperation(expr.Trace),
-				Servers:     toServers(expr.Servers),
-				Parameters:  toParameters(expr.Parameters),
-				Extensions:  toExtensions(expr.SpecificationExtension),
-			})
+			expressions.Set(item.Name, toPathItem(item.Value))
 		}
 		callbacks.Set(item.Name, &v3.Callback{
 			Expression: expressions,
@@ -523,6 +505,27 @@ func toCallbacks(cbs *goa3.CallbacksOrReferences) *orderedmap.Map[string, *v3.Ca
 	return callbacks
 }
 
+func toPathItem(item *goa3.PathItem) *v3.PathItem {
+	if item == nil {
+		return nil
+	}
+	return &v3.PathItem{
+		Description: item.Description,
+		Summary:     item.Summary,
+		Get:         toOperation(item.Get),
+		Put:         toOperation(item.Put),
+		Post:        toOperation(item.Post),
+		Delete:      toOperation(item.Delete),
+		Options:     toOperation(item.Options),
+		Head:        toOperation(item.Head),
+		Patch:       toOperation(item.Patch),
+		Trace:       toOperation(item.Trace),
+		Servers:     toServers(item.Servers),
+		Parameters:  toParameters(item.Parameters),
+		Extensions:  toExtensions(item.SpecificationExtension),
+	}
+}
+
 func toOperation(op *goa3.Operation) *v3.Operation {
 	if op == nil {
 		return nil
@@ -534,7 +537,7 @@ func toOperation(op *goa3.Operation) *v3.Operation {
 		ExternalDocs: toExternalDocs(op.ExternalDocs),
 		OperationId:  op.OperationId,
 		Parameters:   toParameters(op.Parameters),
-		RequestBody:  nil,
+		RequestBody:  toRequestBody(op.GetRequestBody().GetRequestBody()),
 		Responses:    toResponses(op.GetResponses()),
 		Callbacks:    toCallbacks(op.Callbacks),
 		Deprecated:   &op.Deprecated,
@@ -571,8 +574,15 @@ func toParameter(paramOrRef *goa3.ParameterOrReference) *v3.Parameter {
 		Explode:         &param.Explode,
 		AllowReserved:   param.AllowReserved,
 		Schema:          toSchemaOrReference(param.GetSchema()),
-		Example:         param.Example.ToRawInfo(),
+		Example:         toExample(param.Example),
 		Content:         toMediaTypes(param.GetContent()),
 		Extensions:      toExtensions(param.GetSpecificationExtension()),
 	}
 }
+
+func toExample(example *goa3.Any) *yaml.Node {
+	if example == nil {
+		return nil
+	}
+	return example.ToRawInfo()
+}
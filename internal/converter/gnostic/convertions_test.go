@@ -0,0 +1,32 @@
+package gnostic
+
+import (
+	"testing"
+
+	goa3 "github.com/google/gnostic/openapiv3"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		dt    *goa3.DefaultType
+		tag   string
+		value string
+	}{
+		{name: "number", dt: &goa3.DefaultType{Oneof: &goa3.DefaultType_Number{Number: 1.5}}, tag: "!!float", value: "1.5"},
+		{name: "string", dt: &goa3.DefaultType{Oneof: &goa3.DefaultType_String_{String_: "abc"}}, tag: "!!str", value: "abc"},
+		{name: "boolean", dt: &goa3.DefaultType{Oneof: &goa3.DefaultType_Boolean{Boolean: true}}, tag: "!!bool", value: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := toDefault(tt.dt)
+			assert.Equal(t, yaml.ScalarNode, node.Kind)
+			assert.Equal(t, tt.tag, node.Tag)
+			assert.Equal(t, tt.value, node.Value)
+		})
+	}
+	assert.Nil(t, toDefault(nil))
+	assert.Nil(t, toDefault(&goa3.DefaultType{}))
+}
@@ -0,0 +1,444 @@
+package gnostic
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	goa3 "github.com/google/gnostic/openapiv3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// ResponseMediaTypesExtension is an operation extension that maps additional response media types to the full
+// name of the message returned with that media type. It is consumed by the converter and not copied to the output.
+//
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{
+//	    name: "x-response-media-types"
+//	    value: {yaml: "application/vnd.example.v2+json: example.v2.GetBookResponse"}
+//	  }]
+//	};
+const ResponseMediaTypesExtension = "x-response-media-types"
+
+// RequestContentTypeExtension and ResponseContentTypeExtension are operation extensions that replace the media
+// types of the request body and the successful response of a method. Like ResponseMediaTypesExtension, they are
+// consumed by the converter and not copied to the output.
+//
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{
+//	    name: "x-response-content-type"
+//	    value: {yaml: "application/pdf"}
+//	  }]
+//	};
+const (
+	RequestContentTypeExtension  = "x-request-content-type"
+	ResponseContentTypeExtension = "x-response-content-type"
+)
+
+// FileTransferExtension is an operation extension that renders a method as a file upload or download even when
+// the with-file-transfers option isn't set. It is consumed by the converter and not copied to the output.
+//
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{name: "x-file-transfer", value: {yaml: "true"}}]
+//	};
+const FileTransferExtension = "x-file-transfer"
+
+// NoSideEffectsExtension is an operation extension that sets whether a method is free of side effects, which makes
+// it eligible for GET with the allow-get option. It wins over `idempotency_level` and over the method names that
+// infer-get-from-names treats as side-effect free. It is consumed by the converter and not copied to the output.
+//
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{name: "x-no-side-effects", value: {yaml: "false"}}]
+//	};
+const NoSideEffectsExtension = "x-no-side-effects"
+
+// MaxBodyBytesExtension is an operation extension with the maximum size of the request body in bytes, like the
+// limit of the gateway or server in front of the method. It wins over the max-body-bytes option. Unlike the other
+// extensions in this file it's kept in the output, so gateways and clients can read the limit from the spec.
+//
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{name: "x-max-body-bytes", value: {yaml: "1048576"}}]
+//	};
+const MaxBodyBytesExtension = "x-max-body-bytes"
+
+// WatchExtension is an operation extension that marks a watch method, which holds the request open until something
+// changes. It's either true or a map with the style and the timeout of the method. The style is long-poll (the
+// default), sse for a stream of text/event-stream events or chunked for a response that is written in chunks. The
+// timeout is a duration like 300s. Like MaxBodyBytesExtension, it's kept in the output.
+//
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{name: "x-watch", value: {yaml: "{style: sse, timeout: 300s}"}}]
+//	};
+const WatchExtension = "x-watch"
+
+// ReplacedByExtension is an operation extension on a deprecated method with the full name of the method that
+// replaces it. The operation gets a note about its successor and the extension is kept in the output with the
+// operationId of the successor, so clients and docs can link to it.
+//
+//	option deprecated = true;
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{name: "x-replaced-by", value: {yaml: "example.v2.BookService.GetBook"}}]
+//	};
+const ReplacedByExtension = "x-replaced-by"
+
+// LifecycleExtension is an operation extension with the dates in the lifecycle of a deprecated method: when it was
+// deprecated and when it stops working. The dates are like 2025-06-30 or 2025-06-30T00:00:00Z. With the
+// with-lifecycle-headers option they're documented as the Deprecation and Sunset response headers. It is consumed by
+// the converter and not copied to the output.
+//
+//	option deprecated = true;
+//	option (gnostic.openapi.v3.operation) = {
+//	  specification_extension: [{name: "x-lifecycle", value: {yaml: "{deprecation: 2025-01-01, sunset: 2025-06-30}"}}]
+//	};
+const LifecycleExtension = "x-lifecycle"
+
+// Styles of WatchExtension.
+const (
+	WatchStyleLongPoll = "long-poll"
+	WatchStyleSSE      = "sse"
+	WatchStyleChunked  = "chunked"
+)
+
+// MultipartFormExtension is a schema extension that renders a request message as multipart/form-data with a part
+// for each field. PartContentTypeExtension is a property extension that sets the content type of the part for a
+// field. Both are consumed by the converter and not copied to the output.
+//
+//	message UploadAvatarRequest {
+//	  option (gnostic.openapi.v3.schema) = {
+//	    specification_extension: [{name: "x-multipart-form", value: {yaml: "true"}}]
+//	  };
+//	  bytes image = 1 [(gnostic.openapi.v3.property) = {
+//	    specification_extension: [{name: "x-content-type", value: {yaml: "image/png, image/jpeg"}}]
+//	  }];
+//	}
+const (
+	MultipartFormExtension   = "x-multipart-form"
+	PartContentTypeExtension = "x-content-type"
+)
+
+// AnyTypesExtension is a property extension on a google.protobuf.Any field with the message types the field can
+// hold. The "@type" property of the field is restricted to their type URLs, while the other properties are left
+// open. Full message names get the type.googleapis.com/ prefix. It is consumed by the converter and not copied to
+// the output.
+//
+//	google.protobuf.Any payload = 1 [(gnostic.openapi.v3.property) = {
+//	  specification_extension: [{name: "x-any-types", value: {yaml: "[example.v1.Book, example.v1.Author]"}}]
+//	}];
+const AnyTypesExtension = "x-any-types"
+
+// UnwrapExtension is a schema extension that renders a message with exactly one field as the schema of that field,
+// for gateways that flatten such wrappers. It is consumed by the converter and not copied to the output.
+//
+//	message StringList {
+//	  option (gnostic.openapi.v3.schema) = {
+//	    specification_extension: [{name: "x-unwrap", value: {yaml: "true"}}]
+//	  };
+//	  repeated string values = 1;
+//	}
+const UnwrapExtension = "x-unwrap"
+
+// PatternPropertiesExtension is a property extension on a map field with a regular expression, or a list of them,
+// that every key must match. The values of the map are rendered as patternProperties instead of
+// additionalProperties, for payloads with dynamic keys. It is consumed by the converter and not copied to the output.
+//
+//	map<string, string> labels = 1 [(gnostic.openapi.v3.property) = {
+//	  specification_extension: [{name: "x-pattern-properties", value: {yaml: "^[a-z][a-z0-9_]*$"}}]
+//	}];
+const PatternPropertiesExtension = "x-pattern-properties"
+
+// ViewsExtension is a property extension with the AIP-157 views that a field of a resource is returned in, like
+// [FULL]. The names are the names of the values of the view enum, or the part after the prefix of the enum, like
+// BASIC for BOOK_VIEW_BASIC. Fields without it are in every view. It's used by the aip=157 option and is consumed by
+// the converter and not copied to the output.
+//
+//	string content = 5 [(gnostic.openapi.v3.property) = {
+//	  specification_extension: [{name: "x-views", value: {yaml: "[FULL]"}}]
+//	}];
+const ViewsExtension = "x-views"
+
+// converterSchemaExtensions are the schema extensions that configure the converter and are removed from the output.
+var converterSchemaExtensions = []string{MultipartFormExtension, PartContentTypeExtension, AnyTypesExtension, UnwrapExtension, PatternPropertiesExtension, ViewsExtension}
+
+// converterExtensions are the extensions that configure the converter and are removed from the output.
+var converterExtensions = []string{ResponseMediaTypesExtension, RequestContentTypeExtension, ResponseContentTypeExtension, FileTransferExtension, NoSideEffectsExtension, LifecycleExtension}
+
+// MethodExtension returns the specification extension with the given name from the
+// (gnostic.openapi.v3.operation) option on a method, or nil if it isn't set.
+func MethodExtension(md protoreflect.MethodDescriptor, name string) *yaml.Node {
+	if !proto.HasExtension(md.Options(), goa3.E_Operation.TypeDescriptor().Type()) {
+		return nil
+	}
+	opts, ok := proto.GetExtension(md.Options(), goa3.E_Operation.TypeDescriptor().Type()).(*goa3.Operation)
+	if !ok {
+		return nil
+	}
+	return findExtension(opts.GetSpecificationExtension(), name)
+}
+
+// MessageExtension returns the specification extension with the given name from the
+// (gnostic.openapi.v3.schema) option on a message, or nil if it isn't set.
+func MessageExtension(md protoreflect.MessageDescriptor, name string) *yaml.Node {
+	if !proto.HasExtension(md.Options(), goa3.E_Schema.TypeDescriptor().Type()) {
+		return nil
+	}
+	opts, ok := proto.GetExtension(md.Options(), goa3.E_Schema.TypeDescriptor().Type()).(*goa3.Schema)
+	if !ok {
+		return nil
+	}
+	return findExtension(opts.GetSpecificationExtension(), name)
+}
+
+// FieldExtension returns the specification extension with the given name from the
+// (gnostic.openapi.v3.property) option on a field, or nil if it isn't set.
+func FieldExtension(fd protoreflect.FieldDescriptor, name string) *yaml.Node {
+	if !proto.HasExtension(fd.Options(), goa3.E_Property.TypeDescriptor().Type()) {
+		return nil
+	}
+	opts, ok := proto.GetExtension(fd.Options(), goa3.E_Property.TypeDescriptor().Type()).(*goa3.Schema)
+	if !ok {
+		return nil
+	}
+	return findExtension(opts.GetSpecificationExtension(), name)
+}
+
+func findExtension(items []*goa3.NamedAny, name string) *yaml.Node {
+	for _, item := range items {
+		if item.GetName() == name && item.GetValue() != nil {
+			return item.GetValue().ToRawInfo()
+		}
+	}
+	return nil
+}
+
+// ResponseMediaTypes returns the additional response media types of a method, mapped to the full name of the
+// message returned with each media type.
+func ResponseMediaTypes(md protoreflect.MethodDescriptor) *orderedmap.Map[string, protoreflect.FullName] {
+	mediaTypes := orderedmap.New[string, protoreflect.FullName]()
+	node := MethodExtension(md, ResponseMediaTypesExtension)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return mediaTypes
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		mediaTypes.Set(node.Content[i].Value, protoreflect.FullName(node.Content[i+1].Value))
+	}
+	return mediaTypes
+}
+
+// MethodContentType returns the media type set with RequestContentTypeExtension or ResponseContentTypeExtension on
+// a method, or "" if it isn't set.
+func MethodContentType(md protoreflect.MethodDescriptor, extension string) string {
+	node := MethodExtension(md, extension)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// IsFileTransfer returns true if a method is annotated with FileTransferExtension.
+func IsFileTransfer(md protoreflect.MethodDescriptor) bool {
+	node := MethodExtension(md, FileTransferExtension)
+	return node != nil && node.Kind == yaml.ScalarNode && node.Value == "true"
+}
+
+// NoSideEffects returns the value of NoSideEffectsExtension on a method and whether it's set.
+func NoSideEffects(md protoreflect.MethodDescriptor) (value bool, ok bool) {
+	node := MethodExtension(md, NoSideEffectsExtension)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return false, false
+	}
+	switch node.Value {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+// MaxBodyBytes returns the value of MaxBodyBytesExtension on a method and whether it's set to a positive number.
+func MaxBodyBytes(md protoreflect.MethodDescriptor) (int64, bool) {
+	node := MethodExtension(md, MaxBodyBytesExtension)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(node.Value, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	return value, true
+}
+
+// ReplacedBy returns the full name of the method set with ReplacedByExtension on a method, or "" if it isn't set.
+func ReplacedBy(md protoreflect.MethodDescriptor) protoreflect.FullName {
+	node := MethodExtension(md, ReplacedByExtension)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return protoreflect.FullName(strings.TrimPrefix(node.Value, "."))
+}
+
+// Watch is the configuration of a watch method from WatchExtension.
+type Watch struct {
+	Style   string
+	Timeout time.Duration
+}
+
+// WatchMethod returns the WatchExtension of a method and whether it's set. Unknown styles and timeouts that can't be
+// parsed are ignored.
+func WatchMethod(md protoreflect.MethodDescriptor) (Watch, bool) {
+	watch := Watch{Style: WatchStyleLongPoll}
+	node := MethodExtension(md, WatchExtension)
+	if node == nil {
+		return watch, false
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return watch, node.Value == "true"
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1].Value
+			switch key {
+			case "style":
+				switch value {
+				case WatchStyleLongPoll, WatchStyleSSE, WatchStyleChunked:
+					watch.Style = value
+				}
+			case "timeout":
+				if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+					watch.Timeout = timeout
+				} else if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds > 0 {
+					watch.Timeout = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+		return watch, true
+	}
+	return watch, false
+}
+
+// Lifecycle is the lifecycle of a deprecated method from LifecycleExtension. Dates that aren't set are zero.
+type Lifecycle struct {
+	Deprecation time.Time
+	Sunset      time.Time
+}
+
+// MethodLifecycle returns the LifecycleExtension of a method. Unknown keys and dates that can't be parsed are
+// ignored.
+func MethodLifecycle(md protoreflect.MethodDescriptor) Lifecycle {
+	lifecycle := Lifecycle{}
+	node := MethodExtension(md, LifecycleExtension)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return lifecycle
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1].Value
+		date, ok := parseDate(value)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "deprecation":
+			lifecycle.Deprecation = date
+		case "sunset":
+			lifecycle.Sunset = date
+		}
+	}
+	return lifecycle
+}
+
+func parseDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsMultipartForm returns true if a message is annotated with MultipartFormExtension.
+func IsMultipartForm(md protoreflect.MessageDescriptor) bool {
+	node := MessageExtension(md, MultipartFormExtension)
+	return node != nil && node.Kind == yaml.ScalarNode && node.Value == "true"
+}
+
+// UnwrappedField returns the only field of a message that is annotated with UnwrapExtension, or nil if the message
+// isn't annotated or doesn't have exactly one field.
+func UnwrappedField(md protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	node := MessageExtension(md, UnwrapExtension)
+	if node == nil || node.Kind != yaml.ScalarNode || node.Value != "true" || md.Fields().Len() != 1 {
+		return nil
+	}
+	return md.Fields().Get(0)
+}
+
+// PartContentType returns the content type set with PartContentTypeExtension on a field, or "" if it isn't set.
+func PartContentType(fd protoreflect.FieldDescriptor) string {
+	node := FieldExtension(fd, PartContentTypeExtension)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// AnyTypeURLs returns the type URLs of the messages allowed in a google.protobuf.Any field with AnyTypesExtension,
+// or nil if the field isn't restricted.
+func AnyTypeURLs(fd protoreflect.FieldDescriptor) []string {
+	node := FieldExtension(fd, AnyTypesExtension)
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	urls := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.Value == "" {
+			continue
+		}
+		url := item.Value
+		if !strings.Contains(url, "/") {
+			url = "type.googleapis.com/" + url
+		}
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// KeyPatterns returns the regular expressions set with PatternPropertiesExtension on a map field, or nil if the keys
+// aren't restricted.
+func KeyPatterns(fd protoreflect.FieldDescriptor) []string {
+	node := FieldExtension(fd, PatternPropertiesExtension)
+	if node == nil {
+		return nil
+	}
+	items := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+	patterns := []string{}
+	for _, item := range items {
+		if item.Kind == yaml.ScalarNode && item.Value != "" {
+			patterns = append(patterns, item.Value)
+		}
+	}
+	return patterns
+}
+
+// FieldViews returns the views set with ViewsExtension on a field, or nil if the field is in every view.
+func FieldViews(fd protoreflect.FieldDescriptor) []string {
+	node := FieldExtension(fd, ViewsExtension)
+	if node == nil {
+		return nil
+	}
+	items := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+	views := []string{}
+	for _, item := range items {
+		if item.Kind == yaml.ScalarNode && item.Value != "" {
+			views = append(views, item.Value)
+		}
+	}
+	return views
+}
@@ -19,28 +19,30 @@ func SpecWithFileAnnotations(spec *highv3.Document, fd protoreflect.FileDescript
 		return
 	}
 	if opts.Openapi != "" {
-		spec.Info.Version = opts.Openapi
+		spec.Version = opts.Openapi
 	}
 
-	if opts.Info != nil {
-		spec.Info.Title = opts.Info.Title
-		spec.Info.Summary = opts.Info.Summary
-		spec.Info.Description = opts.Info.Description
-		spec.Info.TermsOfService = opts.Info.TermsOfService
-		if opts.Info.Contact != nil {
+	// Only the fields that are set replace the generated ones, so an annotation with just a version keeps the title
+	// and the description that come from the package and its comments
+	if info := opts.Info; info != nil {
+		setString(&spec.Info.Title, info.Title)
+		setString(&spec.Info.Summary, info.Summary)
+		setString(&spec.Info.Description, info.Description)
+		setString(&spec.Info.TermsOfService, info.TermsOfService)
+		setString(&spec.Info.Version, info.Version)
+		if info.Contact != nil {
 			spec.Info.Contact = &highbase.Contact{
-				Name:  opts.Info.Contact.Name,
-				URL:   opts.Info.Contact.Url,
-				Email: opts.Info.Contact.Email,
+				Name:  info.Contact.Name,
+				URL:   info.Contact.Url,
+				Email: info.Contact.Email,
 			}
 		}
-		if opts.Info.License != nil {
+		if info.License != nil {
 			spec.Info.License = &highbase.License{
-				Name: opts.Info.License.Name,
-				URL:  opts.Info.License.Url,
+				Name: info.License.Name,
+				URL:  info.License.Url,
 			}
 		}
-		spec.Info.Version = opts.Info.Version
 	}
 	spec.Servers = append(spec.Servers, toServers(opts.Servers)...)
 	spec.Security = append(spec.Security, toSecurityRequirements(opts.Security)...)
@@ -55,4 +57,38 @@ func SpecWithFileAnnotations(spec *highv3.Document, fd protoreflect.FileDescript
 		}
 	}
 	appendComponents(spec, opts.Components)
+	appendPaths(spec, opts.Paths)
+}
+
+// appendPaths adds the paths of a document annotation, like endpoints that aren't RPCs. Operations that are already
+// in the document for the same path and method aren't replaced.
+func appendPaths(spec *highv3.Document, paths *goa3.Paths) {
+	for _, named := range paths.GetPath() {
+		item := toPathItem(named.GetValue())
+		if item == nil {
+			continue
+		}
+		existing, ok := spec.Paths.PathItems.Get(named.GetName())
+		if !ok {
+			spec.Paths.PathItems.Set(named.GetName(), item)
+			continue
+		}
+		for _, op := range []struct {
+			dst **highv3.Operation
+			src *highv3.Operation
+		}{
+			{&existing.Get, item.Get}, {&existing.Put, item.Put}, {&existing.Post, item.Post}, {&existing.Delete, item.Delete},
+			{&existing.Options, item.Options}, {&existing.Head, item.Head}, {&existing.Patch, item.Patch}, {&existing.Trace, item.Trace},
+		} {
+			if *op.dst == nil {
+				*op.dst = op.src
+			}
+		}
+	}
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
 }
@@ -0,0 +1,137 @@
+package gnostic
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// merger applies annotated values on top of generated ones using the configured override strategy. Conflicts are
+// reported when an annotation sets a value that was generated with a different value. Documentation like titles,
+// descriptions and summaries is expected to be overridden, so it never counts as a conflict.
+type merger struct {
+	opts     options.Options
+	strategy options.OverrideStrategy
+	location string
+}
+
+func newMerger(opts options.Options, category string, location string) merger {
+	return merger{opts: opts, strategy: opts.OverrideStrategy(category), location: location}
+}
+
+// at returns a merger for a nested value, like a property of a message.
+func (m merger) at(name string) merger {
+	m.location = m.location + "." + name
+	return m
+}
+
+func (m merger) conflict(field string, generated, annotated any) {
+	m.opts.Conversion().ReportConflict(m.location, field, generated, annotated)
+}
+
+func setDoc(m merger, dst *string, v string) {
+	if v == "" {
+		return
+	}
+	if *dst != "" && m.strategy == options.OverrideGeneratedWins {
+		return
+	}
+	*dst = v
+}
+
+func setValue[T comparable](m merger, field string, dst *T, v T) {
+	var zero T
+	if v == zero || *dst == v {
+		return
+	}
+	if *dst != zero {
+		m.conflict(field, *dst, v)
+		if m.strategy == options.OverrideGeneratedWins {
+			return
+		}
+	}
+	*dst = v
+}
+
+func setPointer[T comparable](m merger, field string, dst **T, v T) {
+	if *dst != nil {
+		if **dst == v {
+			return
+		}
+		m.conflict(field, **dst, v)
+		if m.strategy == options.OverrideGeneratedWins {
+			return
+		}
+	}
+	*dst = &v
+}
+
+func setRef[T any](m merger, field string, dst **T, v *T) {
+	if v == nil {
+		return
+	}
+	if *dst != nil {
+		if reflect.DeepEqual(*dst, v) {
+			return
+		}
+		m.conflict(field, "generated", "a different value")
+		if m.strategy == options.OverrideGeneratedWins {
+			return
+		}
+	}
+	*dst = v
+}
+
+// setSlice sets a list value. When merge is given, the generated and annotated values are combined unless the
+// replace strategy is used.
+func setSlice[T any](m merger, field string, dst *[]T, v []T, merge func([]T, []T) []T) {
+	if len(v) == 0 || reflect.DeepEqual(*dst, v) {
+		return
+	}
+	if len(*dst) > 0 {
+		if merge != nil && m.strategy != options.OverrideReplace {
+			*dst = merge(*dst, v)
+			return
+		}
+		m.conflict(field, describeSlice(*dst), describeSlice(v))
+		if m.strategy == options.OverrideGeneratedWins {
+			return
+		}
+	}
+	*dst = v
+}
+
+func describeSlice[T any](v []T) string {
+	if strs, ok := any(v).([]string); ok {
+		return fmt.Sprintf("%v", strs)
+	}
+	return fmt.Sprintf("%d items", len(v))
+}
+
+func appendSlice[T any](a []T, b []T) []T {
+	return append(a, b...)
+}
+
+// mergeExtensions sets annotated specification extensions. Only the replace strategy drops generated extensions.
+func mergeExtensions(m merger, generated, annotated *orderedmap.Map[string, *yaml.Node]) *orderedmap.Map[string, *yaml.Node] {
+	if generated == nil || generated.Len() == 0 {
+		return annotated
+	}
+	if m.strategy == options.OverrideReplace {
+		return annotated
+	}
+	for pair := annotated.First(); pair != nil; pair = pair.Next() {
+		if _, ok := generated.Get(pair.Key()); ok {
+			m.conflict(pair.Key(), "generated", "a different value")
+			if m.strategy == options.OverrideGeneratedWins {
+				continue
+			}
+		}
+		generated.Set(pair.Key(), pair.Value())
+	}
+	return generated
+}
@@ -1,79 +1,108 @@
 package gnostic
 
 import (
+	"fmt"
+
 	goa3 "github.com/google/gnostic/openapiv3"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 )
 
-func PathItemWithMethodAnnotations(item *v3.PathItem, md protoreflect.MethodDescriptor) *v3.PathItem {
+func PathItemWithMethodAnnotations(opts options.Options, item *v3.PathItem, md protoreflect.MethodDescriptor) *v3.PathItem {
 	if !proto.HasExtension(md.Options(), goa3.E_Operation.TypeDescriptor().Type()) {
 		return item
 	}
 
 	ext := proto.GetExtension(md.Options(), goa3.E_Operation.TypeDescriptor().Type())
-	opts, ok := ext.(*goa3.Operation)
+	annotation, ok := ext.(*goa3.Operation)
 	if !ok {
 		return item
 	}
+	m := newMerger(opts, options.OverrideCategoryOperation, string(md.FullName()))
 	operations := item.GetOperations()
 	for kv := operations.First(); kv != nil; kv = kv.Next() {
 		oper := kv.Value()
-		if opts.Deprecated {
-			t := true
-			oper.Deprecated = &t
+		if annotation.Deprecated {
+			setPointer(m, "deprecated", &oper.Deprecated, true)
 		}
 
-		for _, param := range opts.Parameters {
-			item.Parameters = append(item.Parameters, toParameter(param))
+		for _, param := range annotation.Parameters {
+			oper.Parameters = setParameter(oper.Parameters, toParameter(param))
 		}
 
-		if opts.RequestBody != nil {
-			oper.RequestBody = toRequestBody(opts.RequestBody.GetRequestBody())
+		if annotation.RequestBody != nil {
+			setRef(m, "requestBody", &oper.RequestBody, toRequestBody(annotation.RequestBody.GetRequestBody()))
 		}
 
-		if opts.Responses != nil {
-			responses := toResponses(opts.Responses)
+		if annotation.Responses != nil {
+			responses := toResponses(annotation.Responses)
+			if m.strategy == options.OverrideReplace {
+				if oper.Responses.Codes.Len() > 0 {
+					m.conflict("responses", fmt.Sprintf("%d responses", oper.Responses.Codes.Len()), fmt.Sprintf("%d responses", responses.Codes.Len()))
+				}
+				oper.Responses.Codes = orderedmap.New[string, *v3.Response]()
+			}
 			for pair := responses.Codes.First(); pair != nil; pair = pair.Next() {
+				if _, ok := oper.Responses.Codes.Get(pair.Key()); ok {
+					m.conflict("responses."+pair.Key(), "generated", "a different response")
+					if m.strategy == options.OverrideGeneratedWins {
+						continue
+					}
+				}
 				oper.Responses.Codes.Set(pair.Key(), pair.Value())
 			}
-			if responses.Default != nil {
-				oper.Responses.Default = responses.Default
-			}
-			for pair := responses.Extensions.First(); pair != nil; pair = pair.Next() {
-				oper.Responses.Extensions.Set(pair.Key(), pair.Value())
-			}
+			setRef(m, "responses.default", &oper.Responses.Default, responses.Default)
+			oper.Responses.Extensions = mergeExtensions(m, oper.Responses.Extensions, responses.Extensions)
 		}
 
-		if opts.Callbacks != nil {
-			oper.Callbacks = toCallbacks(opts.Callbacks)
+		if annotation.Callbacks != nil {
+			oper.Callbacks = toCallbacks(annotation.Callbacks)
 		}
 
-		if security := toSecurityRequirements(opts.Security); len(security) > 0 {
-			oper.Security = security
+		if security := toSecurityRequirements(annotation.Security); len(security) > 0 {
+			setSlice(m, "security", &oper.Security, security, nil)
 		}
-		oper.Servers = toServers(opts.Servers)
+		oper.Servers = toServers(annotation.Servers)
 
-		if opts.Summary != "" {
-			oper.Summary = opts.Summary
-		}
-		if opts.Description != "" {
-			oper.Description = opts.Description
+		setDoc(m, &oper.Summary, annotation.Summary)
+		setDoc(m, &oper.Description, annotation.Description)
+		if len(annotation.Tags) > 0 {
+			setSlice(m, "tags", &oper.Tags, annotation.Tags, func(generated, annotated []string) []string {
+				return append(append([]string{}, annotated...), generated...)
+			})
 		}
-		oper.Tags = append(opts.Tags, oper.Tags...)
 
-		if exDocs := toExternalDocs(opts.ExternalDocs); exDocs != nil {
-			oper.ExternalDocs = exDocs
+		if exDocs := toExternalDocs(annotation.ExternalDocs); exDocs != nil {
+			setRef(m, "externalDocs", &oper.ExternalDocs, exDocs)
 		}
 
-		if opts.OperationId != "" {
-			oper.OperationId = opts.OperationId
-		}
+		setValue(m, "operationId", &oper.OperationId, annotation.OperationId)
 
-		if opts.SpecificationExtension != nil {
-			oper.Extensions = toExtensions(opts.GetSpecificationExtension())
+		if annotation.SpecificationExtension != nil {
+			extensions := toExtensions(annotation.GetSpecificationExtension())
+			for _, name := range converterExtensions {
+				extensions.Delete(name)
+			}
+			oper.Extensions = mergeExtensions(m, oper.Extensions, extensions)
 		}
 	}
 	return item
 }
+
+// setParameter replaces the parameter with the same name and location, like a generated query parameter, or adds it.
+func setParameter(params []*v3.Parameter, param *v3.Parameter) []*v3.Parameter {
+	if param == nil {
+		return params
+	}
+	for i, existing := range params {
+		if existing != nil && existing.Name == param.Name && existing.In == param.In {
+			params[i] = param
+			return params
+		}
+	}
+	return append(params, param)
+}
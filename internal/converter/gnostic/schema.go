@@ -1,6 +1,7 @@
 package gnostic
 
 import (
+	"fmt"
 	"log/slog"
 
 	goa3 "github.com/google/gnostic/openapiv3"
@@ -10,67 +11,69 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 )
 
-func SchemaWithSchemaAnnotations(schema *base.Schema, desc protoreflect.MessageDescriptor) *base.Schema {
+func SchemaWithSchemaAnnotations(opts options.Options, schema *base.Schema, desc protoreflect.MessageDescriptor) *base.Schema {
 	if !proto.HasExtension(desc.Options(), goa3.E_Schema.TypeDescriptor().Type()) {
 		return schema
 	}
 
 	ext := proto.GetExtension(desc.Options(), goa3.E_Schema.TypeDescriptor().Type())
-	opts, ok := ext.(*goa3.Schema)
+	annotation, ok := ext.(*goa3.Schema)
 	if !ok {
 		return schema
 	}
-	return schemaWithAnnotations(schema, opts)
+	return schemaWithAnnotations(newMerger(opts, options.OverrideCategorySchema, string(desc.FullName())), schema, annotation)
 }
 
-func SchemaWithPropertyAnnotations(schema *base.Schema, desc protoreflect.FieldDescriptor) *base.Schema {
+func SchemaWithPropertyAnnotations(opts options.Options, schema *base.Schema, desc protoreflect.FieldDescriptor) *base.Schema {
 	if !proto.HasExtension(desc.Options(), goa3.E_Property.TypeDescriptor().Type()) {
 		return schema
 	}
 
 	ext := proto.GetExtension(desc.Options(), goa3.E_Property.TypeDescriptor().Type())
-	opts, ok := ext.(*goa3.Schema)
+	annotation, ok := ext.(*goa3.Schema)
 	if !ok {
 		return schema
 	}
-	return schemaWithAnnotations(schema, opts)
+	return schemaWithAnnotations(newMerger(opts, options.OverrideCategorySchema, string(desc.FullName())), schema, annotation)
 }
 
 //gocyclo:ignore
-func schemaWithAnnotations(schema *base.Schema, opts *goa3.Schema) *base.Schema {
-	if opts.Description != "" {
-		schema.Description = opts.Description
-	}
-	if opts.Title != "" {
-		schema.Title = opts.Title
-	}
-	if opts.Format != "" {
-		schema.Format = opts.Format
-	}
+func schemaWithAnnotations(m merger, schema *base.Schema, opts *goa3.Schema) *base.Schema {
+	setDoc(m, &schema.Description, opts.Description)
+	setDoc(m, &schema.Title, opts.Title)
+	setValue(m, "format", &schema.Format, opts.Format)
 	if opts.Nullable {
-		schema.Nullable = &opts.Nullable
+		setPointer(m, "nullable", &schema.Nullable, opts.Nullable)
 	}
 	if opts.ReadOnly {
-		schema.ReadOnly = &opts.ReadOnly
+		setPointer(m, "readOnly", &schema.ReadOnly, opts.ReadOnly)
 	}
 	if opts.WriteOnly {
-		schema.WriteOnly = &opts.WriteOnly
+		setPointer(m, "writeOnly", &schema.WriteOnly, opts.WriteOnly)
 	}
 	if opts.Example != nil {
 		// If the example is defined with the YAML option
 		if opts.Example.Yaml != "" {
+			var example *yaml.Node
 			var v string
 			if err := yaml.Unmarshal([]byte(opts.Example.GetYaml()), &v); err != nil {
 				var node yaml.Node
 				if err := yaml.Unmarshal([]byte(opts.Example.GetYaml()), &node); err != nil {
 					slog.Warn("unable to unmarshal example", slog.Any("error", err))
+				} else if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+					example = node.Content[0]
 				} else {
-					schema.Examples = append(schema.Examples, &node)
+					example = &node
 				}
 			} else {
-				schema.Examples = append(schema.Examples, utils.CreateStringNode(v))
+				example = utils.CreateStringNode(v)
+			}
+			if example != nil {
+				setSlice(m, "examples", &schema.Examples, []*yaml.Node{example}, appendSlice[*yaml.Node])
 			}
 		}
 		// If the example is defined with google.protobuf.Any
@@ -79,99 +82,83 @@ func schemaWithAnnotations(schema *base.Schema, opts *goa3.Schema) *base.Schema
 		}
 	}
 	if opts.ExternalDocs != nil {
-		schema.ExternalDocs = toExternalDocs(opts.ExternalDocs)
+		setRef(m, "externalDocs", &schema.ExternalDocs, toExternalDocs(opts.ExternalDocs))
 	}
 	if opts.Deprecated {
-		schema.Deprecated = &opts.Deprecated
+		setPointer(m, "deprecated", &schema.Deprecated, opts.Deprecated)
 	}
 	if opts.MultipleOf != 0 {
-		schema.MultipleOf = &opts.MultipleOf
+		setPointer(m, "multipleOf", &schema.MultipleOf, opts.MultipleOf)
 	}
 	if opts.Maximum != 0 {
 		if opts.ExclusiveMaximum {
-			schema.ExclusiveMaximum = &base.DynamicValue[bool, float64]{N: 1, B: opts.Maximum}
+			setRef(m, "exclusiveMaximum", &schema.ExclusiveMaximum, &base.DynamicValue[bool, float64]{N: 1, B: opts.Maximum})
 		} else {
-			schema.Maximum = &opts.Maximum
+			setPointer(m, "maximum", &schema.Maximum, opts.Maximum)
 		}
 	}
 	if opts.Minimum != 0 {
 		if opts.ExclusiveMinimum {
-			schema.ExclusiveMinimum = &base.DynamicValue[bool, float64]{N: 1, B: opts.Minimum}
+			setRef(m, "exclusiveMinimum", &schema.ExclusiveMinimum, &base.DynamicValue[bool, float64]{N: 1, B: opts.Minimum})
 		} else {
-			schema.Minimum = &opts.Minimum
+			setPointer(m, "minimum", &schema.Minimum, opts.Minimum)
 		}
 	}
 	if opts.MaxLength > 0 {
-		schema.MaxLength = &opts.MaxLength
+		setPointer(m, "maxLength", &schema.MaxLength, opts.MaxLength)
 	}
 	if opts.MinLength > 0 {
-		v := opts.MinLength
-		schema.MinLength = &v
-	}
-	if opts.Pattern != "" {
-		schema.Pattern = opts.Pattern
+		setPointer(m, "minLength", &schema.MinLength, opts.MinLength)
 	}
+	setValue(m, "pattern", &schema.Pattern, opts.Pattern)
 	if opts.MaxItems > 0 {
 		if schema.ParentProxy != nil {
-			schema.ParentProxy.Schema().MaxItems = &opts.MaxItems
+			setPointer(m, "maxItems", &schema.ParentProxy.Schema().MaxItems, opts.MaxItems)
 		}
 	}
 	if opts.MinItems > 0 {
 		if schema.ParentProxy != nil {
-			schema.ParentProxy.Schema().MinItems = &opts.MinItems
+			setPointer(m, "minItems", &schema.ParentProxy.Schema().MinItems, opts.MinItems)
 		}
 	}
 	if opts.UniqueItems {
 		if schema.ParentProxy != nil {
-			schema.ParentProxy.Schema().UniqueItems = &opts.UniqueItems
+			setPointer(m, "uniqueItems", &schema.ParentProxy.Schema().UniqueItems, opts.UniqueItems)
 		}
 	}
 	if opts.MaxProperties > 0 {
 		if schema.ParentProxy != nil {
-			schema.ParentProxy.Schema().MaxProperties = &opts.MaxProperties
+			setPointer(m, "maxProperties", &schema.ParentProxy.Schema().MaxProperties, opts.MaxProperties)
 		}
 	}
 	if opts.MinProperties > 0 {
 		if schema.ParentProxy != nil {
-			schema.ParentProxy.Schema().MinProperties = &opts.MinProperties
+			setPointer(m, "minProperties", &schema.ParentProxy.Schema().MinProperties, opts.MinProperties)
 		}
 	}
-	if len(opts.Required) > 0 {
-		schema.Required = opts.Required
-	}
+	setSlice(m, "required", &schema.Required, opts.Required, mergeRequired)
 	if len(opts.Enum) > 0 {
 		enums := make([]*yaml.Node, len(opts.Enum))
 		for i, enum := range opts.Enum {
 			enums[i] = enum.ToRawInfo()
 		}
-		schema.Enum = enums
+		setSlice(m, "enum", &schema.Enum, enums, nil)
 	}
 	if opts.Type != "" {
-		schema.Type = []string{opts.Type}
-	}
-
-	if opts.AdditionalProperties != nil {
-		switch v := opts.AdditionalProperties.GetOneof().(type) {
-		case *goa3.AdditionalPropertiesItem_SchemaOrReference:
-			if vv := toSchemaOrReference(v.SchemaOrReference); vv != nil {
-				schema.AdditionalProperties = &base.DynamicValue[*base.SchemaProxy, bool]{A: vv}
-			}
-		case *goa3.AdditionalPropertiesItem_Boolean:
-			schema.AdditionalProperties = &base.DynamicValue[*base.SchemaProxy, bool]{N: 1, B: v.Boolean}
-		}
+		setSlice(m, "type", &schema.Type, []string{opts.Type}, nil)
 	}
 
 	if len(opts.AllOf) > 0 {
-		schema.AllOf = toSchemaOrReferences(opts.AllOf)
+		setSlice(m, "allOf", &schema.AllOf, toSchemaOrReferences(opts.AllOf), nil)
 	}
 	if len(opts.OneOf) > 0 {
-		schema.OneOf = toSchemaOrReferences(opts.OneOf)
+		setSlice(m, "oneOf", &schema.OneOf, toSchemaOrReferences(opts.OneOf), nil)
 	}
 	if len(opts.AnyOf) > 0 {
-		schema.AnyOf = toSchemaOrReferences(opts.AnyOf)
+		setSlice(m, "anyOf", &schema.AnyOf, toSchemaOrReferences(opts.AnyOf), nil)
 	}
 	if opts.Not != nil {
-		schema.Not = base.CreateSchemaProxy(toSchema(opts.Not))
+		setRef(m, "not", &schema.Not, base.CreateSchemaProxy(toSchema(opts.Not)))
 	}
 	if opts.Items != nil {
 		items := toSchemaOrReferences(opts.Items.SchemaOrReference)
@@ -181,44 +168,98 @@ func schemaWithAnnotations(schema *base.Schema, opts *goa3.Schema) *base.Schema
 		} else {
 			itemsSchema = base.CreateSchemaProxy(&base.Schema{OneOf: items})
 		}
-		schema.Items = &base.DynamicValue[*base.SchemaProxy, bool]{A: itemsSchema}
+		setRef(m, "items", &schema.Items, &base.DynamicValue[*base.SchemaProxy, bool]{A: itemsSchema})
 	}
 	if opts.Properties != nil {
-		schema.Properties = toSchemaOrReferenceMap(opts.Properties.GetAdditionalProperties())
+		schema.Properties = mergeProperties(m, schema.Properties, opts.Properties.GetAdditionalProperties())
 	}
 	if opts.Default != nil {
-		schema.Default = toDefault(opts.Default)
+		setRef(m, "default", &schema.Default, toDefault(opts.Default))
 	}
 	if opts.AdditionalProperties != nil {
-		schema.AdditionalProperties = toAdditionalPropertiesItem(opts.AdditionalProperties)
+		setRef(m, "additionalProperties", &schema.AdditionalProperties, toAdditionalPropertiesItem(opts.AdditionalProperties))
 	}
 	if opts.Xml != nil {
 		extensions := *orderedmap.New[string, *yaml.Node]()
 		for _, namedAny := range opts.Xml.GetSpecificationExtension() {
 			extensions.Set(namedAny.Name, namedAny.ToRawInfo())
 		}
-		schema.XML = &base.XML{
+		setRef(m, "xml", &schema.XML, &base.XML{
 			Name:       opts.Xml.Name,
 			Namespace:  opts.Xml.Namespace,
 			Prefix:     opts.Xml.Prefix,
 			Attribute:  opts.Xml.Attribute,
 			Wrapped:    opts.Xml.Wrapped,
 			Extensions: &extensions,
-		}
+		})
 	}
 	if opts.Discriminator != nil {
 		mapping := orderedmap.New[string, string]()
 		for _, prop := range opts.Discriminator.GetMapping().GetAdditionalProperties() {
 			mapping.Set(prop.Name, prop.Value)
 		}
-		schema.Discriminator = &base.Discriminator{
+		setRef(m, "discriminator", &schema.Discriminator, &base.Discriminator{
 			PropertyName: opts.Discriminator.GetPropertyName(),
 			Mapping:      mapping,
-		}
+		})
 	}
 	if opts.SpecificationExtension != nil {
-		schema.Extensions = toExtensions(opts.SpecificationExtension)
+		extensions := toExtensions(opts.SpecificationExtension)
+		for _, name := range converterSchemaExtensions {
+			extensions.Delete(name)
+		}
+		if extensions.Len() > 0 {
+			schema.Extensions = mergeExtensions(m, schema.Extensions, extensions)
+		}
 	}
 
 	return schema
 }
+
+// mergeRequired adds the annotated required fields to the ones that were generated, without duplicates.
+func mergeRequired(required []string, annotated []string) []string {
+	seen := make(map[string]struct{}, len(required))
+	for _, name := range required {
+		seen[name] = struct{}{}
+	}
+	for _, name := range annotated {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		required = append(required, name)
+	}
+	return required
+}
+
+// mergeProperties deep-merges annotated properties into the generated ones. Annotations for a property that was
+// generated are applied on top of the generated schema instead of replacing it. References and new properties are
+// set as-is. With the replace strategy, the generated properties are dropped.
+func mergeProperties(m merger, props *orderedmap.Map[string, *base.SchemaProxy], annotated []*goa3.NamedSchemaOrReference) *orderedmap.Map[string, *base.SchemaProxy] {
+	if props == nil || props.Len() == 0 {
+		return toSchemaOrReferenceMap(annotated)
+	}
+	if m.strategy == options.OverrideReplace {
+		m.conflict("properties", fmt.Sprintf("%d properties", props.Len()), fmt.Sprintf("%d properties", len(annotated)))
+		return toSchemaOrReferenceMap(annotated)
+	}
+	for _, item := range annotated {
+		existing, ok := props.Get(item.Name)
+		if !ok {
+			props.Set(item.Name, toSchemaOrReference(item.Value))
+			continue
+		}
+		annotatedSchema := item.GetValue().GetSchema()
+		if annotatedSchema != nil && !existing.IsReference() {
+			if existingSchema := existing.Schema(); existingSchema != nil {
+				schemaWithAnnotations(m.at(item.Name), existingSchema, annotatedSchema)
+				continue
+			}
+		}
+		m.at(item.Name).conflict("schema", "generated", "a reference")
+		if m.strategy != options.OverrideGeneratedWins {
+			props.Set(item.Name, toSchemaOrReference(item.Value))
+		}
+	}
+	return props
+}
@@ -2,7 +2,6 @@ package googleapi
 
 import (
 	"fmt"
-	"iter"
 	"log/slog"
 	"net/http"
 	"regexp"
@@ -12,11 +11,12 @@ import (
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/libopenapi/utils"
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/schema"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 )
@@ -39,25 +39,50 @@ func MakePathItems(opts options.Options, md protoreflect.MethodDescriptor) *orde
 	return httpRuleToPathMap(opts, md, rule)
 }
 
-func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, rule *annotations.HttpRule) *orderedmap.Map[string, *v3.PathItem] {
-	var method, template string
+// HTTPRules returns the google.api.http rule of a method followed by its additional bindings.
+func HTTPRules(md protoreflect.MethodDescriptor) []*annotations.HttpRule {
+	mdopts := md.Options()
+	if !proto.HasExtension(mdopts, annotations.E_Http) {
+		return nil
+	}
+	rule, ok := proto.GetExtension(mdopts, annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+	return append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...)
+}
+
+// RulePattern returns the HTTP method and the path template of an HTTP rule. Both are empty when the rule has no
+// pattern.
+func RulePattern(rule *annotations.HttpRule) (method, template string) {
 	switch pattern := rule.GetPattern().(type) {
 	case *annotations.HttpRule_Get:
-		method, template = http.MethodGet, pattern.Get
+		return http.MethodGet, pattern.Get
 	case *annotations.HttpRule_Put:
-		method, template = http.MethodPut, pattern.Put
+		return http.MethodPut, pattern.Put
 	case *annotations.HttpRule_Post:
-		method, template = http.MethodPost, pattern.Post
+		return http.MethodPost, pattern.Post
 	case *annotations.HttpRule_Delete:
-		method, template = http.MethodDelete, pattern.Delete
+		return http.MethodDelete, pattern.Delete
 	case *annotations.HttpRule_Patch:
-		method, template = http.MethodPatch, pattern.Patch
+		return http.MethodPatch, pattern.Patch
 	case *annotations.HttpRule_Custom:
-		method, template = pattern.Custom.GetKind(), pattern.Custom.GetPath()
-	default:
-		slog.Warn("invalid type of pattern for HTTP rule", slog.Any("pattern", pattern))
-		return nil
+		return strings.ToUpper(pattern.Custom.GetKind()), pattern.Custom.GetPath()
+	}
+	return "", ""
+}
+
+// OpenAPIPath returns the OpenAPI path of a google.api.http path template, like /v1/{name} for /v1/{name=books/*}.
+func OpenAPIPath(opts options.Options, template string) (string, error) {
+	tokens, err := RunPathPatternLexer(template)
+	if err != nil {
+		return "", err
 	}
+	return util.MakePath(opts, partsToOpenAPIPath(opts, tokens)), nil
+}
+
+func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, rule *annotations.HttpRule) *orderedmap.Map[string, *v3.PathItem] {
+	method, template := RulePattern(rule)
 	if method == "" {
 		slog.Warn("invalid HTTP rule: method is blank", slog.Any("method", md))
 		return nil
@@ -84,7 +109,7 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 		operationId = string(service.Name()) + "_" + string(md.Name())
 	}
 	op := &v3.Operation{
-		Summary:     string(md.Name()),
+		Summary:     util.MethodSummary(opts, md),
 		OperationId: operationId,
 		Description: util.FormatComments(fd.SourceLocations().ByDescriptor(md)),
 	}
@@ -127,6 +152,26 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 		if token.Type == TokenVariable && strings.Contains(token.Value, "=") {
 			matches := namedPathPattern.FindStringSubmatch("{" + token.Value + "}")
 			if len(matches) == 3 {
+				// The field is bound by the path, so it isn't a query parameter or part of the body
+				field, jsonPath := resolveField(md.Input(), matches[1])
+				if field != nil {
+					fieldNamesInPath[string(field.FullName())] = struct{}{}
+					fieldNamesInPath[strings.Join(jsonPath, ".")] = struct{}{}
+				}
+				// Transcoders bind the whole matched path to the field, so it's a single parameter
+				if opts.Flavor.IsTranscoder() {
+					param := &v3.Parameter{
+						Name:     matches[1],
+						In:       "path",
+						Required: proto.Bool(true),
+						Schema:   base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}, Pattern: segmentsPattern(matches[2])}),
+					}
+					if field != nil {
+						param.Description = util.FormatComments(fd.SourceLocations().ByDescriptor(field))
+					}
+					op.Parameters = append(op.Parameters, param)
+					continue
+				}
 				// Convert the path from the starred form to use named path parameters.
 				starredPath := matches[2]
 				parts := strings.Split(starredPath, "/")
@@ -152,7 +197,10 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 	case "":
 		op.Parameters = append(op.Parameters, flattenToParams(opts, md.Input(), "", fieldNamesInPath)...)
 	case "*":
-		if len(fieldNamesInPath) > 0 {
+		if IsHTTPBody(md.Input()) {
+			// The bytes of the data field are the whole request, with its content_type as the Content-Type
+			op.RequestBody = &v3.RequestBody{Content: HTTPBodyContent(), Required: util.BoolPtr(true)}
+		} else if len(fieldNamesInPath) > 0 {
 			_, s := schema.MessageToSchema(opts, md.Input())
 			for name := range fieldNamesInPath {
 				s.Properties.Delete(name)
@@ -175,13 +223,20 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 		}
 
 	default:
-		if field, _ := resolveField(md.Input(), rule.Body); field != nil {
+		if field, jsonPath := resolveField(md.Input(), rule.Body); field != nil {
 			loc := fd.SourceLocations().ByDescriptor(field)
-			bodySchema := schema.FieldToSchema(opts, nil, field)
-			op.RequestBody = &v3.RequestBody{
-				Description: util.FormatComments(loc),
-				Content:     util.MakeMediaTypes(opts, bodySchema, false, false),
+			op.RequestBody = &v3.RequestBody{Description: util.FormatComments(loc)}
+			if field.Cardinality() != protoreflect.Repeated && IsHTTPBody(field.Message()) {
+				op.RequestBody.Content = HTTPBodyContent()
+				op.RequestBody.Required = util.BoolPtr(true)
+			} else {
+				op.RequestBody.Content = util.MakeMediaTypes(opts, schema.FieldToSchema(opts, nil, field), false, false)
 			}
+			// Fields that aren't bound by the path or the body are query parameters, like revision in
+			// POST /v1/messages/123?revision=2 with body: "message"
+			fieldNamesInPath[string(field.FullName())] = struct{}{}
+			fieldNamesInPath[strings.Join(jsonPath, ".")] = struct{}{}
+			op.Parameters = append(op.Parameters, flattenToParams(opts, md.Input(), "", fieldNamesInPath)...)
 		} else {
 			slog.Warn("body field not found", slog.String("param", rule.Body))
 		}
@@ -191,15 +246,34 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 	codeMap := orderedmap.New[string, *v3.Response]()
 	mediaType := orderedmap.New[string, *v3.MediaType]()
 	var outputSchema *base.SchemaProxy
-	if rule.ResponseBody == "" {
-		outputSchema = base.CreateSchemaProxyRef("#/components/schemas/" + util.FormatTypeRef(string(md.Output().FullName())))
-	} else {
+	responseMessage := md.Output()
+	if rule.ResponseBody != "" {
+		// With response_body, only this field of the response message is sent, which can be nested, repeated or a map
 		if fd, _ := resolveField(md.Output(), rule.ResponseBody); fd != nil {
-			outputSchema = schema.FieldToSchema(opts, nil, fd)
+			outputSchema = schema.FieldToSchema(opts, base.CreateSchemaProxy(&base.Schema{}), fd)
+			// The response is the value of the field, so it doesn't get the title of the field, like "user" instead
+			// of the User schema that it refers to
+			if !outputSchema.IsReference() && outputSchema.Schema() != nil {
+				outputSchema.Schema().Title = ""
+			}
+			responseMessage = nil
+			if fd.Kind() == protoreflect.MessageKind && fd.Cardinality() != protoreflect.Repeated {
+				responseMessage = fd.Message()
+			}
+		} else {
+			slog.Warn("response body field not found", slog.String("param", rule.ResponseBody))
 		}
 	}
+	if outputSchema == nil {
+		outputSchema = base.CreateSchemaProxyRef("#/components/schemas/" + util.FormatTypeRef(string(md.Output().FullName())))
+	}
 
-	mediaType.Set("application/json", &v3.MediaType{Schema: outputSchema})
+	if IsHTTPBody(responseMessage) {
+		// The data of a google.api.HttpBody is the whole response, with its content_type as the Content-Type
+		mediaType = HTTPBodyContent()
+	} else {
+		mediaType.Set("application/json", &v3.MediaType{Schema: outputSchema})
+	}
 	codeMap.Set("200", &v3.Response{
 		Description: "Success",
 		Content:     mediaType,
@@ -211,7 +285,7 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 			Description: "Error",
 			Content: util.MakeMediaTypes(
 				opts,
-				base.CreateSchemaProxyRef("#/components/schemas/connect.error"),
+				base.CreateSchemaProxyRef(opts.ErrorSchemaRef()),
 				false,
 				false,
 			),
@@ -229,34 +303,104 @@ func httpRuleToPathMap(opts options.Options, md protoreflect.MethodDescriptor, r
 		pathItem.Delete = op
 	case http.MethodPatch:
 		pathItem.Patch = op
+	case http.MethodHead:
+		pathItem.Head = op
+	case http.MethodOptions:
+		pathItem.Options = op
+	case http.MethodTrace:
+		pathItem.Trace = op
 	default:
+		// OpenAPI can only describe the standard methods, so methods like PURGE are documented as a POST that
+		// carries the real method in a header, which many gateways and frameworks understand
+		addMethodOverride(op, method)
+		pathItem.Post = op
 	}
-	paths.Set(partsToOpenAPIPath(tokens), pathItem)
+	paths.Set(partsToOpenAPIPath(opts, tokens), pathItem)
 
+	// Every binding is its own operation. Bindings can share a path with another verb, like a PUT alias of a POST,
+	// so their operations are merged into the path item that is already there.
+	ops := []*v3.Operation{op}
 	for _, binding := range rule.AdditionalBindings {
 		pathMap := httpRuleToPathMap(opts, md, binding)
 		for pair := pathMap.First(); pair != nil; pair = pair.Next() {
-			path := util.MakePath(opts, pair.Key())
-			paths.Set(path, pair.Value())
+			existing, ok := paths.Get(pair.Key())
+			if !ok {
+				paths.Set(pair.Key(), pair.Value())
+				ops = slices.AppendSeq(ops, pair.Value().GetOperations().ValuesFromOldest())
+				continue
+			}
+			ops = append(ops, mergeOperations(md, pair.Key(), existing, pair.Value())...)
 		}
 	}
-	dedupeOperations(op.OperationId, paths.ValuesFromOldest())
+	dedupeOperations(op.OperationId, ops)
 	return paths
 }
 
+// mergeOperations adds the operations of src to dst and returns the ones that were added. When both have an
+// operation for the same verb, the one in dst, which comes from an earlier binding, wins.
+func mergeOperations(md protoreflect.MethodDescriptor, path string, dst, src *v3.PathItem) []*v3.Operation {
+	added := []*v3.Operation{}
+	for _, op := range []struct {
+		dst **v3.Operation
+		src *v3.Operation
+	}{
+		{&dst.Get, src.Get}, {&dst.Put, src.Put}, {&dst.Post, src.Post}, {&dst.Delete, src.Delete},
+		{&dst.Options, src.Options}, {&dst.Head, src.Head}, {&dst.Patch, src.Patch}, {&dst.Trace, src.Trace},
+	} {
+		if op.src == nil {
+			continue
+		}
+		if *op.dst != nil {
+			slog.Warn("duplicate HTTP binding", slog.Any("method", md.FullName()), slog.String("path", path))
+			continue
+		}
+		*op.dst = op.src
+		added = append(added, op.src)
+	}
+	return added
+}
+
+// MethodOverrideExtension is an operation extension with the HTTP method of a custom binding that OpenAPI can't
+// describe, like PURGE. The operation itself is documented as a POST.
+const MethodOverrideExtension = "x-http-method-override"
+
+// methodOverrideHeader is the header that tells a server which method a POST request stands in for.
+const methodOverrideHeader = "X-HTTP-Method-Override"
+
+func addMethodOverride(op *v3.Operation, method string) {
+	op.Parameters = append(op.Parameters, &v3.Parameter{
+		Name:        methodOverrideHeader,
+		In:          "header",
+		Required:    proto.Bool(true),
+		Description: "The HTTP method of this operation. Send it with POST when a client can't send " + method + " requests.",
+		Schema: base.CreateSchemaProxy(&base.Schema{
+			Type:  []string{"string"},
+			Const: utils.CreateStringNode(method),
+		}),
+	})
+	op.Extensions = util.SetExtension(op.Extensions, MethodOverrideExtension, util.StringNode(method))
+
+	note := "This operation uses the " + method + " method, which OpenAPI can't describe. Send " + method +
+		" requests or POST requests with the " + methodOverrideHeader + " header set to " + method + "."
+	if op.Description == "" {
+		op.Description = note
+	} else {
+		op.Description += "\n\n" + note
+	}
+}
+
 // dedupeOperations assigns unique operation ids to additional bindings.
 // From the OpenAPI v3 spec: "The id MUST be unique among all operations described in the API."
 // Since the same gRPC method name is used for operationId, the additional bindings will not be unique,
-// so we append a number, starting at 2, when more than one path binds to the same method.
-func dedupeOperations(id string, value iter.Seq[*v3.PathItem]) {
+// so we append a number, starting at 2, when more than one path binds to the same method. Operations are numbered
+// in the order of the bindings.
+func dedupeOperations(id string, ops []*v3.Operation) {
 	num := 0
-	for path := range value {
-		for op := range path.GetOperations().ValuesFromOldest() {
-			if op.OperationId == id {
-				num++
-				if num > 1 {
-					op.OperationId = fmt.Sprintf("%s%d", id, num)
-				}
+	for _, op := range ops {
+		if op.OperationId == id {
+			num++
+			if num > 1 {
+				op.OperationId = fmt.Sprintf("%s%d", id, num)
 			}
 		}
 	}
@@ -279,7 +423,6 @@ func resolveField(md protoreflect.MessageDescriptor, param string) (protoreflect
 }
 
 func fieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
-	slog.Info("fieldByName", "name", md.FullName(), "name", name)
 	fields := md.Fields()
 	if field := fields.ByName(protoreflect.Name(name)); field != nil {
 		return field
@@ -304,7 +447,7 @@ func partsToParameter(tokens []Token) []string {
 	return params
 }
 
-func partsToOpenAPIPath(tokens []Token) string {
+func partsToOpenAPIPath(opts options.Options, tokens []Token) string {
 	var b strings.Builder
 	for _, token := range tokens {
 		switch token.Type {
@@ -319,7 +462,9 @@ func partsToOpenAPIPath(tokens []Token) string {
 			b.WriteString(token.Value)
 		case TokenVariable:
 			// Handle the name= prefix by extracting just the path portion
-			if strings.Contains(token.Value, "=") {
+			if name, _, ok := strings.Cut(token.Value, "="); ok && opts.Flavor.IsTranscoder() {
+				b.WriteString("{" + name + "}")
+			} else if strings.Contains(token.Value, "=") {
 				matches := namedPathPattern.FindStringSubmatch("{" + token.Value + "}")
 				if len(matches) == 3 {
 					// Add the "name=" "name" value to the list of covered parameters.
@@ -351,6 +496,39 @@ func partsToOpenAPIPath(tokens []Token) string {
 	return b.String()
 }
 
+// segmentsPattern returns the regular expression of the part of a path that a variable like {name=shelves/*} matches.
+func segmentsPattern(template string) string {
+	parts := strings.Split(template, "/")
+	for i, part := range parts {
+		switch part {
+		case "*":
+			parts[i] = "[^/]+"
+		case "**":
+			parts[i] = ".+"
+		default:
+			parts[i] = regexp.QuoteMeta(part)
+		}
+	}
+	return "^" + strings.Join(parts, "/") + "$"
+}
+
+// scalarQueryTypes are the well-known types that transcoders read from a single query parameter with their JSON
+// string value, like an RFC 3339 timestamp.
+var scalarQueryTypes = map[protoreflect.FullName]struct{}{
+	"google.protobuf.Timestamp":   {},
+	"google.protobuf.Duration":    {},
+	"google.protobuf.FieldMask":   {},
+	"google.protobuf.DoubleValue": {},
+	"google.protobuf.FloatValue":  {},
+	"google.protobuf.Int64Value":  {},
+	"google.protobuf.UInt64Value": {},
+	"google.protobuf.Int32Value":  {},
+	"google.protobuf.UInt32Value": {},
+	"google.protobuf.BoolValue":   {},
+	"google.protobuf.StringValue": {},
+	"google.protobuf.BytesValue":  {},
+}
+
 func flattenToParams(opts options.Options, md protoreflect.MessageDescriptor, prefix string, seen map[string]struct{}) []*v3.Parameter {
 	params := []*v3.Parameter{}
 	fields := md.Fields()
@@ -365,25 +543,62 @@ func flattenToParams(opts options.Options, md protoreflect.MessageDescriptor, pr
 			continue
 		}
 		seen[string(field.FullName())] = struct{}{}
-		switch field.Kind() {
-		case protoreflect.MessageKind:
+		transcoder := opts.Flavor.IsTranscoder()
+		switch {
+		case transcoder && field.IsMap():
+			// grpc-gateway reads maps as field[key]=value, Envoy can't set them from the query string
+			if opts.Flavor == options.FlavorGRPCGateway {
+				param := queryParameter(opts, field, paramName)
+				param.Style = "deepObject"
+				param.Explode = util.BoolPtr(true)
+				params = append(params, param)
+			}
+		case transcoder && field.Kind() == protoreflect.MessageKind && isScalarQueryType(field.Message()):
+			params = append(params, queryParameter(opts, field, paramName))
+		case transcoder && field.Kind() == protoreflect.MessageKind && field.IsList():
+			// Repeated messages can't be set from the query string
+		case field.Kind() == protoreflect.MessageKind:
 			params = append(params, flattenToParams(opts, field.Message(), paramName+".", seen)...)
 		default:
-			parent := &base.Schema{}
-			schema := schema.FieldToSchema(opts, base.CreateSchemaProxy(parent), field)
-			var required *bool
-			if len(parent.Required) > 0 {
-				required = util.BoolPtr(true)
-			}
-			loc := field.ParentFile().SourceLocations().ByDescriptor(field)
-			params = append(params, &v3.Parameter{
-				Name:        paramName,
-				In:          "query",
-				Description: util.FormatComments(loc),
-				Schema:      schema,
-				Required:    required,
-			})
+			params = append(params, queryParameter(opts, field, paramName))
 		}
 	}
 	return params
 }
+
+func isScalarQueryType(md protoreflect.MessageDescriptor) bool {
+	_, ok := scalarQueryTypes[md.FullName()]
+	return ok
+}
+
+func queryParameter(opts options.Options, field protoreflect.FieldDescriptor, name string) *v3.Parameter {
+	parent := &base.Schema{}
+	s := schema.FieldToSchema(opts, base.CreateSchemaProxy(parent), field)
+	var required *bool
+	if len(parent.Required) > 0 {
+		required = util.BoolPtr(true)
+	}
+	if opts.Flavor.IsTranscoder() && field.Kind() == protoreflect.EnumKind {
+		s = transcoderEnumSchema(opts, field)
+	}
+	loc := field.ParentFile().SourceLocations().ByDescriptor(field)
+	return &v3.Parameter{
+		Name:        name,
+		In:          "query",
+		Description: util.FormatComments(loc),
+		Schema:      s,
+		Required:    required,
+	}
+}
+
+// transcoderEnumSchema returns the schema of an enum query parameter for transcoders, which accept the number of an
+// enum value as well as its name.
+func transcoderEnumSchema(opts options.Options, field protoreflect.FieldDescriptor) *base.SchemaProxy {
+	opts.IncludeNumberEnumValues = true
+	_, enum := schema.EnumToSchema(opts, field.Enum())
+	s := &base.Schema{Type: []string{"string", "integer"}, Enum: enum.Enum}
+	if field.IsList() {
+		s = &base.Schema{Type: []string{"array"}, Items: &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(s)}}
+	}
+	return base.CreateSchemaProxy(s)
+}
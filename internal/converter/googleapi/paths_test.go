@@ -5,20 +5,36 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 )
 
+func TestSegmentsPattern(t *testing.T) {
+	assert.Equal(t, "^shelves/[^/]+/books/[^/]+$", segmentsPattern("shelves/*/books/*"))
+	assert.Equal(t, "^files/.+$", segmentsPattern("files/**"))
+}
+
 func TestPartsToOpenAPIPath(t *testing.T) {
 	t.Run("with annotation", func(t *testing.T) {
 		v, err := RunPathPatternLexer("/pet/{pet_id}:addPet")
 		require.NoError(t, err)
-		path := partsToOpenAPIPath(v)
+		path := partsToOpenAPIPath(options.NewOptions(), v)
 		assert.Equal(t, "/pet/{pet_id}:addPet", path)
 	})
 
 	t.Run("with glob pattern", func(t *testing.T) {
 		v, err := RunPathPatternLexer("/users/v1/{name=organizations/*/teams/*/members/*}:activate")
 		require.NoError(t, err)
-		path := partsToOpenAPIPath(v)
+		path := partsToOpenAPIPath(options.NewOptions(), v)
 		assert.Equal(t, "/users/v1/organizations/{organization}/teams/{team}/members/{member}:activate", path)
 	})
+
+	t.Run("with glob pattern for a transcoder", func(t *testing.T) {
+		v, err := RunPathPatternLexer("/users/v1/{name=organizations/*/teams/*/members/*}:activate")
+		require.NoError(t, err)
+		opts := options.NewOptions()
+		opts.Flavor = options.FlavorGRPCGateway
+		path := partsToOpenAPIPath(opts, v)
+		assert.Equal(t, "/users/v1/{name}:activate", path)
+	})
 }
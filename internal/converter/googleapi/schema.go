@@ -2,14 +2,21 @@ package googleapi
 
 import (
 	"github.com/pb33f/libopenapi/datamodel/high/base"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// ImmutableExtension is set on the schema of fields with the IMMUTABLE field behavior, which can be set when a
+// resource is created but not changed afterwards.
+const ImmutableExtension = "x-immutable"
+
 func SchemaWithPropertyAnnotations(opts options.Options, schema *base.Schema, desc protoreflect.FieldDescriptor) *base.Schema {
+	schema = schemaWithFieldInfo(schema, desc)
 	dopts := desc.Options()
 	if !proto.HasExtension(dopts, annotations.E_FieldBehavior) {
 		return schema
@@ -37,6 +44,7 @@ func SchemaWithPropertyAnnotations(opts options.Options, schema *base.Schema, de
 			schema.WriteOnly = util.BoolPtr(true)
 		case annotations.FieldBehavior_IMMUTABLE:
 			schema.Description = "(IMMUTABLE) " + schema.Description
+			schema.Extensions = util.SetExtension(schema.Extensions, ImmutableExtension, util.BoolNode(true))
 		case annotations.FieldBehavior_UNORDERED_LIST:
 			schema.Description = "(UNORDERED_LIST) " + schema.Description
 		case annotations.FieldBehavior_NON_EMPTY_DEFAULT:
@@ -48,3 +56,46 @@ func SchemaWithPropertyAnnotations(opts options.Options, schema *base.Schema, de
 	}
 	return schema
 }
+
+// schemaWithFieldInfo applies the format of the google.api.field_info annotation to string schemas, which are the
+// schema of the field or the items of a repeated field. IPV4_OR_IPV6 has no JSON Schema format, so the value has to
+// match either format. A format that's already set by another annotation is kept.
+func schemaWithFieldInfo(schema *base.Schema, desc protoreflect.FieldDescriptor) *base.Schema {
+	if !proto.HasExtension(desc.Options(), annotations.E_FieldInfo) {
+		return schema
+	}
+	info, ok := proto.GetExtension(desc.Options(), annotations.E_FieldInfo).(*annotations.FieldInfo)
+	if !ok || schema.Format != "" || len(schema.Type) != 1 || schema.Type[0] != "string" {
+		return schema
+	}
+	switch info.GetFormat() {
+	case annotations.FieldInfo_UUID4:
+		schema.Format = "uuid"
+	case annotations.FieldInfo_IPV4:
+		schema.Format = "ipv4"
+	case annotations.FieldInfo_IPV6:
+		schema.Format = "ipv6"
+	case annotations.FieldInfo_IPV4_OR_IPV6:
+		schema.AnyOf = append(schema.AnyOf,
+			base.CreateSchemaProxy(&base.Schema{Format: "ipv4"}),
+			base.CreateSchemaProxy(&base.Schema{Format: "ipv6"}),
+		)
+	}
+	return schema
+}
+
+// IsHTTPBody returns true for google.api.HttpBody. Transcoders don't send it as JSON, but send the bytes of its data
+// field as the body with its content_type as the Content-Type.
+func IsHTTPBody(md protoreflect.MessageDescriptor) bool {
+	return md != nil && md.FullName() == "google.api.HttpBody"
+}
+
+// HTTPBodyContent is the content of a google.api.HttpBody request or response body: any media type with the raw
+// bytes as a binary string. The x-request-content-type and x-response-content-type extensions narrow the media type.
+func HTTPBodyContent() *orderedmap.Map[string, *v3.MediaType] {
+	content := orderedmap.New[string, *v3.MediaType]()
+	content.Set("*/*", &v3.MediaType{
+		Schema: base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}, Format: "binary"}),
+	})
+	return content
+}
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+)
+
+// htmlTemplate is a page that renders the spec embedded in it, without loading anything else.
+//
+//go:embed html.html
+var htmlTemplate string
+
+// htmlFile makes a single HTML file next to the spec with the spec embedded as JSON and a small renderer, so the
+// documentation can be shared as one file that works offline.
+func htmlFile(specPath string, spec *v3.Document) (*pluginpb.CodeGeneratorResponse_File, error) {
+	rendered, err := spec.RenderJSON("")
+	if err != nil {
+		return nil, err
+	}
+	// Escaping <, > and & keeps the JSON from closing the script element it's embedded in
+	var embedded bytes.Buffer
+	json.HTMLEscape(&embedded, bytes.TrimSpace(rendered))
+
+	title := "API"
+	if spec.Info != nil && spec.Info.Title != "" {
+		title = spec.Info.Title
+	}
+	content := strings.NewReplacer(
+		"{{TITLE}}", html.EscapeString(title),
+		"{{SPEC}}", embedded.String(),
+	).Replace(htmlTemplate)
+	return newFile(siblingPath(specPath, ".html"), content), nil
+}
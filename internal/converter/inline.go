@@ -0,0 +1,246 @@
+package converter
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// inlineSmallSchemas replaces the references to object schemas with fewer properties than threshold with the schemas
+// themselves and removes them from components.schemas. Schemas that refer to themselves, directly or through other
+// schemas, can't be inlined and neither can the schemas that refer to them.
+func inlineSmallSchemas(threshold int, spec *v3.Document) {
+	if spec.Components == nil || spec.Components.Schemas == nil {
+		return
+	}
+	schemas := spec.Components.Schemas
+
+	const (
+		visiting = iota + 1
+		inline
+		keep
+	)
+	states := map[string]int{}
+	var check func(name string) bool
+	check = func(name string) bool {
+		switch states[name] {
+		case visiting, keep:
+			// A schema that is still being checked refers to itself.
+			states[name] = keep
+			return false
+		case inline:
+			return true
+		}
+		proxy, ok := schemas.Get(name)
+		if !ok || proxy == nil || proxy.IsReference() {
+			states[name] = keep
+			return false
+		}
+		s := proxy.Schema()
+		if s == nil || !slices.Contains(s.Type, "object") || orderedmap.Len(s.Properties) >= threshold {
+			states[name] = keep
+			return false
+		}
+		states[name] = visiting
+		var refs []string
+		refWalker(func(ref string) {
+			if name, ok := strings.CutPrefix(ref, "#/components/schemas/"); ok {
+				refs = append(refs, name)
+			}
+		}).schema(proxy)
+		for _, ref := range refs {
+			if !check(ref) {
+				states[name] = keep
+				return false
+			}
+		}
+		states[name] = inline
+		return true
+	}
+
+	inlined := map[string]*base.SchemaProxy{}
+	for name, proxy := range schemas.FromOldest() {
+		if check(name) {
+			inlined[name] = proxy
+		}
+	}
+	if len(inlined) == 0 {
+		return
+	}
+
+	lookup := func(ref string) *base.SchemaProxy {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if !ok {
+			return nil
+		}
+		return inlined[name]
+	}
+	walkSchemas(spec, func(ref *base.SchemaProxy) *base.SchemaProxy {
+		if proxy := lookup(ref.GetReference()); proxy != nil {
+			return copySchemaProxy(proxy)
+		}
+		return ref
+	}, func(s *base.Schema) {
+		// Fields refer to their message with a $ref extension, so the schema can also have a title and description.
+		if s.Extensions == nil {
+			return
+		}
+		ref := s.Extensions.GetOrZero("$ref")
+		if ref == nil {
+			return
+		}
+		if proxy := lookup(ref.Value); proxy != nil {
+			inlineReference(s, proxy.Schema())
+		}
+	})
+	for name := range inlined {
+		schemas.Delete(name)
+	}
+}
+
+// inlineReference replaces a schema that refers to another schema with a $ref extension with a copy of the other
+// schema, keeping the keywords and extensions that were set next to the $ref.
+func inlineReference(s *base.Schema, target *base.Schema) {
+	inlined := *copySchema(target)
+	src := reflect.ValueOf(s).Elem()
+	dst := reflect.ValueOf(&inlined).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		if !field.IsExported() || field.Name == "Extensions" || src.Field(i).IsZero() {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+	}
+
+	inlined.Extensions = nil
+	for _, extensions := range []*orderedmap.Map[string, *yaml.Node]{copyExtensions(target.Extensions), s.Extensions} {
+		for key, value := range extensions.FromOldest() {
+			if key == "$ref" {
+				continue
+			}
+			if inlined.Extensions == nil {
+				inlined.Extensions = orderedmap.New[string, *yaml.Node]()
+			}
+			inlined.Extensions.Set(key, value)
+		}
+	}
+	*s = inlined
+}
+
+// copySchemaProxy deep copies a schema proxy, so every place a schema is inlined can be changed on its own. References
+// are kept as references.
+func copySchemaProxy(proxy *base.SchemaProxy) *base.SchemaProxy {
+	if proxy == nil {
+		return nil
+	}
+	if proxy.IsReference() {
+		return base.CreateSchemaProxyRef(proxy.GetReference())
+	}
+	s := proxy.Schema()
+	if s == nil {
+		return proxy
+	}
+	return base.CreateSchemaProxy(copySchema(s))
+}
+
+// copySchema deep copies a schema, with its subschemas, values and extensions.
+func copySchema(s *base.Schema) *base.Schema {
+	c := *s
+	c.ParentProxy = nil
+	for _, proxies := range []*[]*base.SchemaProxy{&c.AllOf, &c.OneOf, &c.AnyOf, &c.PrefixItems} {
+		*proxies = copySlice(*proxies, copySchemaProxy)
+	}
+	for _, proxy := range []**base.SchemaProxy{&c.Contains, &c.If, &c.Else, &c.Then, &c.PropertyNames, &c.UnevaluatedItems, &c.Not} {
+		*proxy = copySchemaProxy(*proxy)
+	}
+	for _, m := range []**orderedmap.Map[string, *base.SchemaProxy]{&c.DependentSchemas, &c.PatternProperties, &c.Properties} {
+		*m = copyMap(*m, copySchemaProxy)
+	}
+	for _, value := range []**base.DynamicValue[*base.SchemaProxy, bool]{&c.UnevaluatedProperties, &c.Items, &c.AdditionalProperties} {
+		if *value != nil {
+			v := **value
+			v.A = copySchemaProxy(v.A)
+			*value = &v
+		}
+	}
+	c.ExclusiveMaximum = copyPointer(c.ExclusiveMaximum)
+	c.ExclusiveMinimum = copyPointer(c.ExclusiveMinimum)
+	for _, number := range []**int64{&c.MinContains, &c.MaxContains, &c.MaxLength, &c.MinLength, &c.MaxItems, &c.MinItems, &c.MaxProperties, &c.MinProperties} {
+		*number = copyPointer(*number)
+	}
+	for _, number := range []**float64{&c.MultipleOf, &c.Maximum, &c.Minimum} {
+		*number = copyPointer(*number)
+	}
+	for _, flag := range []**bool{&c.UniqueItems, &c.Nullable, &c.ReadOnly, &c.WriteOnly, &c.Deprecated} {
+		*flag = copyPointer(*flag)
+	}
+	c.Type = slices.Clone(c.Type)
+	c.Required = slices.Clone(c.Required)
+	c.Examples = copySlice(c.Examples, copyNode)
+	c.Enum = copySlice(c.Enum, copyNode)
+	c.Default = copyNode(c.Default)
+	c.Const = copyNode(c.Const)
+	c.Example = copyNode(c.Example)
+	if c.Discriminator != nil {
+		discriminator := *c.Discriminator
+		discriminator.Mapping = copyMap(discriminator.Mapping, func(ref string) string { return ref })
+		c.Discriminator = &discriminator
+	}
+	c.XML = copyPointer(c.XML)
+	if c.ExternalDocs != nil {
+		docs := *c.ExternalDocs
+		docs.Extensions = copyExtensions(docs.Extensions)
+		c.ExternalDocs = &docs
+	}
+	c.Extensions = copyExtensions(c.Extensions)
+	return &c
+}
+
+func copyExtensions(extensions *orderedmap.Map[string, *yaml.Node]) *orderedmap.Map[string, *yaml.Node] {
+	return copyMap(extensions, copyNode)
+}
+
+// copyNode deep copies a node, unlike cloneNode it keeps the styles.
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	c := *node
+	c.Content = copySlice(node.Content, copyNode)
+	return &c
+}
+
+func copyMap[V any](m *orderedmap.Map[string, V], copyValue func(V) V) *orderedmap.Map[string, V] {
+	if m == nil {
+		return nil
+	}
+	c := orderedmap.New[string, V]()
+	for key, value := range m.FromOldest() {
+		c.Set(key, copyValue(value))
+	}
+	return c
+}
+
+func copySlice[V any](values []V, copyValue func(V) V) []V {
+	if values == nil {
+		return nil
+	}
+	c := make([]V, len(values))
+	for i, value := range values {
+		c[i] = copyValue(value)
+	}
+	return c
+}
+
+func copyPointer[V any](value *V) *V {
+	if value == nil {
+		return nil
+	}
+	c := *value
+	return &c
+}
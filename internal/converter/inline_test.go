@@ -0,0 +1,36 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineSmallSchemasCopies(t *testing.T) {
+	field := func() *base.SchemaProxy {
+		return base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}})
+	}
+	label := &base.Schema{Type: []string{"object"}, Properties: orderedmap.New[string, *base.SchemaProxy]()}
+	label.Properties.Set("text", field())
+	message := &base.Schema{Type: []string{"object"}, Properties: orderedmap.New[string, *base.SchemaProxy]()}
+	message.Properties.Set("first", base.CreateSchemaProxyRef("#/components/schemas/Label"))
+	message.Properties.Set("second", base.CreateSchemaProxyRef("#/components/schemas/Label"))
+	message.Properties.Set("third", field())
+	spec := &v3.Document{Components: &v3.Components{Schemas: orderedmap.New[string, *base.SchemaProxy]()}}
+	spec.Components.Schemas.Set("Label", base.CreateSchemaProxy(label))
+	spec.Components.Schemas.Set("Message", base.CreateSchemaProxy(message))
+
+	inlineSmallSchemas(2, spec)
+	_, ok := spec.Components.Schemas.Get("Label")
+	require.False(t, ok)
+	text := func(name string) *base.Schema {
+		return message.Properties.GetOrZero(name).Schema().Properties.GetOrZero("text").Schema()
+	}
+	text("first").Description = "changed"
+	assert.Empty(t, text("second").Description)
+	assert.Empty(t, label.Properties.GetOrZero("text").Schema().Description)
+}
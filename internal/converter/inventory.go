@@ -0,0 +1,129 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/types/descriptorpb"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// Inventory lists every operation of the generated documents for governance spreadsheets and routing audits.
+type Inventory struct {
+	Operations []InventoryEntry `json:"operations"`
+}
+
+// InventoryEntry is an operation in the Inventory.
+type InventoryEntry struct {
+	// Document is the name of the generated document the operation is in.
+	Document string `json:"document"`
+	// Service and Method are the full name of the service and the name of the method. Both are empty for operations
+	// that only come from the base file.
+	Service     string `json:"service"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Verb        string `json:"verb"`
+	OperationID string `json:"operationId"`
+	// Idempotency is the idempotency_level of the method, like NO_SIDE_EFFECTS.
+	Idempotency string `json:"idempotency"`
+	// Security lists the alternative security requirements of the operation. The schemes of one requirement are
+	// joined with "+" and an empty requirement, which makes authentication optional, is "none". It's empty when the
+	// operation doesn't need authentication.
+	Security []string `json:"security"`
+}
+
+var inventoryHeader = []string{"document", "service", "method", "path", "verb", "operationId", "idempotency", "security"}
+
+// inventoryEntries lists the operations of a finished document. The methods are looked up by path and HTTP method,
+// so operations that were removed by options like remove-internal or an overlay aren't listed.
+func inventoryEntries(opts options.Options, document string, spec *v3.Document) []InventoryEntry {
+	entries := []InventoryEntry{}
+	if spec.Paths == nil {
+		return entries
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for verb, op := range item.GetOperations().FromOldest() {
+			entry := InventoryEntry{
+				Document:    document,
+				Path:        path,
+				Verb:        strings.ToUpper(verb),
+				OperationID: op.OperationId,
+				Security:    securityNames(spec, op),
+			}
+			if method, ok := opts.Conversion().Route(path, verb); ok {
+				entry.Service = string(method.Parent().FullName())
+				entry.Method = string(method.Name())
+				entry.Idempotency = descriptorpb.MethodOptions_IDEMPOTENCY_UNKNOWN.String()
+				if options, ok := method.Options().(*descriptorpb.MethodOptions); ok && options != nil {
+					entry.Idempotency = options.GetIdempotencyLevel().String()
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// securityNames returns the security requirements of an operation, which default to the ones of the document.
+func securityNames(spec *v3.Document, op *v3.Operation) []string {
+	requirements := spec.Security
+	if op.Security != nil {
+		requirements = op.Security
+	}
+	names := []string{}
+	for _, requirement := range requirements {
+		if requirement == nil {
+			continue
+		}
+		if orderedmap.Len(requirement.Requirements) == 0 {
+			names = append(names, "none")
+			continue
+		}
+		schemes := []string{}
+		for scheme := range requirement.Requirements.KeysFromOldest() {
+			schemes = append(schemes, scheme)
+		}
+		names = append(names, strings.Join(schemes, "+"))
+	}
+	return names
+}
+
+// inventoryFile writes the inventory as CSV when the name ends with .csv and as JSON otherwise.
+func inventoryFile(name string, entries []InventoryEntry) (*pluginpb.CodeGeneratorResponse_File, error) {
+	var content string
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		buf := &bytes.Buffer{}
+		w := csv.NewWriter(buf)
+		if err := w.Write(inventoryHeader); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			record := []string{
+				entry.Document, entry.Service, entry.Method, entry.Path, entry.Verb, entry.OperationID,
+				entry.Idempotency, strings.Join(entry.Security, " "),
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+		content = buf.String()
+	} else {
+		b, err := json.MarshalIndent(Inventory{Operations: entries}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		content = string(b) + "\n"
+	}
+	return newFile(name, content), nil
+}
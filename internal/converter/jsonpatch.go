@@ -0,0 +1,286 @@
+package converter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// jsonPatchOperation is one operation of an RFC 6902 JSON Patch.
+type jsonPatchOperation struct {
+	Op    string    `yaml:"op"`
+	Path  string    `yaml:"path"`
+	From  string    `yaml:"from"`
+	Value yaml.Node `yaml:"value"`
+}
+
+// applyJSONPatch applies an RFC 6902 JSON Patch, written in JSON or YAML, to the spec. Operations that refer to a
+// location that doesn't exist, like removing a path that is no longer generated, are an error.
+func applyJSONPatch(spec *v3.Document, content []byte) error {
+	ops := []jsonPatchOperation{}
+	if err := yaml.Unmarshal(content, &ops); err != nil {
+		return fmt.Errorf("unable to parse the JSON patch: %w", err)
+	}
+	return rewriteSpec(spec, "JSON patch", func(root *yaml.Node) error {
+		doc := root.Content[0]
+		for i, op := range ops {
+			if err := applyJSONPatchOperation(doc, op); err != nil {
+				return fmt.Errorf("JSON patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+			}
+		}
+		return nil
+	})
+}
+
+func applyJSONPatchOperation(doc *yaml.Node, op jsonPatchOperation) error {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value.Kind == 0 {
+			return fmt.Errorf("missing value")
+		}
+	case "move", "copy":
+		if _, err := parseJSONPointer(op.From); err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+	case "remove":
+	default:
+		return fmt.Errorf("unknown operation")
+	}
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add":
+		return jsonPointerAdd(doc, path, cloneNode(&op.Value))
+	case "remove":
+		_, err := jsonPointerRemove(doc, path)
+		return err
+	case "replace":
+		// Replacing the root replaces the whole document, which can't be removed first.
+		if len(path) > 0 {
+			if _, err := jsonPointerRemove(doc, path); err != nil {
+				return err
+			}
+		}
+		return jsonPointerAdd(doc, path, cloneNode(&op.Value))
+	case "move":
+		from, _ := parseJSONPointer(op.From)
+		value, err := jsonPointerRemove(doc, from)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		return jsonPointerAdd(doc, path, value)
+	case "copy":
+		from, _ := parseJSONPointer(op.From)
+		value, err := jsonPointerGet(doc, from)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		return jsonPointerAdd(doc, path, cloneNode(value))
+	case "test":
+		value, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return err
+		}
+		var actual, expected any
+		if err := value.Decode(&actual); err != nil {
+			return err
+		}
+		if err := op.Value.Decode(&expected); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return fmt.Errorf("test failed")
+		}
+	}
+	return nil
+}
+
+// applyMergePatch applies an RFC 7386 JSON Merge Patch, written in JSON or YAML, to the spec.
+func applyMergePatch(spec *v3.Document, content []byte) error {
+	patch := &yaml.Node{}
+	if err := yaml.Unmarshal(content, patch); err != nil {
+		return fmt.Errorf("unable to parse the merge patch: %w", err)
+	}
+	if len(patch.Content) == 0 {
+		return nil
+	}
+	return rewriteSpec(spec, "merge patch", func(root *yaml.Node) error {
+		root.Content[0] = mergePatch(root.Content[0], patch.Content[0])
+		return nil
+	})
+}
+
+func mergePatch(target, patch *yaml.Node) *yaml.Node {
+	if patch.Kind != yaml.MappingNode {
+		return cloneNode(patch)
+	}
+	if target == nil || target.Kind != yaml.MappingNode {
+		target = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key, value := patch.Content[i].Value, patch.Content[i+1]
+		idx := mappingIndex(target, key)
+		if value.Tag == "!!null" {
+			if idx >= 0 {
+				target.Content = append(target.Content[:idx], target.Content[idx+2:]...)
+			}
+			continue
+		}
+		if idx >= 0 {
+			target.Content[idx+1] = mergePatch(target.Content[idx+1], value)
+		} else {
+			target.Content = append(target.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, mergePatch(nil, value))
+		}
+	}
+	return target
+}
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer '%s'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonPointerGet(doc *yaml.Node, path []string) (*yaml.Node, error) {
+	node := doc
+	for i, token := range path {
+		switch node.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(node, token)
+			if idx < 0 {
+				return nil, fmt.Errorf("%s does not exist", formatJSONPointer(path[:i+1]))
+			}
+			node = node.Content[idx+1]
+		case yaml.SequenceNode:
+			idx, err := sequenceIndex(node, token, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", formatJSONPointer(path[:i+1]), err)
+			}
+			node = node.Content[idx]
+		default:
+			return nil, fmt.Errorf("%s does not exist", formatJSONPointer(path[:i+1]))
+		}
+	}
+	return node, nil
+}
+
+func jsonPointerAdd(doc *yaml.Node, path []string, value *yaml.Node) error {
+	if len(path) == 0 {
+		*doc = *value
+		return nil
+	}
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	token := path[len(path)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		if idx := mappingIndex(parent, token); idx >= 0 {
+			parent.Content[idx+1] = value
+		} else {
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, value)
+		}
+	case yaml.SequenceNode:
+		idx, err := sequenceIndex(parent, token, true)
+		if err != nil {
+			return fmt.Errorf("%s: %w", formatJSONPointer(path), err)
+		}
+		parent.Content = append(parent.Content[:idx], append([]*yaml.Node{value}, parent.Content[idx:]...)...)
+	default:
+		return fmt.Errorf("%s is not an object or an array", formatJSONPointer(path[:len(path)-1]))
+	}
+	return nil
+}
+
+func jsonPointerRemove(doc *yaml.Node, path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("the whole document can't be removed")
+	}
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		idx := mappingIndex(parent, token)
+		if idx < 0 {
+			return nil, fmt.Errorf("%s does not exist", formatJSONPointer(path))
+		}
+		value := parent.Content[idx+1]
+		parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+		return value, nil
+	case yaml.SequenceNode:
+		idx, err := sequenceIndex(parent, token, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", formatJSONPointer(path), err)
+		}
+		value := parent.Content[idx]
+		parent.Content = append(parent.Content[:idx], parent.Content[idx+1:]...)
+		return value, nil
+	}
+	return nil, fmt.Errorf("%s does not exist", formatJSONPointer(path))
+}
+
+func formatJSONPointer(path []string) string {
+	escaped := make([]string, len(path))
+	for i, token := range path {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// sequenceIndex parses an array index. When adding, "-" and the length of the array refer to the end of it.
+func sequenceIndex(node *yaml.Node, token string, adding bool) (int, error) {
+	if adding && token == "-" {
+		return len(node.Content), nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index '%s'", token)
+	}
+	if idx > len(node.Content) || (!adding && idx == len(node.Content)) {
+		return 0, fmt.Errorf("array index %d is out of range", idx)
+	}
+	return idx, nil
+}
+
+// cloneNode deep copies a node. Styles are reset so values from a JSON patch are written like the rest of the spec.
+func cloneNode(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return cloneNode(node.Content[0])
+	}
+	clone := *node
+	clone.Style = 0
+	clone.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		clone.Content[i] = cloneNode(child)
+	}
+	return &clone
+}
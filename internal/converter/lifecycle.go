@@ -0,0 +1,79 @@
+package converter
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// applyLifecycleHeaders documents the Deprecation (RFC 9745) and Sunset (RFC 8594) headers on every response of a
+// deprecated operation, with the dates of the x-lifecycle extension of the method. Servers send them on every
+// response, so they're documented on every response. Sunset is only documented when the method has a sunset date.
+func applyLifecycleHeaders(method protoreflect.MethodDescriptor, op *v3.Operation) {
+	if !isDeprecatedOperation(method, op) || op.Responses == nil {
+		return
+	}
+	lifecycle := gnostic.MethodLifecycle(method)
+
+	deprecation := &v3.Header{
+		Description: "The operation is deprecated.",
+		Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+	}
+	if !lifecycle.Deprecation.IsZero() {
+		deprecation.Description = fmt.Sprintf("The operation is deprecated since %s.", formatDate(lifecycle.Deprecation))
+		deprecation.Example = util.StringNode(fmt.Sprintf("@%d", lifecycle.Deprecation.Unix()))
+	}
+	var sunset *v3.Header
+	if !lifecycle.Sunset.IsZero() {
+		sunset = &v3.Header{
+			Description: fmt.Sprintf("The operation stops working after %s.", formatDate(lifecycle.Sunset)),
+			Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+			Example:     util.StringNode(lifecycle.Sunset.UTC().Format(http.TimeFormat)),
+		}
+		appendDescription(op, fmt.Sprintf("This operation stops working after %s.", formatDate(lifecycle.Sunset)))
+	}
+
+	responses := []*v3.Response{op.Responses.Default}
+	for response := range op.Responses.Codes.ValuesFromOldest() {
+		responses = append(responses, response)
+	}
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+		if response.Headers == nil {
+			response.Headers = orderedmap.New[string, *v3.Header]()
+		}
+		response.Headers.Set("Deprecation", deprecation)
+		if sunset != nil {
+			response.Headers.Set("Sunset", sunset)
+		}
+	}
+}
+
+// isDeprecatedOperation returns true if an operation is deprecated, either by the deprecated option of its method or
+// by an annotation.
+func isDeprecatedOperation(method protoreflect.MethodDescriptor, op *v3.Operation) bool {
+	deprecated := util.IsMethodDeprecated(method)
+	if op.Deprecated != nil {
+		deprecated = op.Deprecated
+	}
+	return deprecated != nil && *deprecated
+}
+
+// formatDate formats a date without the time when it's midnight in UTC, like 2025-06-30.
+func formatDate(date time.Time) string {
+	date = date.UTC()
+	if date.Equal(date.Truncate(24 * time.Hour)) {
+		return date.Format(time.DateOnly)
+	}
+	return date.Format(time.RFC3339)
+}
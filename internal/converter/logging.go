@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/lmittmann/tint"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// setupLogging makes the default logger write where the log option says, in its format and from its level. Without
+// the log option, the debug option logs everything to stderr. The returned function closes the log file and
+// restores the previous logger.
+func setupLogging(opts options.Options) (func(), error) {
+	cfg := opts.Log
+	if cfg == nil {
+		if opts.Debug {
+			slog.SetDefault(slog.New(
+				tint.NewHandler(os.Stderr, &tint.Options{
+					Level: slog.LevelDebug,
+				}),
+			))
+		}
+		return func() {}, nil
+	}
+
+	var w io.Writer = os.Stderr
+	closeFile := func() error { return nil }
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+		closeFile = f.Close
+	}
+
+	var handler slog.Handler
+	switch {
+	case cfg.Format == options.LogFormatJSON:
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: cfg.Level})
+	case cfg.File != "":
+		handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: cfg.Level})
+	default:
+		handler = tint.NewHandler(w, &tint.Options{Level: cfg.Level})
+	}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	return func() {
+		slog.SetDefault(previous)
+		if err := closeFile(); err != nil {
+			slog.Warn("unable to close the log file", slog.Any("error", err))
+		}
+	}, nil
+}
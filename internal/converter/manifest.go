@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+)
+
+// Manifest lists every file that was generated along with a hash of its content. Build systems like Bazel and Nix
+// can use it to verify outputs without parsing the documents.
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+func manifestFile(name string, files []*pluginpb.CodeGeneratorResponse_File) (*pluginpb.CodeGeneratorResponse_File, error) {
+	manifest := Manifest{Files: make([]ManifestFile, 0, len(files))}
+	for _, file := range files {
+		sum := sha256.Sum256([]byte(file.GetContent()))
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Name:   file.GetName(),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	content := string(b) + "\n"
+	return newFile(name, content), nil
+}
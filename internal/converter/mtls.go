@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// mtlsSchemeName is the name of the mutualTLS security scheme that the mtls option adds.
+const mtlsSchemeName = "mtls"
+
+// SPIFFEIDExtension is an operation extension with the pattern of the SPIFFE ID that client certificates must have.
+const SPIFFEIDExtension = "x-spiffe-id"
+
+// applyMTLS adds the mutualTLS security scheme of the `mtls` option to the operations of the listed services and
+// methods. The client certificate is required in addition to the other security of the operation, so it's added to
+// every alternative requirement, including the empty one.
+func applyMTLS(opts options.Options, spec *v3.Document) {
+	used := false
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for verb, op := range item.GetOperations().FromOldest() {
+			if op == nil {
+				continue
+			}
+			method, ok := opts.Conversion().Route(path, verb)
+			if !ok {
+				continue
+			}
+			requirement, ok := mtlsRequirement(opts, method)
+			if !ok {
+				continue
+			}
+			op.Security = withMTLSRequirement(spec, op)
+			if requirement.SPIFFEID != "" {
+				op.Extensions = util.SetExtension(op.Extensions, SPIFFEIDExtension, util.StringNode(requirement.SPIFFEID))
+			}
+			used = true
+		}
+	}
+	if !used {
+		return
+	}
+	if spec.Components == nil {
+		spec.Components = &v3.Components{}
+	}
+	if spec.Components.SecuritySchemes == nil {
+		spec.Components.SecuritySchemes = orderedmap.New[string, *v3.SecurityScheme]()
+	}
+	if _, ok := spec.Components.SecuritySchemes.Get(mtlsSchemeName); !ok {
+		spec.Components.SecuritySchemes.Set(mtlsSchemeName, &v3.SecurityScheme{
+			Type:        "mutualTLS",
+			Description: "Clients authenticate with a TLS client certificate.",
+		})
+	}
+}
+
+// mtlsRequirement returns the requirement for a method, which is the one for the method itself or else the one for
+// its service.
+func mtlsRequirement(opts options.Options, method protoreflect.MethodDescriptor) (options.MTLSRequirement, bool) {
+	for _, name := range []protoreflect.FullName{method.FullName(), method.Parent().FullName()} {
+		for _, requirement := range opts.MTLS {
+			if requirement.Name == name {
+				return requirement, true
+			}
+		}
+	}
+	return options.MTLSRequirement{}, false
+}
+
+// withMTLSRequirement returns the security of an operation, which defaults to the security of the document, with
+// the mutualTLS scheme added to each requirement.
+func withMTLSRequirement(spec *v3.Document, op *v3.Operation) []*base.SecurityRequirement {
+	requirements := spec.Security
+	if op.Security != nil {
+		requirements = op.Security
+	}
+	if len(requirements) == 0 {
+		requirements = []*base.SecurityRequirement{{}}
+	}
+	result := make([]*base.SecurityRequirement, 0, len(requirements))
+	for _, requirement := range requirements {
+		schemes := orderedmap.New[string, []string]()
+		if requirement != nil {
+			for name, scopes := range requirement.Requirements.FromOldest() {
+				schemes.Set(name, scopes)
+			}
+		}
+		if _, ok := schemes.Get(mtlsSchemeName); !ok {
+			schemes.Set(mtlsSchemeName, []string{})
+		}
+		result = append(result, &base.SecurityRequirement{Requirements: schemes})
+	}
+	return result
+}
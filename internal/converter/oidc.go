@@ -0,0 +1,43 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// oidcSchemeName is the name of the security scheme that the oidc-issuer option adds.
+const oidcSchemeName = "oidc"
+
+// applyOIDCIssuer adds an openIdConnect security scheme for the issuer of the `oidc-issuer` option and requires it
+// for the whole document. A scheme with the same name and security that's already set by the base file or
+// annotations are kept, and operations with their own security keep it too.
+func applyOIDCIssuer(opts options.Options, spec *v3.Document) {
+	if spec.Components == nil {
+		spec.Components = &v3.Components{}
+	}
+	if spec.Components.SecuritySchemes == nil {
+		spec.Components.SecuritySchemes = orderedmap.New[string, *v3.SecurityScheme]()
+	}
+	if _, ok := spec.Components.SecuritySchemes.Get(oidcSchemeName); !ok {
+		spec.Components.SecuritySchemes.Set(oidcSchemeName, &v3.SecurityScheme{
+			Type:             "openIdConnect",
+			OpenIdConnectUrl: oidcDiscoveryURL(opts.OIDCIssuer),
+			Description:      "Issuer: " + opts.OIDCIssuer,
+		})
+	}
+	if len(spec.Security) == 0 {
+		requirements := orderedmap.New[string, []string]()
+		requirements.Set(oidcSchemeName, []string{})
+		spec.Security = []*base.SecurityRequirement{{Requirements: requirements}}
+	}
+}
+
+// oidcDiscoveryURL returns the URL of the OpenID Connect discovery document of an issuer.
+func oidcDiscoveryURL(issuer string) string {
+	return strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+}
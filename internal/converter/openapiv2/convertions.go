@@ -0,0 +1,297 @@
+package openapiv2
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	gwoptions "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// sortedKeys returns the keys of a map in order, since protobuf maps don't keep the order of the annotation.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+// jsonNode parses a JSON value of an annotation, like an example or a default. The JSON styles are dropped, so the
+// value is written like the rest of the document.
+func jsonNode(value string) *yaml.Node {
+	if value == "" {
+		return nil
+	}
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(value), node); err != nil {
+		slog.Warn("unable to unmarshal openapiv2 JSON value", slog.String("value", value), slog.Any("error", err))
+		return nil
+	}
+	clearStyle(node)
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		return node.Content[0]
+	}
+	return node
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+func toExtensions(extensions *orderedmap.Map[string, *yaml.Node], values map[string]*structpb.Value) *orderedmap.Map[string, *yaml.Node] {
+	for _, name := range sortedKeys(values) {
+		node, err := util.ExtensionNode(values[name].AsInterface())
+		if err != nil {
+			slog.Warn("unable to encode openapiv2 extension", slog.String("name", name), slog.Any("error", err))
+			continue
+		}
+		extensions = util.SetExtension(extensions, name, node)
+	}
+	return extensions
+}
+
+func toExternalDocs(docs *gwoptions.ExternalDocumentation) *base.ExternalDoc {
+	if docs == nil {
+		return nil
+	}
+	return &base.ExternalDoc{
+		Description: docs.Description,
+		URL:         docs.Url,
+	}
+}
+
+// toRef turns the references of protoc-gen-openapiv2, to #/definitions or to a fully-qualified message name like
+// .example.v1.Book, into references to components.schemas.
+func toRef(ref string) string {
+	if name, ok := strings.CutPrefix(ref, "#/definitions/"); ok {
+		return "#/components/schemas/" + name
+	}
+	if name, ok := strings.CutPrefix(ref, "."); ok {
+		return "#/components/schemas/" + name
+	}
+	return ref
+}
+
+func toSecurityRequirements(reqs []*gwoptions.SecurityRequirement) []*base.SecurityRequirement {
+	result := make([]*base.SecurityRequirement, len(reqs))
+	for i, req := range reqs {
+		requirements := orderedmap.New[string, []string]()
+		for _, name := range sortedKeys(req.SecurityRequirement) {
+			scopes := req.SecurityRequirement[name].GetScope()
+			if scopes == nil {
+				scopes = []string{}
+			}
+			requirements.Set(name, scopes)
+		}
+		result[i] = &base.SecurityRequirement{
+			Requirements:             requirements,
+			ContainsEmptyRequirement: len(req.SecurityRequirement) == 0,
+		}
+	}
+	return result
+}
+
+// toSecuritySchemes converts the OpenAPI v2 security definitions. Basic authentication becomes an http scheme and
+// the OAuth2 flows get their OpenAPI v3 names.
+func toSecuritySchemes(defs *gwoptions.SecurityDefinitions) *orderedmap.Map[string, *v3.SecurityScheme] {
+	if len(defs.GetSecurity()) == 0 {
+		return nil
+	}
+	schemes := orderedmap.New[string, *v3.SecurityScheme]()
+	for _, name := range sortedKeys(defs.GetSecurity()) {
+		def := defs.GetSecurity()[name]
+		scheme := &v3.SecurityScheme{
+			Description: def.Description,
+			Extensions:  toExtensions(nil, def.Extensions),
+		}
+		switch def.Type {
+		case gwoptions.SecurityScheme_TYPE_BASIC:
+			scheme.Type = "http"
+			scheme.Scheme = "basic"
+		case gwoptions.SecurityScheme_TYPE_API_KEY:
+			scheme.Type = "apiKey"
+			scheme.Name = def.Name
+			switch def.In {
+			case gwoptions.SecurityScheme_IN_QUERY:
+				scheme.In = "query"
+			case gwoptions.SecurityScheme_IN_HEADER:
+				scheme.In = "header"
+			}
+		case gwoptions.SecurityScheme_TYPE_OAUTH2:
+			scheme.Type = "oauth2"
+			scopes := orderedmap.New[string, string]()
+			for _, scope := range sortedKeys(def.GetScopes().GetScope()) {
+				scopes.Set(scope, def.GetScopes().GetScope()[scope])
+			}
+			flow := &v3.OAuthFlow{Scopes: scopes}
+			scheme.Flows = &v3.OAuthFlows{}
+			switch def.Flow {
+			case gwoptions.SecurityScheme_FLOW_IMPLICIT:
+				flow.AuthorizationUrl = def.AuthorizationUrl
+				scheme.Flows.Implicit = flow
+			case gwoptions.SecurityScheme_FLOW_PASSWORD:
+				flow.TokenUrl = def.TokenUrl
+				scheme.Flows.Password = flow
+			case gwoptions.SecurityScheme_FLOW_APPLICATION:
+				flow.TokenUrl = def.TokenUrl
+				scheme.Flows.ClientCredentials = flow
+			case gwoptions.SecurityScheme_FLOW_ACCESS_CODE:
+				flow.AuthorizationUrl = def.AuthorizationUrl
+				flow.TokenUrl = def.TokenUrl
+				scheme.Flows.AuthorizationCode = flow
+			}
+		default:
+			slog.Warn("unknown openapiv2 security definition type", slog.String("name", name), slog.Any("type", def.Type))
+			continue
+		}
+		schemes.Set(name, scheme)
+	}
+	return schemes
+}
+
+// toServers builds the servers from the host, base path and schemes of OpenAPI v2. Without schemes, the server uses
+// https like protoc-gen-openapiv2 documents it.
+func toServers(swagger *gwoptions.Swagger) []*v3.Server {
+	if swagger.Host == "" && swagger.BasePath == "" {
+		return nil
+	}
+	if swagger.Host == "" {
+		return []*v3.Server{{URL: swagger.BasePath}}
+	}
+	schemes := swagger.Schemes
+	if len(schemes) == 0 {
+		schemes = []gwoptions.Scheme{gwoptions.Scheme_HTTPS}
+	}
+	servers := []*v3.Server{}
+	for _, scheme := range schemes {
+		if scheme == gwoptions.Scheme_UNKNOWN {
+			continue
+		}
+		servers = append(servers, &v3.Server{
+			URL: strings.ToLower(scheme.String()) + "://" + swagger.Host + swagger.BasePath,
+		})
+	}
+	return servers
+}
+
+func headerType(t gwoptions.HeaderParameter_Type) string {
+	switch t {
+	case gwoptions.HeaderParameter_STRING:
+		return "string"
+	case gwoptions.HeaderParameter_NUMBER:
+		return "number"
+	case gwoptions.HeaderParameter_INTEGER:
+		return "integer"
+	case gwoptions.HeaderParameter_BOOLEAN:
+		return "boolean"
+	}
+	return ""
+}
+
+func toHeaderParameter(header *gwoptions.HeaderParameter) *v3.Parameter {
+	s := &base.Schema{Format: header.Format}
+	if t := headerType(header.Type); t != "" {
+		s.Type = []string{t}
+	}
+	return &v3.Parameter{
+		Name:        header.Name,
+		In:          "header",
+		Description: header.Description,
+		Required:    util.BoolPtr(header.Required),
+		Schema:      base.CreateSchemaProxy(s),
+	}
+}
+
+func toHeaders(headers map[string]*gwoptions.Header) *orderedmap.Map[string, *v3.Header] {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := orderedmap.New[string, *v3.Header]()
+	for _, name := range sortedKeys(headers) {
+		header := headers[name]
+		s := &base.Schema{
+			Format:  header.Format,
+			Pattern: header.Pattern,
+			Default: jsonNode(header.Default),
+		}
+		if header.Type != "" {
+			s.Type = []string{header.Type}
+		}
+		result.Set(name, &v3.Header{
+			Description: header.Description,
+			Schema:      base.CreateSchemaProxy(s),
+		})
+	}
+	return result
+}
+
+// applyResponse adds an annotated response to the responses of an operation, or updates the generated one with the
+// same status code.
+func applyResponse(responses *v3.Responses, code string, annotated *gwoptions.Response) {
+	var resp *v3.Response
+	if code == "default" {
+		resp = responses.Default
+	} else if responses.Codes != nil {
+		resp, _ = responses.Codes.Get(code)
+	}
+	if resp == nil {
+		resp = &v3.Response{}
+		if code == "default" {
+			responses.Default = resp
+		} else {
+			if responses.Codes == nil {
+				responses.Codes = orderedmap.New[string, *v3.Response]()
+			}
+			responses.Codes.Set(code, resp)
+		}
+	}
+	setString(&resp.Description, annotated.Description)
+
+	if annotated.Schema != nil {
+		if resp.Content == nil || resp.Content.Len() == 0 {
+			resp.Content = orderedmap.New[string, *v3.MediaType]()
+			resp.Content.Set("application/json", &v3.MediaType{})
+		}
+		for _, mediaType := range resp.Content.FromOldest() {
+			mediaType.Schema = toSchemaProxy(annotated.Schema)
+		}
+	}
+	for _, contentType := range sortedKeys(annotated.Examples) {
+		if resp.Content == nil {
+			resp.Content = orderedmap.New[string, *v3.MediaType]()
+		}
+		mediaType, ok := resp.Content.Get(contentType)
+		if !ok {
+			mediaType = &v3.MediaType{}
+			resp.Content.Set(contentType, mediaType)
+		}
+		mediaType.Example = jsonNode(annotated.Examples[contentType])
+	}
+	if headers := toHeaders(annotated.Headers); headers != nil {
+		if resp.Headers == nil {
+			resp.Headers = orderedmap.New[string, *v3.Header]()
+		}
+		for name, header := range headers.FromOldest() {
+			resp.Headers.Set(name, header)
+		}
+	}
+	resp.Extensions = toExtensions(resp.Extensions, annotated.Extensions)
+}
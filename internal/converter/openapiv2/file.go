@@ -0,0 +1,85 @@
+package openapiv2
+
+import (
+	gwoptions "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// SpecWithFileAnnotations applies the openapiv2_swagger annotation of a file. The responses of the annotation are
+// added to the operations by PathItemWithMethodAnnotations, and consumes and produces aren't used since the content
+// types come from the protocols.
+func SpecWithFileAnnotations(opts options.Options, spec *v3.Document, fd protoreflect.FileDescriptor) {
+	annotation := fileAnnotation(opts, fd)
+	if annotation == nil {
+		return
+	}
+	if info := annotation.Info; info != nil {
+		setString(&spec.Info.Title, info.Title)
+		setString(&spec.Info.Description, info.Description)
+		setString(&spec.Info.TermsOfService, info.TermsOfService)
+		setString(&spec.Info.Version, info.Version)
+		if info.Contact != nil {
+			spec.Info.Contact = &base.Contact{
+				Name:  info.Contact.Name,
+				URL:   info.Contact.Url,
+				Email: info.Contact.Email,
+			}
+		}
+		if info.License != nil {
+			spec.Info.License = &base.License{
+				Name: info.License.Name,
+				URL:  info.License.Url,
+			}
+		}
+		spec.Info.Extensions = toExtensions(spec.Info.Extensions, info.Extensions)
+	}
+	spec.Servers = append(spec.Servers, toServers(annotation)...)
+	if schemes := toSecuritySchemes(annotation.SecurityDefinitions); schemes != nil {
+		util.AppendComponents(spec, &v3.Components{SecuritySchemes: schemes})
+	}
+	spec.Security = append(spec.Security, toSecurityRequirements(annotation.Security)...)
+	for _, tag := range annotation.Tags {
+		spec.Tags = append(spec.Tags, &base.Tag{
+			Name:         tag.Name,
+			Description:  tag.Description,
+			ExternalDocs: toExternalDocs(tag.ExternalDocs),
+			Extensions:   toExtensions(nil, tag.Extensions),
+		})
+	}
+	if docs := toExternalDocs(annotation.ExternalDocs); docs != nil {
+		spec.ExternalDocs = docs
+	}
+	spec.Extensions = toExtensions(spec.Extensions, annotation.Extensions)
+}
+
+// TagWithServiceAnnotations applies the openapiv2_tag annotation of a service to its tag. The name of the tag isn't
+// changed, since the operations refer to it.
+func TagWithServiceAnnotations(opts options.Options, tag *base.Tag, sd protoreflect.ServiceDescriptor) *base.Tag {
+	if !opts.WithOpenAPIv2Annotations || !proto.HasExtension(sd.Options(), gwoptions.E_Openapiv2Tag) {
+		return tag
+	}
+	annotation, ok := proto.GetExtension(sd.Options(), gwoptions.E_Openapiv2Tag).(*gwoptions.Tag)
+	if !ok {
+		return tag
+	}
+	setString(&tag.Description, annotation.Description)
+	if docs := toExternalDocs(annotation.ExternalDocs); docs != nil {
+		tag.ExternalDocs = docs
+	}
+	tag.Extensions = toExtensions(tag.Extensions, annotation.Extensions)
+	return tag
+}
+
+func fileAnnotation(opts options.Options, fd protoreflect.FileDescriptor) *gwoptions.Swagger {
+	if !opts.WithOpenAPIv2Annotations || !proto.HasExtension(fd.Options(), gwoptions.E_Openapiv2Swagger) {
+		return nil
+	}
+	annotation, _ := proto.GetExtension(fd.Options(), gwoptions.E_Openapiv2Swagger).(*gwoptions.Swagger)
+	return annotation
+}
@@ -0,0 +1,67 @@
+package openapiv2
+
+import (
+	gwoptions "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// PathItemWithMethodAnnotations applies the openapiv2_operation annotation of a method to its operations, after the
+// responses of the openapiv2_swagger annotation of its file. Tags of the annotation replace the tag of the service,
+// like they do with protoc-gen-openapiv2.
+func PathItemWithMethodAnnotations(opts options.Options, item *v3.PathItem, md protoreflect.MethodDescriptor) *v3.PathItem {
+	if !opts.WithOpenAPIv2Annotations {
+		return item
+	}
+	var annotation *gwoptions.Operation
+	if proto.HasExtension(md.Options(), gwoptions.E_Openapiv2Operation) {
+		annotation, _ = proto.GetExtension(md.Options(), gwoptions.E_Openapiv2Operation).(*gwoptions.Operation)
+	}
+	var fileResponses map[string]*gwoptions.Response
+	if file := fileAnnotation(opts, md.ParentFile()); file != nil {
+		fileResponses = file.Responses
+	}
+	if annotation == nil && len(fileResponses) == 0 {
+		return item
+	}
+
+	for _, oper := range item.GetOperations().FromOldest() {
+		if oper.Responses == nil {
+			oper.Responses = &v3.Responses{}
+		}
+		for _, code := range sortedKeys(fileResponses) {
+			applyResponse(oper.Responses, code, fileResponses[code])
+		}
+		if annotation == nil {
+			continue
+		}
+		for _, code := range sortedKeys(annotation.Responses) {
+			applyResponse(oper.Responses, code, annotation.Responses[code])
+		}
+
+		if len(annotation.Tags) > 0 {
+			oper.Tags = append([]string{}, annotation.Tags...)
+		}
+		setString(&oper.Summary, annotation.Summary)
+		setString(&oper.Description, annotation.Description)
+		setString(&oper.OperationId, annotation.OperationId)
+		if docs := toExternalDocs(annotation.ExternalDocs); docs != nil {
+			oper.ExternalDocs = docs
+		}
+		if annotation.Deprecated {
+			oper.Deprecated = util.BoolPtr(true)
+		}
+		if len(annotation.Security) > 0 {
+			oper.Security = toSecurityRequirements(annotation.Security)
+		}
+		for _, header := range annotation.GetParameters().GetHeaders() {
+			oper.Parameters = append(oper.Parameters, toHeaderParameter(header))
+		}
+		oper.Extensions = toExtensions(oper.Extensions, annotation.Extensions)
+	}
+	return item
+}
@@ -0,0 +1,165 @@
+package openapiv2
+
+import (
+	"strings"
+
+	gwoptions "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+func SchemaWithMessageAnnotations(opts options.Options, schema *base.Schema, desc protoreflect.MessageDescriptor) *base.Schema {
+	if !opts.WithOpenAPIv2Annotations || !proto.HasExtension(desc.Options(), gwoptions.E_Openapiv2Schema) {
+		return schema
+	}
+	annotation, ok := proto.GetExtension(desc.Options(), gwoptions.E_Openapiv2Schema).(*gwoptions.Schema)
+	if !ok {
+		return schema
+	}
+	applySchema(schema, annotation)
+	return schema
+}
+
+// SchemaWithFieldAnnotations applies the openapiv2_field annotation of a field. The items of repeated fields and
+// maps aren't annotated, the constraints belong to the field itself.
+func SchemaWithFieldAnnotations(opts options.Options, schema *base.Schema, desc protoreflect.FieldDescriptor, onlyScalar bool) *base.Schema {
+	if !opts.WithOpenAPIv2Annotations || onlyScalar || !proto.HasExtension(desc.Options(), gwoptions.E_Openapiv2Field) {
+		return schema
+	}
+	annotation, ok := proto.GetExtension(desc.Options(), gwoptions.E_Openapiv2Field).(*gwoptions.JSONSchema)
+	if !ok {
+		return schema
+	}
+	applyJSONSchema(schema, annotation)
+	return schema
+}
+
+func SchemaWithEnumAnnotations(opts options.Options, schema *base.Schema, desc protoreflect.EnumDescriptor) *base.Schema {
+	if !opts.WithOpenAPIv2Annotations || !proto.HasExtension(desc.Options(), gwoptions.E_Openapiv2Enum) {
+		return schema
+	}
+	annotation, ok := proto.GetExtension(desc.Options(), gwoptions.E_Openapiv2Enum).(*gwoptions.EnumSchema)
+	if !ok {
+		return schema
+	}
+	setString(&schema.Title, annotation.Title)
+	setString(&schema.Description, annotation.Description)
+	if node := jsonNode(annotation.Default); node != nil {
+		schema.Default = node
+	}
+	if annotation.ReadOnly {
+		schema.ReadOnly = util.BoolPtr(true)
+	}
+	if docs := toExternalDocs(annotation.ExternalDocs); docs != nil {
+		schema.ExternalDocs = docs
+	}
+	if node := jsonNode(annotation.Example); node != nil {
+		schema.Examples = append(schema.Examples, node)
+	}
+	schema.Extensions = toExtensions(schema.Extensions, annotation.Extensions)
+	return schema
+}
+
+func applySchema(schema *base.Schema, annotation *gwoptions.Schema) {
+	applyJSONSchema(schema, annotation.JsonSchema)
+	if annotation.Discriminator != "" {
+		schema.Discriminator = &base.Discriminator{PropertyName: annotation.Discriminator}
+	}
+	if annotation.ReadOnly {
+		schema.ReadOnly = util.BoolPtr(true)
+	}
+	if docs := toExternalDocs(annotation.ExternalDocs); docs != nil {
+		schema.ExternalDocs = docs
+	}
+	if node := jsonNode(annotation.Example); node != nil {
+		schema.Examples = append(schema.Examples, node)
+	}
+}
+
+// toSchemaProxy converts the schema of an annotated response. A reference of the JSON schema replaces the schema.
+func toSchemaProxy(annotation *gwoptions.Schema) *base.SchemaProxy {
+	if ref := annotation.GetJsonSchema().GetRef(); ref != "" {
+		return base.CreateSchemaProxyRef(toRef(ref))
+	}
+	schema := &base.Schema{}
+	applySchema(schema, annotation)
+	return base.CreateSchemaProxy(schema)
+}
+
+//gocyclo:ignore
+func applyJSONSchema(schema *base.Schema, js *gwoptions.JSONSchema) {
+	if js == nil {
+		return
+	}
+	setString(&schema.Title, js.Title)
+	setString(&schema.Description, js.Description)
+	setString(&schema.Format, js.Format)
+	setString(&schema.Pattern, js.Pattern)
+	if node := jsonNode(js.Default); node != nil {
+		schema.Default = node
+	}
+	if node := jsonNode(js.Example); node != nil {
+		schema.Examples = append(schema.Examples, node)
+	}
+	if js.ReadOnly {
+		schema.ReadOnly = util.BoolPtr(true)
+	}
+	if js.MultipleOf != 0 {
+		schema.MultipleOf = &js.MultipleOf
+	}
+	if js.Maximum != 0 {
+		if js.ExclusiveMaximum {
+			schema.ExclusiveMaximum = &base.DynamicValue[bool, float64]{N: 1, B: js.Maximum}
+		} else {
+			schema.Maximum = &js.Maximum
+		}
+	}
+	if js.Minimum != 0 {
+		if js.ExclusiveMinimum {
+			schema.ExclusiveMinimum = &base.DynamicValue[bool, float64]{N: 1, B: js.Minimum}
+		} else {
+			schema.Minimum = &js.Minimum
+		}
+	}
+	setCount(&schema.MaxLength, js.MaxLength)
+	setCount(&schema.MinLength, js.MinLength)
+	setCount(&schema.MaxItems, js.MaxItems)
+	setCount(&schema.MinItems, js.MinItems)
+	setCount(&schema.MaxProperties, js.MaxProperties)
+	setCount(&schema.MinProperties, js.MinProperties)
+	if js.UniqueItems {
+		schema.UniqueItems = util.BoolPtr(true)
+	}
+	for _, name := range js.Required {
+		schema.Required = util.AppendStringDedupe(schema.Required, name)
+	}
+	if len(js.Type) > 0 {
+		types := make([]string, 0, len(js.Type))
+		for _, t := range js.Type {
+			if t != gwoptions.JSONSchema_UNKNOWN {
+				types = append(types, strings.ToLower(t.String()))
+			}
+		}
+		schema.Type = types
+	}
+	if len(js.Enum) > 0 {
+		enum := make([]*yaml.Node, len(js.Enum))
+		for i, value := range js.Enum {
+			enum[i] = util.StringNode(value)
+		}
+		schema.Enum = enum
+	}
+	schema.Extensions = toExtensions(schema.Extensions, js.Extensions)
+}
+
+func setCount(dst **int64, v uint64) {
+	if v > 0 {
+		n := int64(v)
+		*dst = &n
+	}
+}
@@ -0,0 +1,543 @@
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/protovalidate"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/schema"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+	"gopkg.in/yaml.v3"
+)
+
+// decoratePathItem applies the options that affect every operation, regardless of whether the operation was
+// generated for the Connect protocol or from google.api.http annotations.
+func decoratePathItem(opts options.Options, method protoreflect.MethodDescriptor, item *v3.PathItem) {
+	isStreaming := method.IsStreamingClient() || method.IsStreamingServer()
+	mediaTypes := responseMediaTypes(method)
+	var validationErrors *v3.Response
+	if opts.WithValidationErrors {
+		validationErrors = validationErrorResponse(opts, method, isStreaming)
+	}
+	for pair := item.GetOperations().First(); pair != nil; pair = pair.Next() {
+		op := pair.Value()
+		if op == nil {
+			continue
+		}
+		markInternal(opts, method, op)
+		if opts.WithTraceHeaders {
+			op.Parameters = append(op.Parameters, traceHeaderParameters()...)
+		}
+		if opts.WithIdempotencyKey && !isStreaming && isMutatingOperation(opts, pair.Key(), method) {
+			op.Parameters = append(op.Parameters, idempotencyKeyParameter())
+			setResponse(op, "409", &v3.Response{
+				Description: "Conflict: a request with the same Idempotency-Key is still being processed or was sent with a different request body",
+				Content: util.MakeMediaTypes(
+					opts,
+					base.CreateSchemaProxyRef(opts.ErrorSchemaRef()),
+					false,
+					isStreaming,
+				),
+			})
+		}
+		if mediaTypes.Len() > 0 && op.Responses != nil && op.Responses.Codes != nil {
+			if response, ok := op.Responses.Codes.Get("200"); ok && response.Content != nil {
+				for pair := mediaTypes.First(); pair != nil; pair = pair.Next() {
+					response.Content.Set(pair.Key(), &v3.MediaType{
+						Schema: base.CreateSchemaProxyRef("#/components/schemas/" + string(pair.Value())),
+					})
+				}
+			}
+		}
+		if contentType := gnostic.MethodContentType(method, gnostic.RequestContentTypeExtension); contentType != "" && op.RequestBody != nil {
+			op.RequestBody.Content = overrideContentType(op.RequestBody.Content, contentType)
+		}
+		if contentType := gnostic.MethodContentType(method, gnostic.ResponseContentTypeExtension); contentType != "" && op.Responses != nil && op.Responses.Codes != nil {
+			if response, ok := op.Responses.Codes.Get("200"); ok {
+				response.Content = overrideContentType(response.Content, contentType)
+			}
+		}
+		if !isStreaming && op.RequestBody != nil && gnostic.IsMultipartForm(method.Input()) {
+			op.RequestBody.Content = multipartFormContent(opts, method.Input())
+		}
+		if !isStreaming && (opts.WithFileTransfers || gnostic.IsFileTransfer(method)) {
+			applyFileTransfer(opts, method, op)
+		}
+		applyMaxBodyBytes(opts, method, op)
+		if isStreaming {
+			applyHTTPVersionRequirements(method, op)
+		}
+		applyWatch(method, op)
+		applyReplacedBy(opts, method, op)
+		applyAIPs(opts, method, pair.Key(), op, isStreaming)
+		if opts.WithRateLimitResponses {
+			setResponse(op, "429", rateLimitResponse(opts, isStreaming))
+		}
+		if validationErrors != nil {
+			setResponse(op, "400", validationErrors)
+		}
+		if opts.WithConnectValidation {
+			if summary := protovalidate.ValidationSummary(opts, method.Input()); summary != nil {
+				op.Extensions = util.SetExtension(op.Extensions, protovalidate.ValidationExtension, summary)
+			}
+		}
+		if opts.WithLifecycleHeaders {
+			applyLifecycleHeaders(method, op)
+		}
+	}
+}
+
+// HTTPVersionRequirementsExtension is an operation extension on streaming operations with the minimum HTTP version
+// that the operation can be used over.
+const HTTPVersionRequirementsExtension = "x-http-version-requirements"
+
+// applyHTTPVersionRequirements documents which HTTP versions a streaming operation can be used over. Bidirectional
+// streams need HTTP/2 because both sides send messages at the same time, while client and server streams are
+// half-duplex and also work over HTTP/1.1.
+func applyHTTPVersionRequirements(method protoreflect.MethodDescriptor, op *v3.Operation) {
+	streamType, minimum, note := "", "1.1", ""
+	switch {
+	case method.IsStreamingClient() && method.IsStreamingServer():
+		streamType, minimum = "bidi", "2"
+		note = "This is a bidirectional streaming operation and requires HTTP/2."
+	case method.IsStreamingClient():
+		streamType = "client"
+		note = "This is a client streaming operation and can be used over HTTP/1.1 or HTTP/2."
+	default:
+		streamType = "server"
+		note = "This is a server streaming operation and can be used over HTTP/1.1 or HTTP/2."
+	}
+
+	node, err := util.ExtensionNode(map[string]any{
+		"streamType":      streamType,
+		"minimumVersion":  minimum,
+		"http1Compatible": minimum != "2",
+	})
+	if err != nil {
+		return
+	}
+	op.Extensions = util.SetExtension(op.Extensions, HTTPVersionRequirementsExtension, node)
+	appendDescription(op, note)
+}
+
+func rateLimitResponse(opts options.Options, isStreaming bool) *v3.Response {
+	headers := orderedmap.New[string, *v3.Header]()
+	headers.Set("Retry-After", &v3.Header{
+		Description: "The number of seconds to wait before retrying the request.",
+		Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"integer"}}),
+	})
+	headers.Set("X-RateLimit-Limit", &v3.Header{
+		Description: "The maximum number of requests allowed in the current window.",
+		Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"integer"}}),
+	})
+	headers.Set("X-RateLimit-Remaining", &v3.Header{
+		Description: "The number of requests remaining in the current window.",
+		Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"integer"}}),
+	})
+	headers.Set("X-RateLimit-Reset", &v3.Header{
+		Description: "The number of seconds until the current window resets.",
+		Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"integer"}}),
+	})
+	return &v3.Response{
+		Description: "Too Many Requests",
+		Headers:     headers,
+		Content: util.MakeMediaTypes(
+			opts,
+			base.CreateSchemaProxyRef(opts.ErrorSchemaRef()),
+			false,
+			isStreaming,
+		),
+	}
+}
+
+// isMutatingOperation returns true if the operation can have side effects. GET and HEAD operations and methods
+// without side effects, like ones marked with `idempotency_level = NO_SIDE_EFFECTS`, are never considered mutating.
+func isMutatingOperation(opts options.Options, httpMethod string, method protoreflect.MethodDescriptor) bool {
+	switch strings.ToUpper(httpMethod) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return !hasNoSideEffects(opts, method)
+}
+
+// overrideContentType replaces the media types of a request or response with a single media type. JSON media types
+// keep the schema of the message, text media types are a string and anything else is sent as raw bytes.
+func overrideContentType(content *orderedmap.Map[string, *v3.MediaType], contentType string) *orderedmap.Map[string, *v3.MediaType] {
+	var schema *base.SchemaProxy
+	switch {
+	case isJSONContentType(contentType):
+		for mediaType := range content.ValuesFromOldest() {
+			if mediaType != nil && mediaType.Schema != nil {
+				schema = mediaType.Schema
+				break
+			}
+		}
+	case strings.HasPrefix(contentType, "text/"):
+		schema = base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}})
+	default:
+		schema = base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}, Format: "binary"})
+	}
+	res := orderedmap.New[string, *v3.MediaType]()
+	res.Set(contentType, &v3.MediaType{Schema: schema})
+	return res
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// applyFileTransfer renders a request that is a single bytes field as a multipart/form-data upload with one file
+// part, and a response that is a single bytes field as an application/octet-stream download.
+func applyFileTransfer(opts options.Options, method protoreflect.MethodDescriptor, op *v3.Operation) {
+	if field := singleBytesField(method.Input()); field != nil && op.RequestBody != nil {
+		name := util.MakeFieldName(opts, field)
+		props := orderedmap.New[string, *base.SchemaProxy]()
+		props.Set(name, base.CreateSchemaProxy(&base.Schema{
+			Type:        []string{"string"},
+			Format:      "binary",
+			Description: util.FormatComments(field.ParentFile().SourceLocations().ByDescriptor(field)),
+		}))
+		content := orderedmap.New[string, *v3.MediaType]()
+		content.Set("multipart/form-data", &v3.MediaType{
+			Schema: base.CreateSchemaProxy(&base.Schema{
+				Type:       []string{"object"},
+				Properties: props,
+				Required:   []string{name},
+			}),
+		})
+		op.RequestBody.Content = content
+	}
+	if singleBytesField(method.Output()) != nil && op.Responses != nil && op.Responses.Codes != nil {
+		if response, ok := op.Responses.Codes.Get("200"); ok {
+			response.Content = overrideContentType(response.Content, "application/octet-stream")
+		}
+	}
+}
+
+// applyMaxBodyBytes documents the maximum size of the request body with an x-max-body-bytes extension. Request
+// bodies that are sent as raw strings or files, like uploads, also get the limit as their maxLength. The limit of the
+// x-max-body-bytes extension on a method wins over the max-body-bytes option.
+func applyMaxBodyBytes(opts options.Options, method protoreflect.MethodDescriptor, op *v3.Operation) {
+	maxBodyBytes, ok := gnostic.MaxBodyBytes(method)
+	if !ok {
+		maxBodyBytes = opts.MaxBodyBytes
+	}
+	if maxBodyBytes <= 0 || op.RequestBody == nil || op.RequestBody.Content == nil {
+		return
+	}
+	op.Extensions = util.SetExtension(op.Extensions, gnostic.MaxBodyBytesExtension, util.IntNode(maxBodyBytes))
+	for mediaType := range op.RequestBody.Content.ValuesFromOldest() {
+		if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.IsReference() {
+			continue
+		}
+		s := mediaType.Schema.Schema()
+		if s == nil {
+			continue
+		}
+		if isStringSchema(s) {
+			s.MaxLength = &maxBodyBytes
+			continue
+		}
+		if s.Properties == nil {
+			continue
+		}
+		// Parts of a multipart/form-data body that are files can't be larger than the whole body.
+		for prop := range s.Properties.ValuesFromOldest() {
+			if prop == nil || prop.IsReference() {
+				continue
+			}
+			if part := prop.Schema(); part != nil && isStringSchema(part) && part.Format == "binary" {
+				part.MaxLength = &maxBodyBytes
+			}
+		}
+	}
+}
+
+// applyReplacedBy points deprecated operations at the method that replaces them, which is set with the x-replaced-by
+// extension. The operation gets a note about its successor and the extension is set to the operationId of the
+// successor instead of its full name.
+func applyReplacedBy(opts options.Options, method protoreflect.MethodDescriptor, op *v3.Operation) {
+	successor := gnostic.ReplacedBy(method)
+	if successor == "" {
+		return
+	}
+	if !isDeprecatedOperation(method, op) {
+		if op.Extensions != nil {
+			op.Extensions.Delete(gnostic.ReplacedByExtension)
+		}
+		return
+	}
+	if !successor.IsValid() || !successor.Parent().IsValid() {
+		slog.Warn("x-replaced-by should be the full name of a method", slog.String("method", string(method.FullName())), slog.String("value", string(successor)))
+		return
+	}
+	op.Deprecated = util.BoolPtr(true)
+	operationId := string(successor)
+	if opts.ShortOperationIds {
+		operationId = string(successor.Parent().Name()) + "_" + string(successor.Name())
+	}
+	appendDescription(op, fmt.Sprintf("Deprecated: use %s instead.", operationId))
+	op.Extensions = util.SetExtension(op.Extensions, gnostic.ReplacedByExtension, util.StringNode(operationId))
+}
+
+func isStringSchema(s *base.Schema) bool {
+	return len(s.Type) == 1 && s.Type[0] == "string"
+}
+
+// multipartFormContent renders a message as a multipart/form-data request with a part for each field. Bytes fields
+// are sent as files and the content type of each part can be set with the x-content-type extension.
+func multipartFormContent(opts options.Options, msg protoreflect.MessageDescriptor) *orderedmap.Map[string, *v3.MediaType] {
+	form := &base.Schema{
+		Type:       []string{"object"},
+		Properties: orderedmap.New[string, *base.SchemaProxy](),
+	}
+	parent := base.CreateSchemaProxy(form)
+	encoding := orderedmap.New[string, *v3.Encoding]()
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		name := util.MakeFieldName(opts, field)
+		prop := schema.FieldToSchema(opts, parent, field)
+		if field.Kind() == protoreflect.BytesKind && !field.IsMap() {
+			if s := prop.Schema(); field.IsList() && s.Items != nil && s.Items.A != nil {
+				s.Items.A.Schema().Format = "binary"
+			} else {
+				s.Format = "binary"
+			}
+		}
+		form.Properties.Set(name, prop)
+		if contentType := gnostic.PartContentType(field); contentType != "" {
+			encoding.Set(name, &v3.Encoding{ContentType: contentType})
+		}
+	}
+	mediaType := &v3.MediaType{Schema: parent}
+	if encoding.Len() > 0 {
+		mediaType.Encoding = encoding
+	}
+	content := orderedmap.New[string, *v3.MediaType]()
+	content.Set("multipart/form-data", mediaType)
+	return content
+}
+
+// singleBytesField returns the only field of a message if it's a singular bytes field.
+func singleBytesField(msg protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	if msg == nil || msg.Fields().Len() != 1 {
+		return nil
+	}
+	field := msg.Fields().Get(0)
+	if field.Kind() != protoreflect.BytesKind || field.Cardinality() == protoreflect.Repeated {
+		return nil
+	}
+	return field
+}
+
+// setResponse adds a response to an operation unless a response for that status code already exists.
+func setResponse(op *v3.Operation, code string, response *v3.Response) {
+	if op.Responses == nil {
+		op.Responses = &v3.Responses{}
+	}
+	if op.Responses.Codes == nil {
+		op.Responses.Codes = orderedmap.New[string, *v3.Response]()
+	}
+	if _, ok := op.Responses.Codes.Get(code); ok {
+		return
+	}
+	op.Responses.Codes.Set(code, response)
+}
+
+// traceHeaderParameters returns the W3C Trace Context and Baggage headers that are added with with-trace-headers.
+func traceHeaderParameters() []*v3.Parameter {
+	return []*v3.Parameter{
+		{
+			Name:        "traceparent",
+			In:          "header",
+			Description: "W3C Trace Context header with the trace ID, parent span ID and trace flags. See https://www.w3.org/TR/trace-context/#traceparent-header",
+			Schema: base.CreateSchemaProxy(&base.Schema{
+				Type:     []string{"string"},
+				Pattern:  "^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$",
+				Examples: []*yaml.Node{util.StringNode("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+			}),
+		},
+		{
+			Name:        "tracestate",
+			In:          "header",
+			Description: "W3C Trace Context header with vendor-specific trace data. See https://www.w3.org/TR/trace-context/#tracestate-header",
+			Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+		},
+		{
+			Name:        "baggage",
+			In:          "header",
+			Description: "W3C Baggage header with user-defined properties propagated alongside the trace. See https://www.w3.org/TR/baggage/",
+			Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}),
+		},
+	}
+}
+
+// idempotencyKeyParameter returns the Idempotency-Key header that is added to mutating operations with
+// with-idempotency-key. Requests without it are still accepted, they just can't be retried safely.
+func idempotencyKeyParameter() *v3.Parameter {
+	return &v3.Parameter{
+		Name:        "Idempotency-Key",
+		In:          "header",
+		Description: "A unique key, like a UUID, that identifies this request. Retrying a request with the same key will not perform the operation more than once.",
+		Required:    util.BoolPtr(false),
+		Schema: base.CreateSchemaProxy(&base.Schema{
+			Type:     []string{"string"},
+			Examples: []*yaml.Node{util.StringNode("8e03978e-40d5-43e8-bc93-6894a57f9324")},
+		}),
+	}
+}
+
+// responseMediaTypes returns the media types of the x-response-media-types extension of a method, leaving out the
+// ones that name a message that can't be found, since their $ref would point to a schema that doesn't exist.
+func responseMediaTypes(method protoreflect.MethodDescriptor) *orderedmap.Map[string, protoreflect.FullName] {
+	mediaTypes := orderedmap.New[string, protoreflect.FullName]()
+	for pair := gnostic.ResponseMediaTypes(method).First(); pair != nil; pair = pair.Next() {
+		if util.FindMessage(method.ParentFile(), pair.Value()) == nil {
+			slog.Warn("unknown message in x-response-media-types", slog.String("method", string(method.FullName())), slog.String("mediaType", pair.Key()), slog.String("message", string(pair.Value())))
+			continue
+		}
+		mediaTypes.Set(pair.Key(), pair.Value())
+	}
+	return mediaTypes
+}
+
+// addGlobalResponses adds the responses configured with `global-responses` to every operation in the spec. The
+// responses are looked up by name from components.responses, which are normally defined in the base file. A
+// global response for the `default` code replaces the generated error response.
+func addGlobalResponses(opts options.Options, spec *v3.Document) error {
+	if len(opts.GlobalResponses) == 0 {
+		return nil
+	}
+	for _, global := range opts.GlobalResponses {
+		var response *v3.Response
+		ok := false
+		if spec.Components != nil && spec.Components.Responses != nil {
+			response, ok = spec.Components.Responses.Get(global.Response)
+		}
+		if !ok {
+			return fmt.Errorf("global response '%s' not found in components.responses", global.Response)
+		}
+		for item := range spec.Paths.PathItems.ValuesFromOldest() {
+			for op := range item.GetOperations().ValuesFromOldest() {
+				if op == nil {
+					continue
+				}
+				if global.Code == "default" {
+					if op.Responses == nil {
+						op.Responses = &v3.Responses{}
+					}
+					op.Responses.Default = response
+					continue
+				}
+				setResponse(op, global.Code, response)
+			}
+		}
+	}
+	return nil
+}
+
+// applyDefaultResponses changes the `default` response of every operation for the `default-response` option. With
+// off, default responses are removed. With echo, they reference the configured schema from components.schemas
+// instead of the error schema.
+func applyDefaultResponses(opts options.Options, spec *v3.Document) error {
+	switch opts.DefaultResponse {
+	case options.DefaultResponseOff, options.DefaultResponseEcho:
+	default:
+		return nil
+	}
+	if opts.DefaultResponse == options.DefaultResponseEcho {
+		if spec.Components == nil || spec.Components.Schemas == nil || spec.Components.Schemas.GetOrZero(opts.DefaultResponseSchema) == nil {
+			return fmt.Errorf("default response schema '%s' not found in components.schemas", opts.DefaultResponseSchema)
+		}
+	}
+	if spec.Paths == nil {
+		return nil
+	}
+	for item := range spec.Paths.PathItems.ValuesFromOldest() {
+		for op := range item.GetOperations().ValuesFromOldest() {
+			if op == nil || op.Responses == nil || op.Responses.Default == nil {
+				continue
+			}
+			if opts.DefaultResponse == options.DefaultResponseOff {
+				op.Responses.Default = nil
+				continue
+			}
+			// Copy the response, which can be shared by operations, before changing its content
+			response := *op.Responses.Default
+			content := orderedmap.New[string, *v3.MediaType]()
+			for pair := response.Content.First(); pair != nil; pair = pair.Next() {
+				content.Set(pair.Key(), &v3.MediaType{
+					Schema: base.CreateSchemaProxyRef("#/components/schemas/" + opts.DefaultResponseSchema),
+				})
+			}
+			response.Content = content
+			op.Responses.Default = &response
+		}
+	}
+	return nil
+}
+
+// wrapResponseEnvelopes wraps the JSON body of every successful response with the schema configured with
+// `response-envelope`. The envelope's slot property is replaced with the original response schema.
+func wrapResponseEnvelopes(opts options.Options, spec *v3.Document) error {
+	if opts.ResponseEnvelope == "" {
+		return nil
+	}
+	envelopeProxy, ok := spec.Components.Schemas.Get(opts.ResponseEnvelope)
+	if !ok {
+		return fmt.Errorf("response envelope '%s' not found in components.schemas", opts.ResponseEnvelope)
+	}
+	envelope := envelopeProxy.Schema()
+	if envelope == nil || envelope.Properties == nil {
+		return fmt.Errorf("response envelope '%s' has no properties", opts.ResponseEnvelope)
+	}
+	if _, ok := envelope.Properties.Get(opts.ResponseEnvelopeSlot); !ok {
+		return fmt.Errorf("response envelope '%s' has no '%s' property", opts.ResponseEnvelope, opts.ResponseEnvelopeSlot)
+	}
+	for item := range spec.Paths.PathItems.ValuesFromOldest() {
+		for op := range item.GetOperations().ValuesFromOldest() {
+			if op == nil || op.Responses == nil || op.Responses.Codes == nil {
+				continue
+			}
+			response, ok := op.Responses.Codes.Get("200")
+			if !ok || response.Content == nil {
+				continue
+			}
+			mediaType, ok := response.Content.Get("application/json")
+			if !ok || mediaType.Schema == nil {
+				continue
+			}
+			mediaType.Schema = base.CreateSchemaProxy(envelopeSchema(envelope, opts.ResponseEnvelopeSlot, mediaType.Schema))
+		}
+	}
+	return nil
+}
+
+func envelopeSchema(envelope *base.Schema, slot string, data *base.SchemaProxy) *base.Schema {
+	props := orderedmap.New[string, *base.SchemaProxy]()
+	for pair := envelope.Properties.First(); pair != nil; pair = pair.Next() {
+		if pair.Key() == slot {
+			props.Set(pair.Key(), data)
+		} else {
+			props.Set(pair.Key(), pair.Value())
+		}
+	}
+	return &base.Schema{
+		Type:                 envelope.Type,
+		Description:          envelope.Description,
+		Properties:           props,
+		Required:             envelope.Required,
+		AdditionalProperties: envelope.AdditionalProperties,
+	}
+}
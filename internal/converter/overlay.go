@@ -0,0 +1,56 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pb33f/libopenapi"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/speakeasy-api/jsonpath/pkg/overlay"
+	"gopkg.in/yaml.v3"
+)
+
+// applyOverlay applies the actions of an OpenAPI Overlay document to the spec. The spec is rendered, changed and
+// parsed again, so everything that's written afterwards sees the result.
+func applyOverlay(spec *v3.Document, content []byte) error {
+	o := &overlay.Overlay{}
+	if err := yaml.Unmarshal(content, o); err != nil {
+		return fmt.Errorf("unable to parse the overlay: %w", err)
+	}
+	if err := o.Validate(); err != nil {
+		return fmt.Errorf("invalid overlay: %w", err)
+	}
+	return rewriteSpec(spec, fmt.Sprintf("overlay '%s'", o.Info.Title), func(root *yaml.Node) error {
+		if err := o.ApplyTo(root); err != nil {
+			return fmt.Errorf("applying overlay '%s': %w", o.Info.Title, err)
+		}
+		return nil
+	})
+}
+
+// rewriteSpec renders the spec, lets fn change the rendered YAML and parses the result back into the spec, so
+// everything that's written afterwards sees the changes. The name of the change is used in errors.
+func rewriteSpec(spec *v3.Document, name string, fn func(root *yaml.Node) error) error {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(spec.RenderWithIndention(2), root); err != nil {
+		return err
+	}
+	if err := fn(root); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(root)
+	if err != nil {
+		return err
+	}
+
+	document, err := libopenapi.NewDocument(b)
+	if err != nil {
+		return fmt.Errorf("%s produced an invalid document: %w", name, err)
+	}
+	model, errs := document.BuildV3Model()
+	if len(errs) > 0 {
+		return fmt.Errorf("%s produced an invalid document: %w", name, errors.Join(errs...))
+	}
+	*spec = model.Model
+	return nil
+}
@@ -1,18 +1,23 @@
 package converter
 
 import (
+	"strings"
+	"unicode"
+
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/googleapi"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/openapiv2"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
-func addPathItemsFromFile(opts options.Options, fd protoreflect.FileDescriptor, paths *v3.Paths) error {
+func addPathItemsFromFile(opts options.Options, fd protoreflect.FileDescriptor, spec *v3.Document) error {
+	paths := spec.Paths
 	services := fd.Services()
 	for i := 0; i < services.Len(); i++ {
 		service := services.Get(i)
@@ -27,6 +32,16 @@ func addPathItemsFromFile(opts options.Options, fd protoreflect.FileDescriptor,
 			// Helper function to update or set path items
 			addPathItem := func(path string, newItem *v3.PathItem) {
 				path = util.MakePath(opts, path)
+				decoratePathItem(opts, method, newItem)
+				if opts.PathCase != "" && opts.PathCase != options.PathCaseAsIs {
+					setRPCPath(newItem, method)
+				}
+				for httpMethod := range newItem.GetOperations().KeysFromOldest() {
+					opts.Conversion().AddRoute(path, httpMethod, method)
+				}
+				if opts.WithCodeSamples {
+					addCodeSamples(opts, method, codeSampleBaseURL(spec), path, newItem)
+				}
 				if existing, ok := paths.PathItems.Get(path); !ok {
 					paths.PathItems.Set(path, newItem)
 				} else {
@@ -36,15 +51,29 @@ func addPathItemsFromFile(opts options.Options, fd protoreflect.FileDescriptor,
 			}
 
 			// Update path items from google.api annotations
+			restPaths := []string{}
+			restItems := []*v3.PathItem{}
 			for pair := pathItems.First(); pair != nil; pair = pair.Next() {
-				item := gnostic.PathItemWithMethodAnnotations(pair.Value(), method)
-				addPathItem(pair.Key(), item)
+				restPaths = append(restPaths, pair.Key())
+				item := openapiv2.PathItemWithMethodAnnotations(opts, pair.Value(), method)
+				restItems = append(restItems, gnostic.PathItemWithMethodAnnotations(opts, item, method))
+			}
+
+			// Default to ConnectRPC/gRPC path if no google.api annotations. Transcoders only serve the methods
+			// that have google.api.http annotations.
+			var connectItem *v3.PathItem
+			if !opts.Flavor.IsTranscoder() && (len(restItems) == 0 || opts.WithConnectPaths) {
+				connectItem = methodToPathItem(opts, method)
+			}
+			if len(restItems) > 0 && connectItem != nil {
+				linkRouteStyles(restItems, connectItem)
 			}
 
-			// Default to ConnectRPC/gRPC path if no google.api annotations
-			if pathItems == nil || pathItems.Len() == 0 {
-				path := "/" + string(service.FullName()) + "/" + string(method.Name())
-				addPathItem(path, methodToPathItem(opts, method))
+			for i, item := range restItems {
+				addPathItem(restPaths[i], item)
+			}
+			if connectItem != nil {
+				addPathItem("/"+string(service.FullName())+"/"+string(method.Name()), connectItem)
 			}
 		}
 	}
@@ -52,6 +81,49 @@ func addPathItemsFromFile(opts options.Options, fd protoreflect.FileDescriptor,
 	return nil
 }
 
+// RPCPathExtension is an operation extension with the path of the method in the Connect and gRPC protocols, like
+// /acme.v1.BookService/ListBooks. It's added when the path-case option changes the paths.
+const RPCPathExtension = "x-rpc-path"
+
+func setRPCPath(item *v3.PathItem, method protoreflect.MethodDescriptor) {
+	rpcPath := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+	for op := range item.GetOperations().ValuesFromOldest() {
+		op.Extensions = util.SetExtension(op.Extensions, RPCPathExtension, util.StringNode(rpcPath))
+	}
+}
+
+// AlternateOperationsExtension lists the operationIds of the same method in the other route style when a method is
+// documented with both its google.api.http paths and its Connect path.
+const AlternateOperationsExtension = "x-alternate-operations"
+
+// linkRouteStyles gives the Connect operations of a method that also has google.api.http paths their own
+// operationIds and links the operations of both route styles to each other.
+func linkRouteStyles(restItems []*v3.PathItem, connectItem *v3.PathItem) {
+	restIDs := []string{}
+	for _, item := range restItems {
+		for op := range item.GetOperations().ValuesFromOldest() {
+			restIDs = append(restIDs, op.OperationId)
+		}
+	}
+	connectIDs := []string{}
+	for op := range connectItem.GetOperations().ValuesFromOldest() {
+		op.OperationId += ".connect"
+		connectIDs = append(connectIDs, op.OperationId)
+	}
+
+	link := func(op *v3.Operation, ids []string) {
+		op.Extensions = util.SetExtension(op.Extensions, AlternateOperationsExtension, util.StringListNode(ids))
+	}
+	for _, item := range restItems {
+		for op := range item.GetOperations().ValuesFromOldest() {
+			link(op, connectIDs)
+		}
+	}
+	for op := range connectItem.GetOperations().ValuesFromOldest() {
+		link(op, restIDs)
+	}
+}
+
 func mergePathItems(existing, new *v3.PathItem) {
 	// Merge operations
 	operations := []struct {
@@ -219,6 +291,22 @@ func mergeResponse(existing, new *v3.Response) {
 	}
 }
 
+// addEndStreamFrame documents that the stream of messages in a Connect streaming response ends with an
+// end-of-stream frame, which holds the error and the trailing metadata of the RPC.
+func addEndStreamFrame(content *orderedmap.Map[string, *v3.MediaType]) {
+	for contentType, mediaType := range content.FromOldest() {
+		if contentType != "application/connect+json" && contentType != "application/connect+proto" {
+			continue
+		}
+		mediaType.Schema = base.CreateSchemaProxy(&base.Schema{
+			AnyOf: []*base.SchemaProxy{
+				mediaType.Schema,
+				base.CreateSchemaProxyRef("#/components/schemas/connect.end-stream"),
+			},
+		})
+	}
+}
+
 func methodToOperaton(opts options.Options, method protoreflect.MethodDescriptor, returnGet bool) *v3.Operation {
 	fd := method.ParentFile()
 	service := method.Parent().(protoreflect.ServiceDescriptor)
@@ -234,7 +322,7 @@ func methodToOperaton(opts options.Options, method protoreflect.MethodDescriptor
 	}
 
 	op := &v3.Operation{
-		Summary:     string(method.Name()),
+		Summary:     util.MethodSummary(opts, method),
 		OperationId: operationId,
 		Deprecated:  util.IsMethodDeprecated(method),
 		Tags:        []string{tagName},
@@ -249,14 +337,18 @@ func methodToOperaton(opts options.Options, method protoreflect.MethodDescriptor
 	// Responses
 	codeMap := orderedmap.New[string, *v3.Response]()
 	outputId := util.FormatTypeRef(string(method.Output().FullName()))
+	content := util.MakeMediaTypes(
+		opts,
+		base.CreateSchemaProxyRef("#/components/schemas/"+outputId),
+		false,
+		isStreaming,
+	)
+	if isStreaming && opts.WithEndStreamFrame && !opts.Flavor.IsTranscoder() {
+		addEndStreamFrame(content)
+	}
 	codeMap.Set("200", &v3.Response{
 		Description: "Success",
-		Content: util.MakeMediaTypes(
-			opts,
-			base.CreateSchemaProxyRef("#/components/schemas/"+outputId),
-			false,
-			isStreaming,
-		),
+		Content:     content,
 	})
 	op.Responses = &v3.Responses{
 		Codes: codeMap,
@@ -264,26 +356,28 @@ func methodToOperaton(opts options.Options, method protoreflect.MethodDescriptor
 			Description: "Error",
 			Content: util.MakeMediaTypes(
 				opts,
-				base.CreateSchemaProxyRef("#/components/schemas/connect.error"),
+				base.CreateSchemaProxyRef(opts.ErrorSchemaRef()),
 				false,
 				isStreaming,
 			),
 		},
 	}
 
-	op.Parameters = append(op.Parameters,
-		&v3.Parameter{
-			Name:     "Connect-Protocol-Version",
-			In:       "header",
-			Required: util.BoolPtr(true),
-			Schema:   base.CreateSchemaProxyRef("#/components/schemas/connect-protocol-version"),
-		},
-		&v3.Parameter{
-			Name:   "Connect-Timeout-Ms",
-			In:     "header",
-			Schema: base.CreateSchemaProxyRef("#/components/schemas/connect-timeout-header"),
-		},
-	)
+	if !opts.Flavor.IsTranscoder() {
+		op.Parameters = append(op.Parameters,
+			&v3.Parameter{
+				Name:     "Connect-Protocol-Version",
+				In:       "header",
+				Required: util.BoolPtr(true),
+				Schema:   base.CreateSchemaProxyRef("#/components/schemas/connect-protocol-version"),
+			},
+			&v3.Parameter{
+				Name:   "Connect-Timeout-Ms",
+				In:     "header",
+				Schema: base.CreateSchemaProxyRef("#/components/schemas/connect-timeout-header"),
+			},
+		)
+	}
 
 	// Request parameters
 	inputId := util.FormatTypeRef(string(method.Input().FullName()))
@@ -343,13 +437,15 @@ func methodToPathItem(opts options.Options, method protoreflect.MethodDescriptor
 		item.Get = methodToOperaton(opts, method, true)
 	}
 	item.Post = methodToOperaton(opts, method, false)
-	item = gnostic.PathItemWithMethodAnnotations(item, method)
+	item = openapiv2.PathItemWithMethodAnnotations(opts, item, method)
+	item = gnostic.PathItemWithMethodAnnotations(opts, item, method)
 
 	return item
 }
 
 func methodHasGet(opts options.Options, method protoreflect.MethodDescriptor) bool {
-	if !opts.AllowGET {
+	// The Connect GET encoding isn't understood by transcoders
+	if !opts.AllowGET || opts.Flavor.IsTranscoder() {
 		return false
 	}
 
@@ -357,6 +453,37 @@ func methodHasGet(opts options.Options, method protoreflect.MethodDescriptor) bo
 		return false
 	}
 
-	options := method.Options().(*descriptorpb.MethodOptions)
-	return options.GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+	return hasNoSideEffects(opts, method)
+}
+
+// hasNoSideEffects returns true if a method doesn't change anything. The x-no-side-effects extension wins over
+// `idempotency_level`. With the infer-get-from-names option, methods without an `idempotency_level` that are
+// named like a read, such as GetBook, ListBooks or BatchGetBooks, have no side effects.
+func hasNoSideEffects(opts options.Options, method protoreflect.MethodDescriptor) bool {
+	if value, ok := gnostic.NoSideEffects(method); ok {
+		return value
+	}
+	options, ok := method.Options().(*descriptorpb.MethodOptions)
+	if ok && options != nil && options.IdempotencyLevel != nil {
+		return options.GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS
+	}
+	return opts.InferGETFromNames && isReadMethodName(string(method.Name()))
+}
+
+// readMethodPrefixes are the verbs of methods that only read, following the standard methods of
+// https://google.aip.dev/131, https://google.aip.dev/132 and https://google.aip.dev/231.
+var readMethodPrefixes = []string{"BatchGet", "Get", "List"}
+
+func isReadMethodName(name string) bool {
+	for _, prefix := range readMethodPrefixes {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		// The verb has to be a whole word, so GetawayPlans isn't a read
+		if rest == "" || unicode.IsUpper(rune(rest[0])) || unicode.IsDigit(rune(rest[0])) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// postProcess pipes a generated document through the post-process-cmd and returns what the command writes to stdout.
+// The command runs in a shell, so it can be a script or a pipeline like `jq '.info.title = "Pets"'`. The path and
+// format of the document are passed in the OPENAPI_PATH and OPENAPI_FORMAT environment variables.
+func postProcess(opts options.Options, path, content string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", opts.PostProcessCmd)
+	} else {
+		cmd = exec.Command("sh", "-c", opts.PostProcessCmd)
+	}
+	cmd.Env = append(os.Environ(), "OPENAPI_PATH="+path, "OPENAPI_FORMAT="+opts.Format)
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("post-process-cmd failed for %s: %w: %s", path, err, msg)
+		}
+		return "", fmt.Errorf("post-process-cmd failed for %s: %w", path, err)
+	}
+	if stdout.Len() == 0 {
+		return "", fmt.Errorf("post-process-cmd wrote nothing for %s", path)
+	}
+	return stdout.String(), nil
+}
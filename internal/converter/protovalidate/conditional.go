@@ -0,0 +1,187 @@
+package protovalidate
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+const (
+	hasField = `has\(this\.([A-Za-z_][A-Za-z0-9_]*)\)`
+	hasAll   = `\(?(` + hasField + `(?:&&` + hasField + `)*)\)?`
+	literal  = `('[^']*'|"[^"]*"|-?[0-9]+|true|false)`
+)
+
+var (
+	// !has(this.a) || has(this.b) && has(this.c)
+	impliesPattern = regexp.MustCompile(`^!` + hasField + `\|\|` + hasAll + `$`)
+	// has(this.a) ? has(this.b) : true
+	ternaryPattern = regexp.MustCompile(`^` + hasField + `\?` + hasAll + `:true$`)
+	// has(this.a) == has(this.b)
+	togetherPattern = regexp.MustCompile(`^` + hasField + `==` + hasField + `$`)
+	// this.a == 'x' ? has(this.b) : true
+	valueTernaryPattern = regexp.MustCompile(`^this\.([A-Za-z_][A-Za-z0-9_]*)==` + literal + `\?` + hasAll + `:true$`)
+	// this.a != 'x' || has(this.b)
+	valueImpliesPattern = regexp.MustCompile(`^this\.([A-Za-z_][A-Za-z0-9_]*)!=` + literal + `\|\|` + hasAll + `$`)
+
+	hasFieldPattern = regexp.MustCompile(hasField)
+)
+
+// applyConditionalRequired turns message CEL rules that make fields required when another field is set, or has a
+// given value, into JSON Schema. Presence dependencies become dependentSchemas and value conditions become if/then
+// schemas in allOf. The rules that don't match one of these simple patterns are returned so they can be described in
+// text instead.
+func applyConditionalRequired(opts options.Options, schema *base.Schema, desc protoreflect.MessageDescriptor, rules []*validate.Rule) []*validate.Rule {
+	remaining := []*validate.Rule{}
+	for _, rule := range rules {
+		if !applyConditionalRule(opts, schema, desc, stripSpaces(rule.GetExpression())) {
+			remaining = append(remaining, rule)
+		}
+	}
+	return remaining
+}
+
+func applyConditionalRule(opts options.Options, schema *base.Schema, desc protoreflect.MessageDescriptor, expr string) bool {
+	if m := impliesPattern.FindStringSubmatch(expr); m != nil {
+		return addDependency(opts, schema, desc, m[1], requiredNames(m[2]))
+	}
+	if m := ternaryPattern.FindStringSubmatch(expr); m != nil {
+		return addDependency(opts, schema, desc, m[1], requiredNames(m[2]))
+	}
+	if m := togetherPattern.FindStringSubmatch(expr); m != nil {
+		if fieldByName(desc, m[1]) == nil || fieldByName(desc, m[2]) == nil {
+			return false
+		}
+		addDependency(opts, schema, desc, m[1], []string{m[2]})
+		addDependency(opts, schema, desc, m[2], []string{m[1]})
+		return true
+	}
+	if m := valueTernaryPattern.FindStringSubmatch(expr); m != nil {
+		return addCondition(opts, schema, desc, m[1], m[2], requiredNames(m[3]))
+	}
+	if m := valueImpliesPattern.FindStringSubmatch(expr); m != nil {
+		return addCondition(opts, schema, desc, m[1], m[2], requiredNames(m[3]))
+	}
+	return false
+}
+
+// addDependency requires the fields in required when the field named name is set.
+func addDependency(opts options.Options, schema *base.Schema, desc protoreflect.MessageDescriptor, name string, required []string) bool {
+	field := fieldByName(desc, name)
+	requiredProps, ok := propertyNames(opts, desc, required)
+	if field == nil || !ok {
+		return false
+	}
+	if schema.DependentSchemas == nil {
+		schema.DependentSchemas = orderedmap.New[string, *base.SchemaProxy]()
+	}
+	prop := util.MakeFieldName(opts, field)
+	if existing, ok := schema.DependentSchemas.Get(prop); ok && existing.Schema() != nil {
+		for _, name := range requiredProps {
+			existing.Schema().Required = util.AppendStringDedupe(existing.Schema().Required, name)
+		}
+		return true
+	}
+	schema.DependentSchemas.Set(prop, base.CreateSchemaProxy(&base.Schema{Required: requiredProps}))
+	return true
+}
+
+// addCondition requires the fields in required when the field named name has the given value.
+func addCondition(opts options.Options, schema *base.Schema, desc protoreflect.MessageDescriptor, name, value string, required []string) bool {
+	field := fieldByName(desc, name)
+	requiredProps, ok := propertyNames(opts, desc, required)
+	if field == nil || !ok {
+		return false
+	}
+	node := literalNode(field, value)
+	if node == nil {
+		return false
+	}
+	prop := util.MakeFieldName(opts, field)
+	properties := orderedmap.New[string, *base.SchemaProxy]()
+	properties.Set(prop, base.CreateSchemaProxy(&base.Schema{Const: node}))
+	schema.AllOf = append(schema.AllOf, base.CreateSchemaProxy(&base.Schema{
+		If: base.CreateSchemaProxy(&base.Schema{
+			Properties: properties,
+			Required:   []string{prop},
+		}),
+		Then: base.CreateSchemaProxy(&base.Schema{Required: requiredProps}),
+	}))
+	return true
+}
+
+// literalNode returns the JSON value of a CEL literal compared to a field. Enum numbers become the name of the
+// value, since that's how enums are written in JSON.
+func literalNode(field protoreflect.FieldDescriptor, value string) *yaml.Node {
+	switch {
+	case strings.HasPrefix(value, "'") || strings.HasPrefix(value, `"`):
+		return util.StringNode(value[1 : len(value)-1])
+	case value == "true" || value == "false":
+		return util.BoolNode(value == "true")
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	if field.Kind() == protoreflect.EnumKind {
+		enumValue := field.Enum().Values().ByNumber(protoreflect.EnumNumber(n))
+		if enumValue == nil {
+			return nil
+		}
+		return util.StringNode(string(enumValue.Name()))
+	}
+	return util.IntNode(n)
+}
+
+func requiredNames(expr string) []string {
+	names := []string{}
+	for _, m := range hasFieldPattern.FindAllStringSubmatch(expr, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func propertyNames(opts options.Options, desc protoreflect.MessageDescriptor, names []string) ([]string, bool) {
+	props := make([]string, 0, len(names))
+	for _, name := range names {
+		field := fieldByName(desc, name)
+		if field == nil {
+			return nil, false
+		}
+		props = append(props, util.MakeFieldName(opts, field))
+	}
+	return props, len(props) > 0
+}
+
+func fieldByName(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	return desc.Fields().ByName(protoreflect.Name(name))
+}
+
+// stripSpaces removes the whitespace of an expression outside of string literals.
+func stripSpaces(expr string) string {
+	b := strings.Builder{}
+	var quote rune
+	for _, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -8,8 +8,9 @@ import (
 	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	"buf.build/go/protovalidate/resolve"
 	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
 	"github.com/pb33f/libopenapi/utils"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/yaml.v3"
@@ -24,7 +25,8 @@ func SchemaWithMessageAnnotations(opts options.Options, schema *base.Schema, des
 	if constraints == nil || constraints.GetDisabled() {
 		return schema
 	}
-	updateWithCEL(schema, constraints.GetCel())
+	describeCEL(schema, applyConditionalRequired(opts, schema, desc, constraints.GetCel()))
+	setCELExpressions(schema, constraints.GetCel())
 	return schema
 }
 
@@ -122,7 +124,44 @@ func updateSchemaWithFieldRules(schema *base.Schema, constraints *validate.Field
 	}
 }
 
+// CELExpressionsExtension is the schema extension with the CEL rules of a message or field.
+const CELExpressionsExtension = "x-cel-expressions"
+
+// updateWithCEL documents CEL rules in the description of a schema and lists them in the x-cel-expressions
+// extension.
 func updateWithCEL(schema *base.Schema, constraints []*validate.Rule) {
+	describeCEL(schema, constraints)
+	setCELExpressions(schema, constraints)
+}
+
+// setCELExpressions lists CEL rules with their id, message and expression, so tools can read them without parsing
+// the description:
+//
+//	x-cel-expressions:
+//	  - id: title_not_blank
+//	    message: title must not be blank
+//	    expression: this.title.trim() != ''
+func setCELExpressions(schema *base.Schema, constraints []*validate.Rule) {
+	if len(constraints) == 0 {
+		return
+	}
+	type rule struct {
+		ID         *string `yaml:"id,omitempty"`
+		Message    *string `yaml:"message,omitempty"`
+		Expression *string `yaml:"expression,omitempty"`
+	}
+	rules := make([]rule, len(constraints))
+	for i, cel := range constraints {
+		rules[i] = rule{ID: cel.Id, Message: cel.Message, Expression: cel.Expression}
+	}
+	node, err := util.ExtensionNode(rules)
+	if err != nil {
+		return
+	}
+	schema.Extensions = util.SetExtension(schema.Extensions, CELExpressionsExtension, node)
+}
+
+func describeCEL(schema *base.Schema, constraints []*validate.Rule) {
 	if len(constraints) == 0 {
 		return
 	}
@@ -147,7 +186,7 @@ func updateWithCEL(schema *base.Schema, constraints []*validate.Rule) {
 
 func updateSchemaFloat(schema *base.Schema, constraint *validate.FloatRules) {
 	if constraint.Const != nil {
-		schema.Const = utils.CreateStringNode(strconv.FormatFloat(float64(*constraint.Const), 'f', -1, 32))
+		schema.Const = util.Float32Node(*constraint.Const)
 		switch tt := constraint.LessThan.(type) {
 		case *validate.FloatRules_Lt:
 			v := float64(tt.Lt)
@@ -167,7 +206,7 @@ func updateSchemaFloat(schema *base.Schema, constraint *validate.FloatRules) {
 		if len(constraint.In) > 0 {
 			items := make([]*yaml.Node, len(constraint.In))
 			for i, item := range constraint.In {
-				items[i] = utils.CreateStringNode(strconv.FormatFloat(float64(item), 'f', -1, 32))
+				items[i] = util.Float32Node(item)
 			}
 			schema.Enum = items
 		}
@@ -175,18 +214,18 @@ func updateSchemaFloat(schema *base.Schema, constraint *validate.FloatRules) {
 	if len(constraint.NotIn) > 0 {
 		items := make([]*yaml.Node, len(constraint.NotIn))
 		for i, item := range constraint.NotIn {
-			items[i] = utils.CreateStringNode(strconv.FormatFloat(float64(item), 'f', -1, 32))
+			items[i] = util.Float32Node(item)
 		}
 		schema.Not = base.CreateSchemaProxy(&base.Schema{Type: schema.Type, Enum: items})
 	}
 	for _, item := range constraint.Example {
-		schema.Examples = append(schema.Examples, utils.CreateStringNode(strconv.FormatFloat(float64(item), 'f', -1, 32)))
+		schema.Examples = append(schema.Examples, util.Float32Node(item))
 	}
 }
 
 func updateSchemaDouble(schema *base.Schema, constraint *validate.DoubleRules) {
 	if constraint.Const != nil {
-		schema.Const = utils.CreateStringNode(strconv.FormatFloat(float64(*constraint.Const), 'f', -1, 64))
+		schema.Const = util.FloatNode(*constraint.Const)
 	}
 	switch tt := constraint.LessThan.(type) {
 	case *validate.DoubleRules_Lt:
@@ -207,19 +246,19 @@ func updateSchemaDouble(schema *base.Schema, constraint *validate.DoubleRules) {
 	if len(constraint.In) > 0 {
 		items := make([]*yaml.Node, len(constraint.In))
 		for i, item := range constraint.In {
-			items[i] = utils.CreateStringNode(strconv.FormatFloat(float64(item), 'f', -1, 64))
+			items[i] = util.FloatNode(item)
 		}
 		schema.Enum = items
 	}
 	if len(constraint.NotIn) > 0 {
 		items := make([]*yaml.Node, len(constraint.NotIn))
 		for i, item := range constraint.NotIn {
-			items[i] = utils.CreateStringNode(strconv.FormatFloat(float64(item), 'f', -1, 64))
+			items[i] = util.FloatNode(item)
 		}
 		schema.Not = base.CreateSchemaProxy(&base.Schema{Type: schema.Type, Enum: items})
 	}
 	for _, item := range constraint.Example {
-		schema.Examples = append(schema.Examples, utils.CreateStringNode(strconv.FormatFloat(float64(item), 'f', -1, 64)))
+		schema.Examples = append(schema.Examples, util.FloatNode(item))
 	}
 }
 
@@ -303,7 +342,7 @@ func updateSchemaInt64(schema *base.Schema, constraint *validate.Int64Rules) {
 
 func updateSchemaUint32(schema *base.Schema, constraint *validate.UInt32Rules) {
 	if constraint.Const != nil {
-		schema.Const = utils.CreateStringNode(strconv.FormatUint(uint64(*constraint.Const), 10))
+		schema.Const = utils.CreateIntNode(strconv.FormatUint(uint64(*constraint.Const), 10))
 	}
 	switch tt := constraint.LessThan.(type) {
 	case *validate.UInt32Rules_Lt:
@@ -324,19 +363,19 @@ func updateSchemaUint32(schema *base.Schema, constraint *validate.UInt32Rules) {
 	if len(constraint.In) > 0 {
 		items := make([]*yaml.Node, len(constraint.In))
 		for i, item := range constraint.In {
-			items[i] = utils.CreateStringNode(strconv.FormatUint(uint64(item), 10))
+			items[i] = utils.CreateIntNode(strconv.FormatUint(uint64(item), 10))
 		}
 		schema.Enum = items
 	}
 	if len(constraint.NotIn) > 0 {
 		items := make([]*yaml.Node, len(constraint.NotIn))
 		for i, item := range constraint.NotIn {
-			items[i] = utils.CreateStringNode(strconv.FormatUint(uint64(item), 10))
+			items[i] = utils.CreateIntNode(strconv.FormatUint(uint64(item), 10))
 		}
 		schema.Not = base.CreateSchemaProxy(&base.Schema{Type: schema.Type, Enum: items})
 	}
 	for _, item := range constraint.Example {
-		schema.Examples = append(schema.Examples, utils.CreateStringNode(strconv.FormatUint(uint64(item), 10)))
+		schema.Examples = append(schema.Examples, utils.CreateIntNode(strconv.FormatUint(uint64(item), 10)))
 	}
 }
 
@@ -459,7 +498,7 @@ func updateSchemaSint64(schema *base.Schema, constraint *validate.SInt64Rules) {
 
 func updateSchemaFixed32(schema *base.Schema, constraint *validate.Fixed32Rules) {
 	if constraint.Const != nil {
-		schema.Const = utils.CreateStringNode(strconv.FormatUint(uint64(*constraint.Const), 10))
+		schema.Const = utils.CreateIntNode(strconv.FormatUint(uint64(*constraint.Const), 10))
 	}
 	switch tt := constraint.LessThan.(type) {
 	case *validate.Fixed32Rules_Lt:
@@ -480,19 +519,19 @@ func updateSchemaFixed32(schema *base.Schema, constraint *validate.Fixed32Rules)
 	if len(constraint.In) > 0 {
 		items := make([]*yaml.Node, len(constraint.In))
 		for i, item := range constraint.In {
-			items[i] = utils.CreateStringNode(strconv.FormatUint(uint64(item), 10))
+			items[i] = utils.CreateIntNode(strconv.FormatUint(uint64(item), 10))
 		}
 		schema.Enum = items
 	}
 	if len(constraint.NotIn) > 0 {
 		items := make([]*yaml.Node, len(constraint.NotIn))
 		for i, item := range constraint.NotIn {
-			items[i] = utils.CreateStringNode(strconv.FormatUint(uint64(item), 10))
+			items[i] = utils.CreateIntNode(strconv.FormatUint(uint64(item), 10))
 		}
 		schema.Not = base.CreateSchemaProxy(&base.Schema{Type: schema.Type, Enum: items})
 	}
 	for _, item := range constraint.Example {
-		schema.Examples = append(schema.Examples, utils.CreateStringNode(strconv.FormatUint(uint64(item), 10)))
+		schema.Examples = append(schema.Examples, utils.CreateIntNode(strconv.FormatUint(uint64(item), 10)))
 	}
 }
 
@@ -692,7 +731,7 @@ func updateSchemaString(schema *base.Schema, constraint *validate.StringRules) {
 		}
 	case *validate.StringRules_UriRef:
 		if v.UriRef {
-			schema.Format = "uri-ref"
+			schema.Format = "uri-reference"
 		}
 	case *validate.StringRules_Address:
 		if v.Address {
@@ -702,6 +741,11 @@ func updateSchemaString(schema *base.Schema, constraint *validate.StringRules) {
 		if v.Uuid {
 			schema.Format = "uuid"
 		}
+	case *validate.StringRules_Tuuid:
+		// A trimmed UUID has no dashes, so it doesn't match the "uuid" format
+		if v.Tuuid {
+			schema.Pattern = "^[0-9a-fA-F]{32}$"
+		}
 	case *validate.StringRules_IpWithPrefixlen:
 	case *validate.StringRules_Ipv4WithPrefixlen:
 	case *validate.StringRules_Ipv6WithPrefixlen:
@@ -796,13 +840,11 @@ func updateSchemaEnum(schema *base.Schema, constraint *validate.EnumRules) {
 	}
 }
 
+// updateSchemaRepeated applies the rules of a repeated field to its array schema. `unique = false` is the default of
+// JSON Schema too, so only `uniqueItems: true` is written.
 func updateSchemaRepeated(schema *base.Schema, constraint *validate.RepeatedRules) {
-	if constraint.Unique != nil {
-		schema.UniqueItems = constraint.Unique
-	}
-	if constraint.MaxItems != nil {
-		v := int64(*constraint.MaxItems)
-		schema.MaxItems = &v
+	if constraint.GetUnique() {
+		schema.UniqueItems = util.BoolPtr(true)
 	}
 	if constraint.MinItems != nil {
 		v := int64(*constraint.MinItems)
@@ -812,11 +854,32 @@ func updateSchemaRepeated(schema *base.Schema, constraint *validate.RepeatedRule
 		v := int64(*constraint.MaxItems)
 		schema.MaxItems = &v
 	}
-	if constraint.Items != nil && schema.Items != nil && schema.Items.A != nil && !schema.Items.A.IsReference() {
-		updateSchemaWithFieldRules(schema.Items.A.Schema(), constraint.Items, false)
+	if constraint.Items != nil && schema.Items != nil && schema.Items.A != nil {
+		schema.Items.A = schemaWithItemRules(schema.Items.A, constraint.Items)
 	}
 }
 
+// schemaWithItemRules applies the rules for the items of a repeated field or the values of a map. References to
+// messages and enums can't be changed, so the rules are placed next to the $ref instead.
+func schemaWithItemRules(proxy *base.SchemaProxy, constraints *validate.FieldRules) *base.SchemaProxy {
+	if !proxy.IsReference() {
+		if s := proxy.Schema(); s != nil {
+			updateWithCEL(s, constraints.GetCel())
+			updateSchemaWithFieldRules(s, constraints, false)
+		}
+		return proxy
+	}
+	if constraints.GetType() == nil && len(constraints.GetCel()) == 0 {
+		return proxy
+	}
+	extensions := orderedmap.New[string, *yaml.Node]()
+	extensions.Set("$ref", utils.CreateStringNode(proxy.GetReference()))
+	s := &base.Schema{Extensions: extensions}
+	updateWithCEL(s, constraints.GetCel())
+	updateSchemaWithFieldRules(s, constraints, false)
+	return base.CreateSchemaProxy(s)
+}
+
 func updateSchemaMap(schema *base.Schema, constraint *validate.MapRules) {
 	if constraint.MinPairs != nil {
 		v := int64(*constraint.MinPairs)
@@ -826,13 +889,38 @@ func updateSchemaMap(schema *base.Schema, constraint *validate.MapRules) {
 		v := int64(*constraint.MaxPairs)
 		schema.MaxProperties = &v
 	}
-	// NOTE: Most of these properties don't make sense for object keys
-	// updateSchemaWithFieldRules(schema, constraint.Keys)
-	if schema.AdditionalProperties != nil && constraint.Values != nil {
-		updateSchemaWithFieldRules(schema.AdditionalProperties.A.Schema(), constraint.Values, false)
+	if constraint.Keys != nil {
+		if keys := mapKeySchema(constraint.Keys); keys != nil {
+			schema.PropertyNames = base.CreateSchemaProxy(keys)
+		}
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.A != nil && constraint.Values != nil {
+		schema.AdditionalProperties.A = schemaWithItemRules(schema.AdditionalProperties.A, constraint.Values)
 	}
 }
 
+// mapKeySchema returns the schema for the keys of a map, which are always strings in JSON. String rules carry over
+// as-is. Rules for other key types can't be expressed on a string, so only the shape of the key is described.
+func mapKeySchema(constraints *validate.FieldRules) *base.Schema {
+	schema := &base.Schema{Type: []string{"string"}}
+	switch constraints.Type.(type) {
+	case *validate.FieldRules_String_:
+		updateSchemaWithFieldRules(schema, constraints, true)
+	case *validate.FieldRules_Int32, *validate.FieldRules_Int64, *validate.FieldRules_Sint32,
+		*validate.FieldRules_Sint64, *validate.FieldRules_Sfixed32, *validate.FieldRules_Sfixed64:
+		schema.Pattern = "^-?[0-9]+$"
+	case *validate.FieldRules_Uint32, *validate.FieldRules_Uint64, *validate.FieldRules_Fixed32,
+		*validate.FieldRules_Fixed64:
+		schema.Pattern = "^[0-9]+$"
+	case *validate.FieldRules_Bool:
+		schema.Enum = []*yaml.Node{utils.CreateStringNode("true"), utils.CreateStringNode("false")}
+	default:
+		return nil
+	}
+	updateWithCEL(schema, constraints.GetCel())
+	return schema
+}
+
 func updateSchemaAny(schema *base.Schema, constraint *validate.AnyRules) {
 	if len(constraint.In) > 0 {
 		items := make([]*yaml.Node, len(constraint.In))
@@ -0,0 +1,195 @@
+package protovalidate
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"buf.build/go/protovalidate/resolve"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// ValidationExtension is the operation extension with the protovalidate rules of the request message.
+const ValidationExtension = "x-connect-validation"
+
+// ValidationSummary returns the protovalidate rules that apply to a request message, or nil if there aren't any.
+// The rules are compiled into a form that a middleware can enforce with only the spec:
+//
+//	message: example.v1.CreateBookRequest
+//	messages:
+//	  example.v1.CreateBookRequest:
+//	    fields:
+//	      book: {required: true, message: example.v1.Book}
+//	  example.v1.Book:
+//	    cel: [{id: ..., message: ..., expression: ...}]
+//	    fields:
+//	      title: {string: {minLen: "1"}}
+//
+// Rules are in the protojson form of buf.validate.MessageRules, OneofRules and FieldRules, so they can be parsed
+// back into those messages. Fields that hold a message with rules point to it with "message".
+func ValidationSummary(opts options.Options, desc protoreflect.MessageDescriptor) *yaml.Node {
+	// Collect every message that can be reached from the request and the rules set directly on each of them
+	reachable := []protoreflect.MessageDescriptor{}
+	summaries := map[protoreflect.FullName]map[string]any{}
+	children := map[protoreflect.FullName][]protoreflect.FullName{}
+	var collect func(msg protoreflect.MessageDescriptor)
+	collect = func(msg protoreflect.MessageDescriptor) {
+		if _, ok := summaries[msg.FullName()]; ok || util.IsWellKnown(msg) {
+			return
+		}
+		summaries[msg.FullName()] = directRules(opts, msg)
+		reachable = append(reachable, msg)
+		for i := 0; i < msg.Fields().Len(); i++ {
+			if child := fieldMessage(msg.Fields().Get(i)); child != nil {
+				children[msg.FullName()] = append(children[msg.FullName()], child.FullName())
+				collect(child)
+			}
+		}
+	}
+	collect(desc)
+
+	// A message needs validation if it has rules or contains a message that needs validation
+	validated := map[protoreflect.FullName]bool{}
+	for name, summary := range summaries {
+		validated[name] = len(summary) > 0
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, msg := range reachable {
+			if validated[msg.FullName()] {
+				continue
+			}
+			for _, child := range children[msg.FullName()] {
+				if validated[child] {
+					validated[msg.FullName()] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	if !validated[desc.FullName()] {
+		return nil
+	}
+
+	messages := map[string]any{}
+	for _, msg := range reachable {
+		if !validated[msg.FullName()] {
+			continue
+		}
+		summary := summaries[msg.FullName()]
+		fields, _ := summary["fields"].(map[string]any)
+		for i := 0; i < msg.Fields().Len(); i++ {
+			field := msg.Fields().Get(i)
+			child := fieldMessage(field)
+			if child == nil || !validated[child.FullName()] {
+				continue
+			}
+			if fields == nil {
+				fields = map[string]any{}
+				summary["fields"] = fields
+			}
+			name := util.MakeFieldName(opts, field)
+			fieldSummary, _ := fields[name].(map[string]any)
+			if fieldSummary == nil {
+				fieldSummary = map[string]any{}
+				fields[name] = fieldSummary
+			}
+			fieldSummary["message"] = string(child.FullName())
+		}
+		messages[string(msg.FullName())] = summary
+	}
+
+	node, err := util.ExtensionNode(map[string]any{
+		"message":  string(desc.FullName()),
+		"messages": messages,
+	})
+	if err != nil {
+		slog.Warn("unable to encode validation summary", slog.Any("error", err))
+		return nil
+	}
+	return node
+}
+
+// fieldMessage returns the message held by a field, including the values of map fields.
+func fieldMessage(field protoreflect.FieldDescriptor) protoreflect.MessageDescriptor {
+	if field.IsMap() {
+		return field.MapValue().Message()
+	}
+	return field.Message()
+}
+
+// directRules returns the rules set on a message, its oneofs and its fields.
+func directRules(opts options.Options, desc protoreflect.MessageDescriptor) map[string]any {
+	summary := map[string]any{}
+	if rules, err := resolve.MessageRules(desc); err != nil {
+		slog.Warn("unable to resolve message rules", slog.Any("error", err))
+	} else if rules != nil {
+		if rules.GetDisabled() {
+			return summary
+		}
+		mergeRules(summary, rules)
+	}
+
+	oneofs := map[string]any{}
+	for i := 0; i < desc.Oneofs().Len(); i++ {
+		oneof := desc.Oneofs().Get(i)
+		rules, err := resolve.OneofRules(oneof)
+		if err != nil {
+			slog.Warn("unable to resolve oneof rules", slog.Any("error", err))
+			continue
+		}
+		if v := rulesToValue(rules); len(v) > 0 {
+			oneofs[string(oneof.Name())] = v
+		}
+	}
+	if len(oneofs) > 0 {
+		summary["oneofs"] = oneofs
+	}
+
+	fields := map[string]any{}
+	for i := 0; i < desc.Fields().Len(); i++ {
+		field := desc.Fields().Get(i)
+		rules, err := resolve.FieldRules(field)
+		if err != nil {
+			slog.Warn("unable to resolve field rules", slog.Any("error", err))
+			continue
+		}
+		if v := rulesToValue(rules); len(v) > 0 {
+			fields[util.MakeFieldName(opts, field)] = v
+		}
+	}
+	if len(fields) > 0 {
+		summary["fields"] = fields
+	}
+	return summary
+}
+
+func mergeRules(dst map[string]any, rules proto.Message) {
+	for k, v := range rulesToValue(rules) {
+		dst[k] = v
+	}
+}
+
+// rulesToValue converts rules to their protojson form as a generic value.
+func rulesToValue(rules proto.Message) map[string]any {
+	if rules == nil || !rules.ProtoReflect().IsValid() {
+		return nil
+	}
+	b, err := protojson.Marshal(rules)
+	if err != nil {
+		slog.Warn("unable to marshal rules", slog.Any("error", err))
+		return nil
+	}
+	res := map[string]any{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		slog.Warn("unable to unmarshal rules", slog.Any("error", err))
+		return nil
+	}
+	return res
+}
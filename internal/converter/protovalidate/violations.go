@@ -0,0 +1,81 @@
+package protovalidate
+
+import (
+	"log/slog"
+
+	"buf.build/go/protovalidate/resolve"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// ViolationFieldPaths returns the paths of the fields and oneofs of a message that have protovalidate rules, like
+// "parent.name", in the form protovalidate reports them in violations: proto names joined with dots. Fields of
+// nested messages are followed through singular message fields. exact is false when repeated or map fields have
+// rules, since violations of their elements also have the index or key of the element in the path.
+func ViolationFieldPaths(desc protoreflect.MessageDescriptor) (paths []string, exact bool) {
+	paths = []string{}
+	exact = true
+	var collect func(msg protoreflect.MessageDescriptor, prefix string, visiting map[protoreflect.FullName]bool)
+	collect = func(msg protoreflect.MessageDescriptor, prefix string, visiting map[protoreflect.FullName]bool) {
+		if visiting[msg.FullName()] || util.IsWellKnown(msg) || isDisabled(msg) {
+			return
+		}
+		visiting[msg.FullName()] = true
+		defer delete(visiting, msg.FullName())
+
+		for i := 0; i < msg.Oneofs().Len(); i++ {
+			oneof := msg.Oneofs().Get(i)
+			rules, err := resolve.OneofRules(oneof)
+			if err != nil {
+				slog.Warn("unable to resolve oneof rules", slog.Any("error", err))
+				continue
+			}
+			if len(rulesToValue(rules)) > 0 {
+				paths = append(paths, prefix+string(oneof.Name()))
+			}
+		}
+		for i := 0; i < msg.Fields().Len(); i++ {
+			field := msg.Fields().Get(i)
+			path := prefix + string(field.Name())
+			rules, err := resolve.FieldRules(field)
+			if err != nil {
+				slog.Warn("unable to resolve field rules", slog.Any("error", err))
+				continue
+			}
+			if len(rulesToValue(rules)) > 0 {
+				paths = util.AppendStringDedupe(paths, path)
+				if field.IsList() || field.IsMap() {
+					// Rules for the items, keys or values are reported with the index or key of the element
+					exact = false
+				}
+			}
+			child := fieldMessage(field)
+			if child == nil {
+				continue
+			}
+			if field.IsList() || field.IsMap() {
+				before := len(paths)
+				collect(child, path+".", visiting)
+				if len(paths) > before {
+					// Elements are reported as path[index] or path[key], so list the field itself instead
+					paths = util.AppendStringDedupe(paths[:before], path)
+					exact = false
+				}
+				continue
+			}
+			collect(child, path+".", visiting)
+		}
+	}
+	collect(desc, "", map[protoreflect.FullName]bool{})
+	return paths, exact
+}
+
+func isDisabled(desc protoreflect.MessageDescriptor) bool {
+	rules, err := resolve.MessageRules(desc)
+	if err != nil {
+		slog.Warn("unable to resolve message rules", slog.Any("error", err))
+		return false
+	}
+	return rules != nil && rules.GetDisabled()
+}
@@ -0,0 +1,22 @@
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// convertFile runs fn, which converts the file with the given name, and turns a panic into an error that names the
+// file instead of taking down the whole generation with a bare stack trace. Errors are prefixed with the name too.
+func convertFile(name string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Debug("panic while converting file", slog.String("name", name), slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("%s: panic: %v (the debug option logs the stack trace)", name, r)
+		}
+	}()
+	if err := fn(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
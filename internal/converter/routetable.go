@@ -0,0 +1,125 @@
+package converter
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	pluginpb "google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/googleapi"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// RouteTable lists the routes of every method, for runtimes like vanguard-go or a custom transcoder that are
+// configured from the same protos as the documentation.
+type RouteTable struct {
+	Routes []Route `json:"routes"`
+}
+
+// Route is a method in the RouteTable with its Connect procedure and its google.api.http rules.
+type Route struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	// Procedure is the path of the method in the Connect and gRPC protocols, like /acme.v1.BookService/ListBooks.
+	Procedure string `json:"procedure"`
+	// ConnectPath is the path of the Connect operation in the documentation, after options like path-prefix and
+	// path-case.
+	ConnectPath string `json:"connectPath"`
+	// StreamType is unary, client, server or bidi.
+	StreamType string `json:"streamType"`
+	// Idempotency is the idempotency_level of the method, like NO_SIDE_EFFECTS.
+	Idempotency  string `json:"idempotency"`
+	RequestType  string `json:"requestType"`
+	ResponseType string `json:"responseType"`
+	// HTTPRules are the google.api.http rule of the method and its additional bindings.
+	HTTPRules []RouteHTTPRule `json:"httpRules"`
+}
+
+// RouteHTTPRule is a google.api.http rule of a Route.
+type RouteHTTPRule struct {
+	Verb string `json:"verb"`
+	// Pattern is the path template like it's written in the rule, like /v1/{name=books/*}.
+	Pattern string `json:"pattern"`
+	// Path is the path of the operation in the documentation, like /v1/{name}.
+	Path         string `json:"path"`
+	Body         string `json:"body,omitempty"`
+	ResponseBody string `json:"responseBody,omitempty"`
+}
+
+// routeTableRoutes lists the routes of every method of the documented services in a file.
+func routeTableRoutes(opts options.Options, fd protoreflect.FileDescriptor) []Route {
+	routes := []Route{}
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		if !opts.HasService(services.Get(i).FullName()) {
+			continue
+		}
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			routes = append(routes, methodRoute(opts, methods.Get(j)))
+		}
+	}
+	return routes
+}
+
+func methodRoute(opts options.Options, method protoreflect.MethodDescriptor) Route {
+	procedure := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+	route := Route{
+		Service:      string(method.Parent().FullName()),
+		Method:       string(method.Name()),
+		Procedure:    procedure,
+		ConnectPath:  util.MakePath(opts, procedure),
+		StreamType:   streamType(method),
+		Idempotency:  descriptorpb.MethodOptions_IDEMPOTENCY_UNKNOWN.String(),
+		RequestType:  string(method.Input().FullName()),
+		ResponseType: string(method.Output().FullName()),
+		HTTPRules:    []RouteHTTPRule{},
+	}
+	if options, ok := method.Options().(*descriptorpb.MethodOptions); ok && options != nil {
+		route.Idempotency = options.GetIdempotencyLevel().String()
+	}
+	for _, rule := range googleapi.HTTPRules(method) {
+		verb, pattern := googleapi.RulePattern(rule)
+		if verb == "" || pattern == "" {
+			continue
+		}
+		path, err := googleapi.OpenAPIPath(opts, pattern)
+		if err != nil {
+			slog.Warn("unable to parse template pattern", slog.Any("error", err), slog.String("template", pattern))
+			continue
+		}
+		route.HTTPRules = append(route.HTTPRules, RouteHTTPRule{
+			Verb:         verb,
+			Pattern:      pattern,
+			Path:         path,
+			Body:         rule.GetBody(),
+			ResponseBody: rule.GetResponseBody(),
+		})
+	}
+	return route
+}
+
+func streamType(method protoreflect.MethodDescriptor) string {
+	switch {
+	case method.IsStreamingClient() && method.IsStreamingServer():
+		return "bidi"
+	case method.IsStreamingClient():
+		return "client"
+	case method.IsStreamingServer():
+		return "server"
+	}
+	return "unary"
+}
+
+// routeTableFile writes the route table as JSON.
+func routeTableFile(name string, routes []Route) (*pluginpb.CodeGeneratorResponse_File, error) {
+	b, err := json.MarshalIndent(RouteTable{Routes: routes}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	content := string(b) + "\n"
+	return newFile(name, content), nil
+}
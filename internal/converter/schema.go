@@ -1,17 +1,14 @@
 package converter
 
 import (
-	"log/slog"
 	"sort"
-	"strconv"
 
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	"github.com/pb33f/libopenapi/orderedmap"
-	"github.com/pb33f/libopenapi/utils"
 	"google.golang.org/protobuf/reflect/protoreflect"
-	"gopkg.in/yaml.v3"
 
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/schema"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 )
@@ -21,6 +18,9 @@ type State struct {
 	CurrentFile protoreflect.FileDescriptor
 	Messages    map[protoreflect.MessageDescriptor]struct{}
 	Enums       map[protoreflect.EnumDescriptor]struct{}
+	// Built are the messages and enums whose schemas were already added to the document. When the schemas of many
+	// files are merged into one document, the messages they share are only built once.
+	Built map[protoreflect.FullName]struct{}
 }
 
 func NewState(opts options.Options) *State {
@@ -53,11 +53,17 @@ func (st *State) CollectFile(tt protoreflect.FileDescriptor) {
 	services := tt.Services()
 	for i := 0; i < services.Len(); i++ {
 		service := services.Get(i)
+		if !st.Opts.HasService(service.FullName()) {
+			continue
+		}
 		methods := service.Methods()
 		for j := 0; j < methods.Len(); j++ {
 			method := methods.Get(j)
 			st.CollectMessage(method.Input())
 			st.CollectMessage(method.Output())
+			for name := range gnostic.ResponseMediaTypes(method).ValuesFromOldest() {
+				st.CollectMessage(util.FindMessage(tt, name))
+			}
 		}
 	}
 }
@@ -89,6 +95,11 @@ func (st *State) CollectMessage(tt protoreflect.MessageDescriptor) {
 		st.CollectField(fields.Get(i))
 	}
 
+	// Messages can have extensions that are declared elsewhere
+	for _, ext := range st.Opts.Conversion().Extensions(tt.FullName()) {
+		st.CollectField(ext)
+	}
+
 	// Messages can have enums
 	enums := tt.Enums()
 	for i := 0; i < enums.Len(); i++ {
@@ -135,40 +146,34 @@ func (st *State) SortedMessages() []protoreflect.MessageDescriptor {
 	return messages
 }
 
-func enumToSchema(state *State, tt protoreflect.EnumDescriptor) (string, *base.Schema) {
-	slog.Debug("enumToSchema", slog.Any("descriptor", tt.FullName()))
-	children := []*yaml.Node{}
-	values := tt.Values()
-	for i := 0; i < values.Len(); i++ {
-		value := values.Get(i)
-		children = append(children, utils.CreateStringNode(string(value.Name())))
-		if state.Opts.IncludeNumberEnumValues {
-			children = append(children, utils.CreateIntNode(strconv.FormatInt(int64(value.Number()), 10)))
-		}
-	}
-
-	title := string(tt.Name())
-	if state.Opts.FullyQualifiedMessageNames {
-		title = string(tt.FullName())
-	}
-	s := &base.Schema{
-		Title:       title,
-		Description: util.FormatComments(tt.ParentFile().SourceLocations().ByDescriptor(tt)),
-		Type:        []string{"string"},
-		Enum:        children,
-	}
-	return string(tt.FullName()), s
-}
-
 func stateToSchema(st *State) *orderedmap.Map[string, *base.SchemaProxy] {
 	schemas := orderedmap.New[string, *base.SchemaProxy]()
+	isBuilt := func(name protoreflect.FullName) bool {
+		if st.Built == nil {
+			return false
+		}
+		if _, ok := st.Built[name]; ok {
+			return true
+		}
+		st.Built[name] = struct{}{}
+		return false
+	}
 
-	for _, enum := range st.SortedEnums() {
-		id, schema := enumToSchema(st, enum)
-		schemas.Set(id, base.CreateSchemaProxy(schema))
+	// Inlined enums aren't referenced, so they don't need a component
+	if !st.Opts.InlineEnums {
+		for _, enum := range st.SortedEnums() {
+			if isBuilt(enum.FullName()) {
+				continue
+			}
+			id, schema := schema.EnumToSchema(st.Opts, enum)
+			schemas.Set(id, base.CreateSchemaProxy(schema))
+		}
 	}
 
 	for _, message := range st.SortedMessages() {
+		if isBuilt(message.FullName()) {
+			continue
+		}
 		id, schema := schema.MessageToSchema(st.Opts, message)
 		if schema != nil {
 			schemas.Set(id, base.CreateSchemaProxy(schema))
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+)
+
+// describeProperties appends a human-readable summary of the constraints of each property to its description.
+func describeProperties(s *base.Schema) {
+	if s.Properties == nil {
+		return
+	}
+	for pair := s.Properties.First(); pair != nil; pair = pair.Next() {
+		prop := pair.Value().Schema()
+		if prop == nil {
+			continue
+		}
+		summary := describeConstraints(prop, slices.Contains(s.Required, pair.Key()))
+		if summary == "" {
+			continue
+		}
+		if prop.Description == "" {
+			prop.Description = summary
+		} else {
+			prop.Description = prop.Description + "\n\n" + summary
+		}
+	}
+}
+
+// describeConstraints returns a summary of the validation keywords on a schema, like
+// "Required. Max length 64. Must match `^[a-z-]+$`."
+func describeConstraints(s *base.Schema, required bool) string {
+	parts := []string{}
+	if required {
+		parts = append(parts, "Required.")
+	}
+	if s.MinLength != nil && s.MaxLength != nil && *s.MinLength == *s.MaxLength {
+		parts = append(parts, fmt.Sprintf("Length %d.", *s.MinLength))
+	} else {
+		if s.MinLength != nil {
+			parts = append(parts, fmt.Sprintf("Min length %d.", *s.MinLength))
+		}
+		if s.MaxLength != nil {
+			parts = append(parts, fmt.Sprintf("Max length %d.", *s.MaxLength))
+		}
+	}
+	if s.Pattern != "" {
+		parts = append(parts, fmt.Sprintf("Must match `%s`.", s.Pattern))
+	}
+	if s.Minimum != nil {
+		parts = append(parts, fmt.Sprintf("Minimum %s.", formatNumber(*s.Minimum)))
+	}
+	if s.ExclusiveMinimum != nil && s.ExclusiveMinimum.IsB() {
+		parts = append(parts, fmt.Sprintf("Greater than %s.", formatNumber(s.ExclusiveMinimum.B)))
+	}
+	if s.Maximum != nil {
+		parts = append(parts, fmt.Sprintf("Maximum %s.", formatNumber(*s.Maximum)))
+	}
+	if s.ExclusiveMaximum != nil && s.ExclusiveMaximum.IsB() {
+		parts = append(parts, fmt.Sprintf("Less than %s.", formatNumber(s.ExclusiveMaximum.B)))
+	}
+	if s.MinItems != nil {
+		parts = append(parts, fmt.Sprintf("Min items %d.", *s.MinItems))
+	}
+	if s.MaxItems != nil {
+		parts = append(parts, fmt.Sprintf("Max items %d.", *s.MaxItems))
+	}
+	if s.UniqueItems != nil && *s.UniqueItems {
+		parts = append(parts, "Items must be unique.")
+	}
+	if s.MinProperties != nil {
+		parts = append(parts, fmt.Sprintf("Min entries %d.", *s.MinProperties))
+	}
+	if s.MaxProperties != nil {
+		parts = append(parts, fmt.Sprintf("Max entries %d.", *s.MaxProperties))
+	}
+	if s.Const != nil {
+		parts = append(parts, fmt.Sprintf("Must be `%s`.", s.Const.Value))
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, node := range s.Enum {
+			values[i] = "`" + node.Value + "`"
+		}
+		parts = append(parts, fmt.Sprintf("Must be one of %s.", strings.Join(values, ", ")))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
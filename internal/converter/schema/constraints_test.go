@@ -0,0 +1,40 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/utils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDescribeConstraints(t *testing.T) {
+	t.Run("no constraints", func(t *testing.T) {
+		assert.Equal(t, "", describeConstraints(&base.Schema{Type: []string{"string"}}, false))
+	})
+
+	t.Run("string", func(t *testing.T) {
+		maxLen := int64(64)
+		s := &base.Schema{Type: []string{"string"}, MaxLength: &maxLen, Pattern: "^[a-z-]+$"}
+		assert.Equal(t, "Required. Max length 64. Must match `^[a-z-]+$`.", describeConstraints(s, true))
+	})
+
+	t.Run("number", func(t *testing.T) {
+		minimum := 1.5
+		s := &base.Schema{
+			Type:             []string{"number"},
+			Minimum:          &minimum,
+			ExclusiveMaximum: &base.DynamicValue[bool, float64]{N: 1, B: 10},
+		}
+		assert.Equal(t, "Minimum 1.5. Less than 10.", describeConstraints(s, false))
+	})
+
+	t.Run("enum", func(t *testing.T) {
+		s := &base.Schema{
+			Type: []string{"string"},
+			Enum: []*yaml.Node{utils.CreateStringNode("a"), utils.CreateStringNode("b")},
+		}
+		assert.Equal(t, "Must be one of `a`, `b`.", describeConstraints(s, false))
+	})
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	"github.com/pb33f/libopenapi/orderedmap"
 	"github.com/pb33f/libopenapi/utils"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/openapiv2"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/yaml.v3"
@@ -19,16 +22,19 @@ func MessageToSchema(opts options.Options, tt protoreflect.MessageDescriptor) (s
 	slog.Debug("messageToSchema", slog.Any("descriptor", tt.FullName()))
 	defer slog.Debug("/messageToSchema", slog.Any("descriptor", tt.FullName()))
 	if util.IsWellKnown(tt) {
-		wk := util.WellKnownToSchema(tt)
+		wk := util.WellKnownToSchema(opts, tt)
 		if wk == nil {
 			return "", nil
 		}
-		return wk.ID, wk.Schema
+		return wk.ID, transformSchema(opts, wk.Schema, tt)
 	}
 	title := string(tt.Name())
 	if opts.FullyQualifiedMessageNames {
 		title = string(tt.FullName())
 	}
+	if field := gnostic.UnwrappedField(tt); field != nil {
+		return string(tt.FullName()), transformSchema(opts, unwrappedSchema(opts, tt, field, title), tt)
+	}
 	s := &base.Schema{
 		Title:                title,
 		Description:          util.FormatComments(tt.ParentFile().SourceLocations().ByDescriptor(tt)),
@@ -37,37 +43,51 @@ func MessageToSchema(opts options.Options, tt protoreflect.MessageDescriptor) (s
 	}
 
 	oneOneGroups := map[protoreflect.FullName][]protoreflect.FieldDescriptor{}
+	fields := orderedFields(opts, tt)
 	regularProps := orderedmap.New[string, *base.SchemaProxy]()
-
-	fields := tt.Fields()
-	for i := 0; i < fields.Len(); i++ {
-		field := fields.Get(i)
+	// Every property has the same parent, so it's wrapped once instead of for every field
+	parent := base.CreateSchemaProxy(s)
+	groupKeys := []protoreflect.FullName{}
+	for _, field := range fields {
 		if oneOf := field.ContainingOneof(); oneOf != nil && !oneOf.IsSynthetic() {
+			if _, ok := oneOneGroups[oneOf.FullName()]; !ok {
+				groupKeys = append(groupKeys, oneOf.FullName())
+			}
 			oneOneGroups[oneOf.FullName()] = append(oneOneGroups[oneOf.FullName()], field)
 			continue
 		}
-		prop := FieldToSchema(opts, base.CreateSchemaProxy(s), field)
+		prop := FieldToSchema(opts, parent, field)
 		if field.HasOptionalKeyword() {
 			nullable := true
 			prop.Schema().Nullable = &nullable
 		}
 		regularProps.Set(util.MakeFieldName(opts, field), prop)
 	}
+	// Known extensions are written with their full name in brackets, like protojson does
+	for _, ext := range opts.Conversion().Extensions(tt.FullName()) {
+		prop := FieldToSchema(opts, parent, ext)
+		if ext.HasOptionalKeyword() {
+			nullable := true
+			prop.Schema().Nullable = &nullable
+		}
+		regularProps.Set("["+string(ext.FullName())+"]", prop)
+	}
 
 	s.Properties = regularProps
 	if len(oneOneGroups) > 0 {
 		// make all of groups
-		groupKeys := []protoreflect.FullName{}
-		for key := range oneOneGroups {
-			groupKeys = append(groupKeys, key)
+		if opts.PropertyOrder == "" {
+			// Without an explicit property order, oneofs and their fields are sorted by name
+			slices.Sort(groupKeys)
+			for _, items := range oneOneGroups {
+				slices.SortFunc(items, func(a, b protoreflect.FieldDescriptor) int {
+					return strings.Compare(string(a.Name()), string(b.Name()))
+				})
+			}
 		}
-		slices.Sort(groupKeys)
 		allOfs := []*base.SchemaProxy{}
 		for _, key := range groupKeys {
 			items := oneOneGroups[key]
-			slices.SortFunc(items, func(a, b protoreflect.FieldDescriptor) int {
-				return strings.Compare(string(a.Name()), string(b.Name()))
-			})
 			allOfs = append(allOfs, makeOneOfGroup(opts, items))
 		}
 		if len(allOfs) == 1 {
@@ -79,9 +99,134 @@ func MessageToSchema(opts options.Options, tt protoreflect.MessageDescriptor) (s
 
 	// Apply Updates from Options
 	s = opts.MessageAnnotator.AnnotateMessage(opts, s, tt)
+	if opts.WithConstraintDescriptions {
+		describeProperties(s)
+	}
+	return string(tt.FullName()), transformSchema(opts, s, tt)
+}
+
+// unwrappedSchema returns the schema of the only field of a message, with the title and description of the message,
+// for messages annotated with gnostic.UnwrapExtension.
+func unwrappedSchema(opts options.Options, tt protoreflect.MessageDescriptor, field protoreflect.FieldDescriptor, title string) *base.Schema {
+	// The wrapper has no properties, so whatever the field adds to its parent, like required, is dropped
+	s := FieldToSchema(opts, base.CreateSchemaProxy(&base.Schema{}), field).Schema()
+	s.Title = title
+	if description := util.FormatComments(tt.ParentFile().SourceLocations().ByDescriptor(tt)); description != "" {
+		s.Description = description
+	}
+	return opts.MessageAnnotator.AnnotateMessage(opts, s, tt)
+}
+
+// EnumToSchema returns the schema of an enum, which lists the names of its values and, with IncludeNumberEnumValues,
+// their numbers.
+func EnumToSchema(opts options.Options, tt protoreflect.EnumDescriptor) (string, *base.Schema) {
+	slog.Debug("enumToSchema", slog.Any("descriptor", tt.FullName()))
+	children := []*yaml.Node{}
+	values := tt.Values()
+	for i := 0; i < values.Len(); i++ {
+		value := values.Get(i)
+		children = append(children, utils.CreateStringNode(string(value.Name())))
+		if opts.IncludeNumberEnumValues {
+			children = append(children, utils.CreateIntNode(strconv.FormatInt(int64(value.Number()), 10)))
+		}
+	}
+
+	title := string(tt.Name())
+	if opts.FullyQualifiedMessageNames {
+		title = string(tt.FullName())
+	}
+	s := &base.Schema{
+		Title:       title,
+		Description: util.FormatComments(tt.ParentFile().SourceLocations().ByDescriptor(tt)),
+		Type:        []string{"string"},
+		Enum:        children,
+	}
+	if opts.WithEnumExtensions {
+		s.Extensions = enumExtensions(opts, tt)
+	}
+	s = openapiv2.SchemaWithEnumAnnotations(opts, s, tt)
 	return string(tt.FullName()), s
 }
 
+// msEnumValue is a value of the x-ms-enum extension that AutoRest reads.
+type msEnumValue struct {
+	Value       string `yaml:"value"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// msEnum is the x-ms-enum extension that AutoRest reads.
+type msEnum struct {
+	Name          string        `yaml:"name"`
+	ModelAsString bool          `yaml:"modelAsString"`
+	Values        []msEnumValue `yaml:"values"`
+}
+
+// enumExtensions returns the extensions that code generators use to name and document the values of an enum:
+// x-enum-varnames and x-enum-descriptions for openapi-generator and x-ms-enum for AutoRest. The names and descriptions
+// line up with the values in the enum of the schema.
+func enumExtensions(opts options.Options, tt protoreflect.EnumDescriptor) *orderedmap.Map[string, *yaml.Node] {
+	varNames := []string{}
+	descriptions := []string{}
+	hasDescriptions := false
+	ms := msEnum{Name: string(tt.Name()), ModelAsString: true}
+	values := tt.Values()
+	for i := 0; i < values.Len(); i++ {
+		value := values.Get(i)
+		description := util.FormatComments(tt.ParentFile().SourceLocations().ByDescriptor(value))
+		hasDescriptions = hasDescriptions || description != ""
+		varNames = append(varNames, string(value.Name()))
+		descriptions = append(descriptions, description)
+		ms.Values = append(ms.Values, msEnumValue{Value: string(value.Name()), Name: string(value.Name()), Description: description})
+		if opts.IncludeNumberEnumValues {
+			varNames = append(varNames, string(value.Name())+"_NUMBER")
+			descriptions = append(descriptions, description)
+		}
+	}
+
+	extensions := util.SetExtension(nil, "x-enum-varnames", util.StringListNode(varNames))
+	if hasDescriptions {
+		extensions.Set("x-enum-descriptions", util.StringListNode(descriptions))
+	}
+	if node, err := util.ExtensionNode(ms); err == nil {
+		extensions.Set("x-ms-enum", node)
+	}
+	return extensions
+}
+
+// inlineEnum copies the values of the enum of a field into the schema of the field, for InlineEnums.
+func inlineEnum(opts options.Options, s *base.Schema, tt protoreflect.FieldDescriptor) *base.Schema {
+	_, enum := EnumToSchema(opts, tt.Enum())
+	s.Type = enum.Type
+	s.Enum = enum.Enum
+	for pair := enum.Extensions.First(); pair != nil; pair = pair.Next() {
+		s.Extensions = util.SetExtension(s.Extensions, pair.Key(), pair.Value())
+	}
+	return s
+}
+
+func transformSchema(opts options.Options, s *base.Schema, tt protoreflect.MessageDescriptor) *base.Schema {
+	for _, transformer := range opts.SchemaTransformers {
+		s = transformer.TransformSchema(s, tt)
+	}
+	return s
+}
+
+// orderedFields returns the fields of a message in the order their properties are written: the order they're
+// declared in, or ordered by field number with options.PropertyOrderNumber.
+func orderedFields(opts options.Options, tt protoreflect.MessageDescriptor) []protoreflect.FieldDescriptor {
+	fields := make([]protoreflect.FieldDescriptor, tt.Fields().Len())
+	for i := range fields {
+		fields[i] = tt.Fields().Get(i)
+	}
+	if opts.PropertyOrder == options.PropertyOrderNumber {
+		slices.SortStableFunc(fields, func(a, b protoreflect.FieldDescriptor) int {
+			return int(a.Number()) - int(b.Number())
+		})
+	}
+	return fields
+}
+
 func FieldToSchema(opts options.Options, parent *base.SchemaProxy, tt protoreflect.FieldDescriptor) *base.SchemaProxy {
 	slog.Debug("FieldToSchema", slog.Any("descriptor", tt.FullName()))
 	defer slog.Debug("/FieldToSchema", slog.Any("descriptor", tt.FullName()))
@@ -93,6 +238,16 @@ func FieldToSchema(opts options.Options, parent *base.SchemaProxy, tt protorefle
 		root.Type = []string{"object"}
 		root.Description = util.TypeFieldDescription(opts, tt)
 		root.AdditionalProperties = &base.DynamicValue[*base.SchemaProxy, bool]{A: FieldToSchema(opts, parent, tt.MapValue())}
+		if anySchema := restrictedAny(tt, tt.MapValue()); anySchema != nil {
+			root.AdditionalProperties = &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(anySchema)}
+		}
+		if patterns := gnostic.KeyPatterns(tt); len(patterns) > 0 {
+			root.PatternProperties = orderedmap.New[string, *base.SchemaProxy]()
+			for _, pattern := range patterns {
+				root.PatternProperties.Set(pattern, root.AdditionalProperties.A)
+			}
+			root.AdditionalProperties = &base.DynamicValue[*base.SchemaProxy, bool]{N: 1, B: false}
+		}
 		root = opts.FieldAnnotator.AnnotateField(opts, root, tt, false)
 		return base.CreateSchemaProxy(root)
 	} else if tt.IsList() {
@@ -100,8 +255,14 @@ func FieldToSchema(opts options.Options, parent *base.SchemaProxy, tt protorefle
 		switch tt.Kind() {
 		case protoreflect.MessageKind:
 			itemSchema = ReferenceFieldToSchema(opts, parent, tt)
+			if anySchema := restrictedAny(tt, tt); anySchema != nil {
+				itemSchema = base.CreateSchemaProxy(anySchema)
+			}
 		case protoreflect.EnumKind:
 			itemSchema = ReferenceFieldToSchema(opts, parent, tt)
+			if opts.InlineEnums {
+				itemSchema = base.CreateSchemaProxy(inlineEnum(opts, ScalarFieldToSchema(opts, parent, tt, true), tt))
+			}
 		default:
 			itemSchema = base.CreateSchemaProxy(ScalarFieldToSchema(opts, parent, tt, true))
 		}
@@ -119,9 +280,22 @@ func FieldToSchema(opts options.Options, parent *base.SchemaProxy, tt protorefle
 		switch tt.Kind() {
 		case protoreflect.MessageKind, protoreflect.EnumKind:
 			msg := ScalarFieldToSchema(opts, parent, tt, false)
+			if tt.Kind() == protoreflect.EnumKind && opts.InlineEnums {
+				return base.CreateSchemaProxy(inlineEnum(opts, msg, tt))
+			}
+			if anySchema := restrictedAny(tt, tt); anySchema != nil {
+				anySchema.Title = msg.Title
+				anySchema.Description = msg.Description
+				anySchema.Deprecated = msg.Deprecated
+				return base.CreateSchemaProxy(anySchema)
+			}
 			ref := ReferenceFieldToSchema(opts, parent, tt)
 			extensions := orderedmap.New[string, *yaml.Node]()
 			extensions.Set("$ref", utils.CreateStringNode(ref.GetReference()))
+			// Keep the extensions of the field annotations next to the $ref
+			for name, value := range msg.Extensions.FromOldest() {
+				extensions.Set(name, value)
+			}
 			msg.Extensions = extensions
 			return base.CreateSchemaProxy(msg)
 		}
@@ -152,10 +326,10 @@ func ScalarFieldToSchema(opts options.Options, parent *base.SchemaProxy, tt prot
 	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind: // int64 types
 		// NOTE: 64-bit integer types can be strings or numbers because they sometimes
 		//       cannot fit into a JSON number type
-		s.Type = []string{"integer", "string"}
+		s.Type = util.Int64Type(opts)
 		s.Format = "int64"
 	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind: // uint64 types
-		s.Type = []string{"integer", "string"}
+		s.Type = util.Int64Type(opts)
 		s.Format = "int64"
 	case protoreflect.DoubleKind:
 		s.Type = []string{"number"}
@@ -169,8 +343,8 @@ func ScalarFieldToSchema(opts options.Options, parent *base.SchemaProxy, tt prot
 		s.Type = []string{"string"}
 		s.Format = "byte"
 	}
-	// Apply Updates from Options
-	s = opts.FieldAnnotator.AnnotateField(opts, s, tt, false)
+	// Apply Updates from Options. Container rules, like repeated.min_items, belong to the container and not the items.
+	s = opts.FieldAnnotator.AnnotateField(opts, s, tt, inContainer)
 	return s
 }
 
@@ -187,6 +361,33 @@ func ReferenceFieldToSchema(opts options.Options, parent *base.SchemaProxy, tt p
 	}
 }
 
+// restrictedAny returns the schema of a google.protobuf.Any value whose "@type" can only be one of the types listed
+// on the field, or nil if value isn't an Any or the field doesn't list any types.
+func restrictedAny(field, value protoreflect.FieldDescriptor) *base.Schema {
+	if value.Kind() != protoreflect.MessageKind || value.Message().FullName() != "google.protobuf.Any" {
+		return nil
+	}
+	urls := gnostic.AnyTypeURLs(field)
+	if len(urls) == 0 {
+		return nil
+	}
+	enum := make([]*yaml.Node, len(urls))
+	for i, url := range urls {
+		enum[i] = utils.CreateStringNode(url)
+	}
+	props := orderedmap.New[string, *base.SchemaProxy]()
+	props.Set("@type", base.CreateSchemaProxy(&base.Schema{
+		Type: []string{"string"},
+		Enum: enum,
+	}))
+	return &base.Schema{
+		Type:                 []string{"object"},
+		Properties:           props,
+		Required:             []string{"@type"},
+		AdditionalProperties: &base.DynamicValue[*base.SchemaProxy, bool]{N: 1, B: true},
+	}
+}
+
 func makeOneOfGroup(opts options.Options, fields []protoreflect.FieldDescriptor) *base.SchemaProxy {
 	rootSchemas := make([]*base.SchemaProxy, 0, len(fields))
 	for _, field := range fields {
@@ -0,0 +1,162 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// applySpectralCompat adjusts the spec so it passes the default Spectral ruleset (spectral:oas). Missing operation,
+// tag and info descriptions get placeholders, operations without tags are tagged by their path, every tag that is
+// used is defined and unused components are removed. Rules that need real information, like info-contact and
+// oas3-api-servers, are left to the base file.
+func applySpectralCompat(spec *v3.Document) {
+	if spec.Info != nil && spec.Info.Description == "" {
+		spec.Info.Description = spec.Info.Title
+	}
+
+	definedTags := map[string]struct{}{}
+	for _, tag := range spec.Tags {
+		definedTags[tag.Name] = struct{}{}
+	}
+	addTag := func(name string) {
+		if _, ok := definedTags[name]; ok {
+			return
+		}
+		definedTags[name] = struct{}{}
+		spec.Tags = append(spec.Tags, &base.Tag{Name: name})
+	}
+	if spec.Paths != nil {
+		for path, item := range spec.Paths.PathItems.FromOldest() {
+			for method, op := range item.GetOperations().FromOldest() {
+				if op == nil {
+					continue
+				}
+				if op.Description == "" {
+					op.Description = operationPlaceholder(op, method, path)
+				}
+				if len(op.Tags) == 0 {
+					op.Tags = []string{pathTag(path)}
+				}
+				for _, tag := range op.Tags {
+					addTag(tag)
+				}
+			}
+		}
+	}
+	for _, tag := range spec.Tags {
+		if tag.Description == "" {
+			tag.Description = tag.Name
+		}
+	}
+
+	removeUnusedComponents(spec)
+}
+
+func operationPlaceholder(op *v3.Operation, method, path string) string {
+	if op.Summary != "" {
+		return op.Summary
+	}
+	if op.OperationId != "" {
+		return op.OperationId
+	}
+	return strings.ToUpper(method) + " " + path
+}
+
+// pathTag returns the first segment of a path, which is the service name for Connect paths.
+func pathTag(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment == "" {
+		return "default"
+	}
+	return segment
+}
+
+// removeUnusedComponents removes schemas, responses, parameters, headers and request bodies that can't be reached
+// from any path or webhook.
+func removeUnusedComponents(spec *v3.Document) {
+	components := spec.Components
+	if components == nil {
+		return
+	}
+
+	used := map[string]map[string]struct{}{}
+	queue := []string{}
+	walker := refWalker(func(ref string) {
+		kind, name, ok := strings.Cut(strings.TrimPrefix(ref, "#/components/"), "/")
+		if !ok || !strings.HasPrefix(ref, "#/components/") {
+			return
+		}
+		if used[kind] == nil {
+			used[kind] = map[string]struct{}{}
+		}
+		if _, ok := used[kind][name]; ok {
+			return
+		}
+		used[kind][name] = struct{}{}
+		queue = append(queue, kind+"/"+name)
+	})
+
+	if spec.Paths != nil {
+		for item := range spec.Paths.PathItems.ValuesFromOldest() {
+			walker.pathItem(item)
+		}
+	}
+	for item := range spec.Webhooks.ValuesFromOldest() {
+		walker.pathItem(item)
+	}
+
+	for len(queue) > 0 {
+		kind, name, _ := strings.Cut(queue[0], "/")
+		queue = queue[1:]
+		switch kind {
+		case "schemas":
+			walker.schema(components.Schemas.GetOrZero(name))
+		case "responses":
+			walker.response(components.Responses.GetOrZero(name))
+		case "parameters":
+			walker.parameter(components.Parameters.GetOrZero(name))
+		case "headers":
+			walker.header(components.Headers.GetOrZero(name))
+		case "requestBodies":
+			walker.requestBody(components.RequestBodies.GetOrZero(name))
+		}
+	}
+
+	components.Schemas = keepUsed(components.Schemas, used["schemas"])
+	components.Responses = keepUsed(components.Responses, used["responses"])
+	components.Parameters = keepUsed(components.Parameters, used["parameters"])
+	components.Headers = keepUsed(components.Headers, used["headers"])
+	components.RequestBodies = keepUsed(components.RequestBodies, used["requestBodies"])
+}
+
+// refWalker returns a schema walker that calls fn with every reference it finds: the references of schema proxies
+// and the $ref extensions that fields use to keep their own title and description next to a reference.
+func refWalker(fn func(ref string)) *schemaWalker {
+	return newSchemaWalker(func(ref *base.SchemaProxy) *base.SchemaProxy {
+		fn(ref.GetReference())
+		return ref
+	}, func(s *base.Schema) {
+		if s.Extensions == nil {
+			return
+		}
+		if ref := s.Extensions.GetOrZero("$ref"); ref != nil {
+			fn(ref.Value)
+		}
+	})
+}
+
+func keepUsed[T any](m *orderedmap.Map[string, T], used map[string]struct{}) *orderedmap.Map[string, T] {
+	if m == nil {
+		return nil
+	}
+	res := orderedmap.New[string, T]()
+	for name, value := range m.FromOldest() {
+		if _, ok := used[name]; ok {
+			res.Set(name, value)
+		}
+	}
+	return res
+}
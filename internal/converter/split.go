@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// untaggedPart is the name of the part with the operations that have no tags.
+const untaggedPart = "untagged"
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// specPart is a document that splitByTag wrote the operations of a tag to.
+type specPart struct {
+	path string
+	spec *v3.Document
+}
+
+// splitByTag splits a document into a document for every tag, with the paths of that tag and the components they
+// use, and an index document whose paths reference the paths in those documents. A path item goes to the document
+// of the first tag of its first operation. The document isn't changed.
+func splitByTag(path string, spec *v3.Document) (*v3.Document, []specPart, error) {
+	index := *spec
+	index.Paths = &v3.Paths{PathItems: orderedmap.New[string, *v3.PathItem]()}
+	parts := []specPart{}
+	byTag := map[string]*v3.Document{}
+	if spec.Paths != nil {
+		for route, item := range spec.Paths.PathItems.FromOldest() {
+			tag := pathItemTag(item)
+			part, ok := byTag[tag]
+			if !ok {
+				part = newSpecPart(spec, tag)
+				byTag[tag] = part
+				parts = append(parts, specPart{path: partPath(path, tag), spec: part})
+			}
+			part.Paths.PathItems.Set(route, item)
+
+			ref := &url.URL{Fragment: "/paths/" + escapeJSONPointer(route)}
+			index.Paths.PathItems.Set(route, &v3.PathItem{
+				Extensions: util.SetExtension(nil, "$ref", util.StringNode(filepath.Base(partPath(path, tag))+"#"+ref.EscapedFragment())),
+			})
+		}
+	}
+
+	for _, part := range parts {
+		removeUnusedComponents(part.spec)
+	}
+	if spec.Components != nil {
+		components := *spec.Components
+		index.Components = &components
+		removeUnusedComponents(&index)
+	}
+	return &index, parts, nil
+}
+
+// newSpecPart returns an empty document for the paths of a tag, with the info, servers, security and components of
+// the document it's split from.
+func newSpecPart(spec *v3.Document, tag string) *v3.Document {
+	part := &v3.Document{
+		Version:           spec.Version,
+		JsonSchemaDialect: spec.JsonSchemaDialect,
+		Servers:           spec.Servers,
+		Security:          spec.Security,
+		Paths:             &v3.Paths{PathItems: orderedmap.New[string, *v3.PathItem]()},
+	}
+	if spec.Info != nil {
+		info := *spec.Info
+		info.Title = strings.TrimSpace(info.Title + " - " + tag)
+		part.Info = &info
+	}
+	for _, t := range spec.Tags {
+		if t.Name == tag {
+			part.Tags = append(part.Tags, t)
+		}
+	}
+	if spec.Components != nil {
+		components := *spec.Components
+		part.Components = &components
+	}
+	return part
+}
+
+// pathItemTag returns the first tag of the first operation of a path item.
+func pathItemTag(item *v3.PathItem) string {
+	for op := range item.GetOperations().ValuesFromOldest() {
+		if op != nil && len(op.Tags) > 0 {
+			return op.Tags[0]
+		}
+	}
+	return untaggedPart
+}
+
+// partPath returns the path of the document for a tag next to the document at path, like
+// foo.openapi.yaml -> foo.acme.v1.BookService.openapi.yaml.
+func partPath(path, tag string) string {
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(path, ext)
+	suffix := ""
+	if strings.HasSuffix(name, ".openapi") {
+		name, suffix = strings.TrimSuffix(name, ".openapi"), ".openapi"
+	}
+	return name + "." + strings.Trim(unsafeFileNameChars.ReplaceAllString(tag, "-"), "-") + suffix + ext
+}
+
+// escapeJSONPointer escapes a reference token of a JSON pointer.
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -1,9 +1,12 @@
 package converter
 
 import (
+	"strings"
+
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/openapiv2"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
@@ -18,15 +21,44 @@ func fileToTags(opts options.Options, fd protoreflect.FileDescriptor) []*base.Ta
 		}
 		loc := fd.SourceLocations().ByDescriptor(service)
 		description := util.FormatComments(loc)
+		if util.IsServiceDeprecated(service) {
+			description = strings.TrimSpace(serviceDeprecationNotice + "\n\n" + description)
+		}
 
 		tagName := string(service.FullName())
 		if opts.ShortServiceTags {
 			tagName = string(service.Name())
 		}
-		tags = append(tags, &base.Tag{
+		tag := &base.Tag{
 			Name:        tagName,
 			Description: description,
-		})
+		}
+		if opts.WithTagDisplayNames {
+			var humanizer options.TagHumanizer = serviceHumanizer{}
+			if opts.TagHumanizer != nil {
+				humanizer = opts.TagHumanizer
+			}
+			if displayName := humanizer.HumanizeTag(service); displayName != "" {
+				tag.Extensions = util.SetExtension(tag.Extensions, displayNameExtension, util.StringNode(displayName))
+			}
+		}
+		tags = append(tags, openapiv2.TagWithServiceAnnotations(opts, tag, service))
 	}
 	return tags
 }
+
+// serviceDeprecationNotice starts the description of the tag of a deprecated service, whose operations are all
+// deprecated.
+const serviceDeprecationNotice = "Deprecated: every operation of this service is deprecated."
+
+// serviceHumanizer is the default options.TagHumanizer. It drops the "Service" suffix of the service name and
+// splits it into words: "UserAccountService" → "User Account".
+type serviceHumanizer struct{}
+
+func (serviceHumanizer) HumanizeTag(service protoreflect.ServiceDescriptor) string {
+	name := string(service.Name())
+	if trimmed := strings.TrimSuffix(name, "Service"); trimmed != "" {
+		name = trimmed
+	}
+	return util.TitleCase(name)
+}
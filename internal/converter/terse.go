@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// stripDocumentation removes descriptions, titles, summaries and examples from the spec, webhooks included. Response
+// descriptions and the info title are kept because OpenAPI requires them.
+func stripDocumentation(spec *v3.Document) {
+	if spec.Info != nil {
+		spec.Info.Description = ""
+		spec.Info.Summary = ""
+	}
+	for _, tag := range spec.Tags {
+		tag.Description = ""
+	}
+	w := newSchemaWalker(nil, func(s *base.Schema) {
+		s.Title = ""
+		s.Description = ""
+		s.Example = nil
+		s.Examples = nil
+		s.ExternalDocs = nil
+	})
+	w.element = func(element any) {
+		switch element := element.(type) {
+		case *v3.PathItem:
+			element.Summary = ""
+			element.Description = ""
+		case *v3.Operation:
+			element.Summary = ""
+			element.Description = ""
+		case *v3.Parameter:
+			element.Description = ""
+			element.Example = nil
+			element.Examples = nil
+		case *v3.Header:
+			element.Description = ""
+			element.Example = nil
+			element.Examples = nil
+		case *v3.RequestBody:
+			element.Description = ""
+		case *v3.MediaType:
+			element.Example = nil
+			element.Examples = nil
+		}
+	}
+	w.document(spec)
+	if spec.Components != nil {
+		spec.Components.Examples = orderedmap.New[string, *base.Example]()
+	}
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// StringNode returns a YAML node for a string value.
+func StringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// BoolNode returns a YAML node for a boolean value, so it's written as true or false instead of as a string.
+func BoolNode(value bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}
+}
+
+// IntNode returns a YAML node for an integer value.
+func IntNode(value int64) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(value, 10)}
+}
+
+// FloatNode returns a YAML node for a number value. Whole numbers are written like integers, so they don't need an
+// explicit !!float tag. Infinity and NaN aren't JSON numbers, so they're strings like "Infinity", "-Infinity" and
+// "NaN", the way protojson writes them.
+func FloatNode(value float64) *yaml.Node {
+	return floatNode(value, 64)
+}
+
+// Float32Node returns a YAML node for a float value, like FloatNode does for a double.
+func Float32Node(value float32) *yaml.Node {
+	return floatNode(float64(value), 32)
+}
+
+func floatNode(value float64, bitSize int) *yaml.Node {
+	switch {
+	case math.IsInf(value, 1):
+		return StringNode("Infinity")
+	case math.IsInf(value, -1):
+		return StringNode("-Infinity")
+	case math.IsNaN(value):
+		return StringNode("NaN")
+	}
+	formatted := strconv.FormatFloat(value, 'f', -1, bitSize)
+	if !strings.Contains(formatted, ".") {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: formatted}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: formatted}
+}
+
+// StringListNode returns a YAML sequence node with a string node for every value.
+func StringListNode(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, value := range values {
+		node.Content = append(node.Content, StringNode(value))
+	}
+	return node
+}
+
+// ExtensionNode encodes a Go value, like a map, a slice, a struct with yaml tags or a scalar, as a YAML node with
+// the right types for every value. Map keys are sorted.
+func ExtensionNode(value any) (*yaml.Node, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// SetExtension sets an extension and returns the extensions, which are created when they're nil. Use it like:
+//
+//	op.Extensions = util.SetExtension(op.Extensions, "x-example", util.BoolNode(true))
+func SetExtension(extensions *orderedmap.Map[string, *yaml.Node], name string, value *yaml.Node) *orderedmap.Map[string, *yaml.Node] {
+	if extensions == nil {
+		extensions = orderedmap.New[string, *yaml.Node]()
+	}
+	extensions.Set(name, value)
+	return extensions
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// googleTypeToSchemaFns has curated schemas for the common types in google.type. These are regular messages in
+// JSON, so the schemas describe the same objects the generic converter would, but with the bounds and formats
+// from the documentation of each type.
+var googleTypeToSchemaFns = map[string]func(options.Options, protoreflect.MessageDescriptor) *IDSchema{
+	"google.type.Date":      googleTypeDate,
+	"google.type.TimeOfDay": googleTypeTimeOfDay,
+	"google.type.Money":     googleTypeMoney,
+	"google.type.LatLng":    googleTypeLatLng,
+	"google.type.Decimal":   googleTypeDecimal,
+}
+
+// decimalPattern matches the decimal strings accepted by google.type.Decimal, like "2.5", "-.5" or "1e-3".
+const decimalPattern = `^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`
+
+func googleTypeDate(opts options.Options, msg protoreflect.MessageDescriptor) *IDSchema {
+	return googleTypeObject(opts, msg, "A whole or partial calendar date. A year, month or day of 0 means that part of the date is unset.", []googleTypeField{
+		{name: "year", schema: boundedInteger("int32", 0, 9999)},
+		{name: "month", schema: boundedInteger("int32", 0, 12)},
+		{name: "day", schema: boundedInteger("int32", 0, 31)},
+	})
+}
+
+func googleTypeTimeOfDay(opts options.Options, msg protoreflect.MessageDescriptor) *IDSchema {
+	return googleTypeObject(opts, msg, "A time of day. Hours may be 24 for scenarios like business closing time and seconds may be 60 to allow for leap seconds.", []googleTypeField{
+		{name: "hours", schema: boundedInteger("int32", 0, 24)},
+		{name: "minutes", schema: boundedInteger("int32", 0, 59)},
+		{name: "seconds", schema: boundedInteger("int32", 0, 60)},
+		{name: "nanos", schema: boundedInteger("int32", 0, 999999999)},
+	})
+}
+
+func googleTypeMoney(opts options.Options, msg protoreflect.MessageDescriptor) *IDSchema {
+	return googleTypeObject(opts, msg, "An amount of money with its currency type. The amount is units plus nanos, and both must have the same sign.", []googleTypeField{
+		{name: "currency_code", schema: &base.Schema{
+			Type:        []string{"string"},
+			Description: "The three-letter currency code defined in ISO 4217.",
+			Pattern:     "^[A-Z]{3}$",
+		}},
+		{name: "units", schema: &base.Schema{
+			Type:        Int64Type(opts),
+			Format:      "int64",
+			Description: "The whole units of the amount. For example if `currencyCode` is `\"USD\"`, then 1 unit is one US dollar.",
+		}},
+		{name: "nanos", schema: func() *base.Schema {
+			s := boundedInteger("int32", -999999999, 999999999)
+			s.Description = "Number of nano (10^-9) units of the amount."
+			return s
+		}()},
+	})
+}
+
+func googleTypeLatLng(opts options.Options, msg protoreflect.MessageDescriptor) *IDSchema {
+	return googleTypeObject(opts, msg, "A latitude/longitude pair in degrees, using the WGS84 standard.", []googleTypeField{
+		{name: "latitude", schema: boundedNumber(-90, 90)},
+		{name: "longitude", schema: boundedNumber(-180, 180)},
+	})
+}
+
+func googleTypeDecimal(opts options.Options, msg protoreflect.MessageDescriptor) *IDSchema {
+	return googleTypeObject(opts, msg, "A decimal number, encoded as a string in the `value` property to avoid losing precision.", []googleTypeField{
+		{name: "value", schema: &base.Schema{
+			Type:    []string{"string"},
+			Pattern: decimalPattern,
+		}},
+	})
+}
+
+type googleTypeField struct {
+	name   string
+	schema *base.Schema
+}
+
+// googleTypeObject makes an object schema for a google.type message. Property names follow the same naming
+// options as other messages, which is why they are looked up from the message descriptor.
+func googleTypeObject(opts options.Options, msg protoreflect.MessageDescriptor, fallbackDescription string, fields []googleTypeField) *IDSchema {
+	description := FormatComments(msg.ParentFile().SourceLocations().ByDescriptor(msg))
+	if description == "" {
+		description = fallbackDescription
+	}
+	props := orderedmap.New[string, *base.SchemaProxy]()
+	for _, field := range fields {
+		name := field.name
+		if fd := msg.Fields().ByName(protoreflect.Name(field.name)); fd != nil {
+			name = MakeFieldName(opts, fd)
+		}
+		props.Set(name, base.CreateSchemaProxy(field.schema))
+	}
+	return &IDSchema{
+		ID: string(msg.FullName()),
+		Schema: &base.Schema{
+			Description:          description,
+			Type:                 []string{"object"},
+			Properties:           props,
+			AdditionalProperties: &base.DynamicValue[*base.SchemaProxy, bool]{N: 1, B: false},
+		},
+	}
+}
+
+func boundedInteger(format string, minimum, maximum float64) *base.Schema {
+	return &base.Schema{
+		Type:    []string{"integer"},
+		Format:  format,
+		Minimum: &minimum,
+		Maximum: &maximum,
+	}
+}
+
+func boundedNumber(minimum, maximum float64) *base.Schema {
+	return &base.Schema{
+		Type:    []string{"number"},
+		Format:  "double",
+		Minimum: &minimum,
+		Maximum: &maximum,
+	}
+}
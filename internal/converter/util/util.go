@@ -3,17 +3,22 @@ package util
 import (
 	"path"
 	"strings"
+	"unicode"
 
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
 	"github.com/pb33f/libopenapi/orderedmap"
-	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func AppendComponents(spec *v3.Document, components *v3.Components) {
 	for pair := components.Schemas.First(); pair != nil; pair = pair.Next() {
+		// Schemas for well-known types that are already defined, usually by the base file, take precedence
+		if _, ok := spec.Components.Schemas.Get(pair.Key()); ok && IsWellKnownName(pair.Key()) {
+			continue
+		}
 		spec.Components.Schemas.Set(pair.Key(), pair.Value())
 	}
 	for pair := components.Responses.First(); pair != nil; pair = pair.Next() {
@@ -84,7 +89,12 @@ func FormatTypeRef(t string) string {
 	return strings.TrimPrefix(t, ".")
 }
 
+// IsMethodDeprecated returns whether a method is deprecated, or nil if it isn't set. Methods of deprecated services
+// and files are always deprecated.
 func IsMethodDeprecated(md protoreflect.MethodDescriptor) *bool {
+	if service, ok := md.Parent().(protoreflect.ServiceDescriptor); ok && IsServiceDeprecated(service) {
+		return BoolPtr(true)
+	}
 	options, ok := md.Options().(*descriptorpb.MethodOptions)
 	if !ok || options == nil {
 		return nil
@@ -95,6 +105,20 @@ func IsMethodDeprecated(md protoreflect.MethodDescriptor) *bool {
 	return options.Deprecated
 }
 
+// IsServiceDeprecated returns true if a service, or the file it's in, is deprecated.
+func IsServiceDeprecated(sd protoreflect.ServiceDescriptor) bool {
+	if options, ok := sd.Options().(*descriptorpb.ServiceOptions); ok && options.GetDeprecated() {
+		return true
+	}
+	return IsFileDeprecated(sd.ParentFile())
+}
+
+// IsFileDeprecated returns true if a file has the deprecated file option.
+func IsFileDeprecated(fd protoreflect.FileDescriptor) bool {
+	options, ok := fd.Options().(*descriptorpb.FileOptions)
+	return ok && options.GetDeprecated()
+}
+
 func IsFieldDeprecated(fd protoreflect.FieldDescriptor) *bool {
 	options, ok := fd.Options().(*descriptorpb.FieldOptions)
 	if !ok || options == nil {
@@ -132,15 +156,173 @@ func MakeMediaTypes(opts options.Options, s *base.SchemaProxy, isRequest, isStre
 	return mediaTypes
 }
 
+// Int64Type returns the types of 64-bit integers. Connect accepts both JSON numbers and strings, while transcoders
+// always write them as strings.
+func Int64Type(opts options.Options) []string {
+	if opts.Flavor.IsTranscoder() {
+		return []string{"string"}
+	}
+	return []string{"integer", "string"}
+}
+
+// FindMessage looks up a message by its full name in the given file and all of its transitive imports.
+func FindMessage(fd protoreflect.FileDescriptor, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	seen := map[string]struct{}{}
+	queue := []protoreflect.FileDescriptor{fd}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[file.Path()]; ok {
+			continue
+		}
+		seen[file.Path()] = struct{}{}
+		if msg := findMessageInFile(file, name); msg != nil {
+			return msg
+		}
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			queue = append(queue, imports.Get(i).FileDescriptor)
+		}
+	}
+	return nil
+}
+
+func findMessageInFile(fd protoreflect.FileDescriptor, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	relative := string(name)
+	if pkg := string(fd.Package()); pkg != "" {
+		if !strings.HasPrefix(relative, pkg+".") {
+			return nil
+		}
+		relative = strings.TrimPrefix(relative, pkg+".")
+	}
+	messages := fd.Messages()
+	var msg protoreflect.MessageDescriptor
+	for _, part := range strings.Split(relative, ".") {
+		msg = messages.ByName(protoreflect.Name(part))
+		if msg == nil {
+			return nil
+		}
+		messages = msg.Messages()
+	}
+	return msg
+}
+
+// wellKnownImportDirs are the directories of the files that are shipped as common google dependencies. Other files
+// under google/, like the APIs in google/cloud, are regular inputs.
+var wellKnownImportDirs = map[string]struct{}{
+	"google/api":               {},
+	"google/longrunning":       {},
+	"google/protobuf":          {},
+	"google/protobuf/compiler": {},
+	"google/rpc":               {},
+	"google/rpc/context":       {},
+	"google/type":              {},
+}
+
+// IsWellKnownImport returns true for files that are shipped as common google dependencies, like
+// google/api/annotations.proto, google/rpc/status.proto and google/protobuf/timestamp.proto.
+func IsWellKnownImport(filename string) bool {
+	_, ok := wellKnownImportDirs[path.Dir(filename)]
+	return ok
+}
+
 func MakeFieldName(opts options.Options, fd protoreflect.FieldDescriptor) string {
-	if opts.WithProtoNames {
+	if opts.WithProtoNames || hasJSONNameConflict(fd) {
 		return string(fd.Name())
 	}
 	return fd.JSONName()
 }
 
+// hasJSONNameConflict returns true if an earlier field of the same message has the same JSON name. This can only
+// happen in messages with `json_format = LEGACY_BEST_EFFORT`. protojson reads the JSON name as the earlier field, so
+// the later one can only be set with its protobuf name.
+func hasJSONNameConflict(fd protoreflect.FieldDescriptor) bool {
+	msg := fd.ContainingMessage()
+	if msg == nil || fd.IsExtension() || IsJSONCompliant(msg) {
+		return false
+	}
+	other := msg.Fields().ByJSONName(fd.JSONName())
+	return other != nil && other.Number() != fd.Number()
+}
+
+// IsJSONCompliant returns true if the json_format feature of a message or enum is ALLOW. The feature is inherited
+// from parent messages and the file. It defaults to LEGACY_BEST_EFFORT for proto2 files and to ALLOW otherwise.
+func IsJSONCompliant(desc protoreflect.Descriptor) bool {
+	for d := desc; d != nil; d = d.Parent() {
+		var features *descriptorpb.FeatureSet
+		switch opts := d.Options().(type) {
+		case *descriptorpb.MessageOptions:
+			features = opts.GetFeatures()
+		case *descriptorpb.EnumOptions:
+			features = opts.GetFeatures()
+		case *descriptorpb.FileOptions:
+			features = opts.GetFeatures()
+		}
+		if features.GetJsonFormat() != descriptorpb.FeatureSet_JSON_FORMAT_UNKNOWN {
+			return features.GetJsonFormat() == descriptorpb.FeatureSet_ALLOW
+		}
+	}
+	return desc.ParentFile() == nil || desc.ParentFile().Syntax() != protoreflect.Proto2
+}
+
 func MakePath(opts options.Options, main string) string {
-	return path.Join(opts.PathPrefix, main)
+	return path.Join(opts.PathPrefix, ConvertPathCase(main, opts.PathCase))
+}
+
+// ConvertPathCase converts the literal parts of a path to a case. Path parameters, like {book_id}, are left alone
+// because they name fields. Dots and colons, like in package names and custom verbs, separate words that are
+// converted on their own.
+func ConvertPathCase(p string, pathCase options.PathCase) string {
+	if pathCase == "" || pathCase == options.PathCaseAsIs {
+		return p
+	}
+	var b, word strings.Builder
+	depth := 0
+	flush := func() {
+		if word.Len() > 0 {
+			b.WriteString(convertWordCase(word.String(), pathCase))
+			word.Reset()
+		}
+	}
+	for _, r := range p {
+		switch {
+		case r == '{':
+			flush()
+			depth++
+			b.WriteRune(r)
+		case r == '}':
+			depth--
+			b.WriteRune(r)
+		case depth > 0:
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-':
+			word.WriteRune(r)
+		default:
+			flush()
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+func convertWordCase(word string, pathCase options.PathCase) string {
+	if pathCase == options.PathCaseLower {
+		return strings.ToLower(word)
+	}
+	words := splitCamelCase(strings.ReplaceAll(word, "-", "_"))
+	for i := range words {
+		words[i] = strings.ToLower(words[i])
+	}
+	if pathCase == options.PathCaseSnake {
+		return strings.Join(words, "_")
+	}
+	return strings.Join(words, "-")
+}
+
+// SnakeCase converts a name like "BookShelf" to "book_shelf".
+func SnakeCase(name string) string {
+	return convertWordCase(name, options.PathCaseSnake)
 }
 
 func AppendStringDedupe(strs []string, str string) []string {
@@ -166,3 +348,118 @@ func Singular(plural string) string {
 	}
 	return plural
 }
+
+// MethodSummary returns the summary for operations generated from a method, from the first of the summary sources
+// of the options that has one: the first line of the method's comments, the humanized method name
+// ("ListBooks" → "List books") or the method name, which is also the fallback.
+func MethodSummary(opts options.Options, md protoreflect.MethodDescriptor) string {
+	for _, source := range opts.SummarySources {
+		switch source {
+		case options.SummarySourceComment:
+			loc := md.ParentFile().SourceLocations().ByDescriptor(md)
+			if line := firstLine(loc.LeadingComments); line != "" {
+				return line
+			}
+		case options.SummarySourceHumanized:
+			return Humanize(string(md.Name()))
+		case options.SummarySourceName:
+			return string(md.Name())
+		}
+	}
+	return string(md.Name())
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// Humanize turns a CamelCase identifier into a sentence-cased phrase: "ListBooks" → "List books". Acronyms are
+// kept as-is: "GetHTTPConfig" → "Get HTTP config".
+func Humanize(name string) string {
+	words := splitCamelCase(name)
+	for i, word := range words {
+		switch {
+		case isAcronym(word):
+		case i == 0:
+			runes := []rune(word)
+			words[i] = string(unicode.ToUpper(runes[0])) + strings.ToLower(string(runes[1:]))
+		default:
+			words[i] = strings.ToLower(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// TitleCase turns a CamelCase identifier into a title-cased phrase: "UserAccount" → "User Account". Acronyms are
+// kept as-is: "HTTPConfig" → "HTTP Config".
+func TitleCase(name string) string {
+	words := splitCamelCase(name)
+	for i, word := range words {
+		if isAcronym(word) {
+			continue
+		}
+		runes := []rune(word)
+		words[i] = string(unicode.ToUpper(runes[0])) + strings.ToLower(string(runes[1:]))
+	}
+	return strings.Join(words, " ")
+}
+
+func splitCamelCase(s string) []string {
+	runes := []rune(s)
+	words := []string{}
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		switch {
+		case cur == '_':
+			if start < i {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+		case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			words = append(words, string(runes[start:i]))
+			start = i
+		case unicode.IsUpper(cur) && unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func isAcronym(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
+
+// Slugify turns a name into lowercase words joined by dashes, which is safe to use in a URL fragment:
+// "acme.v1.BookService.ListBooks" → "acme-v1-book-service-list-books".
+func Slugify(name string) string {
+	words := []string{}
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		for _, word := range splitCamelCase(part) {
+			if word != "" {
+				words = append(words, strings.ToLower(word))
+			}
+		}
+	}
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"math"
+)
+
+func TestHumanize(t *testing.T) {
+	assert.Equal(t, "List books", Humanize("ListBooks"))
+	assert.Equal(t, "Get HTTP config", Humanize("GetHTTPConfig"))
+	assert.Equal(t, "Batch get v2 items", Humanize("BatchGetV2Items"))
+	assert.Equal(t, "Ping", Humanize("Ping"))
+	assert.Equal(t, "Get user", Humanize("get_user"))
+}
+
+func TestTitleCase(t *testing.T) {
+	assert.Equal(t, "User Account", TitleCase("UserAccount"))
+	assert.Equal(t, "HTTP Config", TitleCase("HTTPConfig"))
+	assert.Equal(t, "Book Store", TitleCase("book_store"))
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "acme-v1-book-service-list-books", Slugify("acme.v1.BookService.ListBooks"))
+	assert.Equal(t, "get-http-config", Slugify("GetHTTPConfig"))
+	assert.Equal(t, "get-user", Slugify("get_user"))
+	assert.Equal(t, "books-list", Slugify("Books / List"))
+	assert.Equal(t, "", Slugify("..."))
+}
+
+func TestConvertPathCase(t *testing.T) {
+	connect := "/acme.v1.BookService/ListBooks"
+	rest := "/v1/{parent=shelves/*}/bookCopies/{book_id}:markAsRead"
+	assert.Equal(t, connect, ConvertPathCase(connect, options.PathCaseAsIs))
+	assert.Equal(t, "/acme.v1.book-service/list-books", ConvertPathCase(connect, options.PathCaseKebab))
+	assert.Equal(t, "/acme.v1.book_service/list_books", ConvertPathCase(connect, options.PathCaseSnake))
+	assert.Equal(t, "/acme.v1.bookservice/listbooks", ConvertPathCase(connect, options.PathCaseLower))
+	assert.Equal(t, "/v1/{parent=shelves/*}/book-copies/{book_id}:mark-as-read", ConvertPathCase(rest, options.PathCaseKebab))
+	assert.Equal(t, "/v1/user-accounts", ConvertPathCase("/v1/user_accounts", options.PathCaseKebab))
+	assert.Equal(t, "/v1/user_accounts", ConvertPathCase("/v1/user-accounts", options.PathCaseSnake))
+}
+
+func TestIsWellKnownImport(t *testing.T) {
+	assert.True(t, IsWellKnownImport("google/api/annotations.proto"))
+	assert.True(t, IsWellKnownImport("google/rpc/status.proto"))
+	assert.True(t, IsWellKnownImport("google/protobuf/timestamp.proto"))
+	assert.True(t, IsWellKnownImport("google/type/money.proto"))
+	assert.False(t, IsWellKnownImport("google/cloud/translate/v3/translation_service.proto"))
+	assert.False(t, IsWellKnownImport("google/example/library/v1/library.proto"))
+	assert.False(t, IsWellKnownImport("acme/google/api/books.proto"))
+}
+
+func TestExtensionNodes(t *testing.T) {
+	extensions := SetExtension(nil, "x-enabled", BoolNode(true))
+	extensions = SetExtension(extensions, "x-limit", IntNode(1024))
+	extensions = SetExtension(extensions, "x-ratio", FloatNode(0.5))
+	extensions = SetExtension(extensions, "x-ids", StringListNode([]string{"a", "b"}))
+	node, err := ExtensionNode(map[string]any{"name": "test", "count": 2, "tags": []string{"x"}})
+	require.NoError(t, err)
+	extensions = SetExtension(extensions, "x-info", node)
+
+	out, err := yaml.Marshal(extensions)
+	require.NoError(t, err)
+	assert.Equal(t, `x-enabled: true
+x-limit: 1024
+x-ratio: 0.5
+x-ids:
+    - a
+    - b
+x-info:
+    count: 2
+    name: test
+    tags:
+        - x
+`, string(out))
+}
+
+func TestFloatNode(t *testing.T) {
+	assert.Equal(t, "0.1", FloatNode(0.1).Value)
+	assert.Equal(t, "0.1", Float32Node(0.1).Value)
+	out, err := yaml.Marshal([]*yaml.Node{FloatNode(42), FloatNode(-1.5), Float32Node(0)})
+	require.NoError(t, err)
+	assert.Equal(t, "- 42\n- -1.5\n- 0\n", string(out))
+	for value, expected := range map[float64]string{math.Inf(1): "Infinity", math.Inf(-1): "-Infinity", math.NaN(): "NaN"} {
+		assert.Equal(t, StringNode(expected), FloatNode(value))
+		assert.Equal(t, StringNode(expected), Float32Node(float32(value)))
+	}
+}
@@ -1,11 +1,15 @@
 package util
 
 import (
+	"slices"
+
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	"github.com/pb33f/libopenapi/orderedmap"
 	"github.com/pb33f/libopenapi/utils"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
 )
 
 var wellKnownToSchemaFns = map[string]func(protoreflect.MessageDescriptor) *IDSchema{
@@ -36,16 +40,76 @@ type IDSchema struct {
 }
 
 func IsWellKnown(msg protoreflect.MessageDescriptor) bool {
-	_, ok := wellKnownToSchemaFns[string(msg.FullName())]
+	return IsWellKnownName(string(msg.FullName()))
+}
+
+// IsWellKnownName returns true if a schema with this name is generated from the well-known type registry.
+func IsWellKnownName(name string) bool {
+	if _, ok := wellKnownToSchemaFns[name]; ok {
+		return true
+	}
+	_, ok := googleTypeToSchemaFns[name]
 	return ok
 }
 
-func WellKnownToSchema(msg protoreflect.MessageDescriptor) *IDSchema {
+func WellKnownToSchema(opts options.Options, msg protoreflect.MessageDescriptor) *IDSchema {
+	if fn, ok := googleTypeToSchemaFns[string(msg.FullName())]; ok {
+		return fn(opts, msg)
+	}
 	fn, ok := wellKnownToSchemaFns[string(msg.FullName())]
 	if !ok {
 		return nil
 	}
-	return fn(msg)
+	s := fn(msg)
+	if opts.WithStructDocs {
+		documentStruct(s)
+	}
+	return s
+}
+
+// structExample is the example of google.protobuf.Struct and google.protobuf.Value with WithStructDocs, a small
+// configuration with every kind of value.
+const structExample = `name: example
+replicas: 3
+enabled: true
+parent: null
+labels:
+  team: platform
+ports:
+  - 80
+  - 443
+`
+
+const structNote = "Values nest objects and arrays to any depth, like JSON. Every nested value is a google.protobuf.Value again."
+
+// documentStruct makes the schemas of google.protobuf.Value and google.protobuf.Struct recursive, so the arrays and
+// objects of a Value contain Values, and adds an example and a note about the nesting.
+func documentStruct(s *IDSchema) {
+	valueRef := base.CreateSchemaProxyRef("#/components/schemas/google.protobuf.Value")
+	switch s.ID {
+	case "google.protobuf.Value":
+		for _, proxy := range s.Schema.OneOf {
+			option := proxy.Schema()
+			switch {
+			case slices.Contains(option.Type, "array"):
+				option.Items = &base.DynamicValue[*base.SchemaProxy, bool]{A: valueRef}
+			case slices.Contains(option.Type, "object"):
+				option.AdditionalProperties = &base.DynamicValue[*base.SchemaProxy, bool]{A: valueRef}
+			}
+		}
+	case "google.protobuf.Struct":
+		// The fields of a Struct already refer to Value
+	default:
+		return
+	}
+	example := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(structExample), example); err == nil && len(example.Content) == 1 {
+		s.Schema.Examples = []*yaml.Node{example.Content[0]}
+	}
+	if s.Schema.Description != "" {
+		s.Schema.Description += "\n\n"
+	}
+	s.Schema.Description += structNote
 }
 
 func googleDuration(msg protoreflect.MessageDescriptor) *IDSchema {
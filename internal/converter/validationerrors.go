@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/protovalidate"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// validationErrorResponse returns the 400 response of a method whose request has protovalidate rules: an
+// invalid_argument error with a google.rpc.BadRequest detail. The field of each violation is restricted to the
+// paths of the constrained fields of the request. It returns nil when the request has no rules.
+func validationErrorResponse(opts options.Options, method protoreflect.MethodDescriptor, isStreaming bool) *v3.Response {
+	paths, exact := protovalidate.ViolationFieldPaths(method.Input())
+	if len(paths) == 0 {
+		return nil
+	}
+	field := &base.Schema{Type: []string{"string"}}
+	values := make([]*yaml.Node, len(paths))
+	for i, path := range paths {
+		values[i] = util.StringNode(path)
+	}
+	if exact {
+		field.Enum = values
+	} else {
+		field.Description = "The path of the field that failed validation. Elements of repeated and map fields have their index or key in the path."
+		field.Examples = values
+	}
+	violationProps := orderedmap.New[string, *base.SchemaProxy]()
+	violationProps.Set("field", base.CreateSchemaProxy(field))
+	violationsProps := orderedmap.New[string, *base.SchemaProxy]()
+	violationsProps.Set("fieldViolations", base.CreateSchemaProxy(&base.Schema{
+		Type: []string{"array"},
+		Items: &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(&base.Schema{
+			AllOf: []*base.SchemaProxy{
+				base.CreateSchemaProxyRef("#/components/schemas/google.rpc.BadRequest.FieldViolation"),
+				base.CreateSchemaProxy(&base.Schema{Properties: violationProps}),
+			},
+		})},
+	}))
+	badRequest := base.CreateSchemaProxy(&base.Schema{
+		AllOf: []*base.SchemaProxy{
+			base.CreateSchemaProxyRef("#/components/schemas/google.rpc.BadRequest"),
+			base.CreateSchemaProxy(&base.Schema{Properties: violationsProps}),
+		},
+	})
+
+	errorProps := orderedmap.New[string, *base.SchemaProxy]()
+	detailProps := orderedmap.New[string, *base.SchemaProxy]()
+	var detail *base.Schema
+	if opts.Flavor.IsTranscoder() {
+		// google.rpc.Status has the numeric code and the details are google.protobuf.Any in their JSON form
+		errorProps.Set("code", base.CreateSchemaProxy(&base.Schema{Const: util.IntNode(3)}))
+		detailProps.Set("@type", base.CreateSchemaProxy(&base.Schema{Const: util.StringNode("type.googleapis.com/google.rpc.BadRequest")}))
+		detail = &base.Schema{AllOf: []*base.SchemaProxy{base.CreateSchemaProxy(&base.Schema{Properties: detailProps}), badRequest}}
+	} else {
+		// Connect errors have the binary detail in value and its JSON form in debug
+		errorProps.Set("code", base.CreateSchemaProxy(&base.Schema{Const: util.StringNode("invalid_argument")}))
+		detailProps.Set("type", base.CreateSchemaProxy(&base.Schema{Const: util.StringNode("google.rpc.BadRequest")}))
+		detailProps.Set("value", base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}, Format: "byte"}))
+		detailProps.Set("debug", badRequest)
+		detail = &base.Schema{Type: []string{"object"}, Properties: detailProps}
+	}
+	errorProps.Set("details", base.CreateSchemaProxy(&base.Schema{
+		Type:  []string{"array"},
+		Items: &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(detail)},
+	}))
+
+	return &v3.Response{
+		Description: "Bad Request: the request failed validation",
+		Content: util.MakeMediaTypes(
+			opts,
+			base.CreateSchemaProxy(&base.Schema{
+				AllOf: []*base.SchemaProxy{
+					base.CreateSchemaProxyRef(opts.ErrorSchemaRef()),
+					base.CreateSchemaProxy(&base.Schema{Properties: errorProps}),
+				},
+			}),
+			false,
+			isStreaming,
+		),
+	}
+}
+
+// badRequestSchemas returns the schemas of google.rpc.BadRequest and its field violations.
+func badRequestSchemas() (*base.Schema, *base.Schema) {
+	violationProps := orderedmap.New[string, *base.SchemaProxy]()
+	violationProps.Set("field", base.CreateSchemaProxy(&base.Schema{
+		Description: "A path that leads to a field in the request body.",
+		Type:        []string{"string"},
+	}))
+	violationProps.Set("description", base.CreateSchemaProxy(&base.Schema{
+		Description: "A description of why the request element is bad.",
+		Type:        []string{"string"},
+	}))
+	violation := &base.Schema{
+		Title:       "FieldViolation",
+		Description: "A message type used to describe a single bad request field.",
+		Type:        []string{"object"},
+		Properties:  violationProps,
+	}
+
+	props := orderedmap.New[string, *base.SchemaProxy]()
+	props.Set("fieldViolations", base.CreateSchemaProxy(&base.Schema{
+		Description: "Describes all violations in a client request.",
+		Type:        []string{"array"},
+		Items:       &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxyRef("#/components/schemas/google.rpc.BadRequest.FieldViolation")},
+	}))
+	badRequest := &base.Schema{
+		Title:       "BadRequest",
+		Description: "Describes violations in a client request. This error type focuses on the syntactic aspects of the request.",
+		Type:        []string{"object"},
+		Properties:  props,
+	}
+	return badRequest, violation
+}
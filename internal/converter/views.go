@@ -0,0 +1,216 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// ResourceViewsExtension is an operation extension on methods with an AIP-157 view field. It maps every value of the
+// view enum to the schema of the resource that's returned in that view:
+//
+//	x-resource-views:
+//	  parameter: view
+//	  views:
+//	    BOOK_VIEW_BASIC: '#/components/schemas/example.v1.Book.BOOK_VIEW_BASIC'
+//	    BOOK_VIEW_FULL: '#/components/schemas/example.v1.Book'
+const ResourceViewsExtension = "x-resource-views"
+
+// applyAIP157 documents the view field of methods like AIP-157 describes it. Views that leave out fields of the
+// resource, set with the x-views extension on the fields, get a schema with only the fields of the view, named after
+// the resource and the value of the view enum. Responses refer to the schemas of every view, and operations link
+// every view to its schema with ResourceViewsExtension.
+// The pass works on the finished document, since it adds schemas next to the one of the resource.
+func applyAIP157(opts options.Options, spec *v3.Document) {
+	if spec.Paths == nil || spec.Components == nil || spec.Components.Schemas == nil {
+		return
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for verb, op := range item.GetOperations().FromOldest() {
+			if op == nil {
+				continue
+			}
+			method, ok := opts.Conversion().Route(path, verb)
+			if !ok {
+				continue
+			}
+			view := method.Input().Fields().ByName("view")
+			resource := viewResource(method)
+			if view == nil || view.Kind() != protoreflect.EnumKind || view.IsList() || resource == nil {
+				continue
+			}
+			resourceSchema, ok := spec.Components.Schemas.Get(string(resource.FullName()))
+			if !ok || resourceSchema.Schema() == nil {
+				continue
+			}
+
+			views := orderedmap.New[string, string]()
+			notes := []string{}
+			values := view.Enum().Values()
+			for i := 0; i < values.Len(); i++ {
+				value := values.Get(i)
+				if value.Number() == 0 {
+					continue
+				}
+				ref := "#/components/schemas/" + string(resource.FullName())
+				if name := viewSchema(opts, spec, resource, resourceSchema.Schema(), value); name != "" {
+					ref = "#/components/schemas/" + name
+				}
+				views.Set(string(value.Name()), ref)
+				notes = append(notes, fmt.Sprintf("%s returns %s", value.Name(), strings.TrimPrefix(ref, "#/components/schemas/")))
+			}
+			if views.Len() == 0 {
+				continue
+			}
+			referenceViews(opts, spec, op, method.Output(), resource, views)
+			node, err := util.ExtensionNode(map[string]any{
+				"parameter": util.MakeFieldName(opts, view),
+				"views":     views,
+			})
+			if err != nil {
+				continue
+			}
+			op.Extensions = util.SetExtension(op.Extensions, ResourceViewsExtension, node)
+			describeParameter(opts, method, op, "view", "The view of the resource to return: "+strings.Join(notes, ", ")+".")
+		}
+	}
+}
+
+// referenceViews makes the responses of an operation refer to the schemas of every view with an anyOf, in place of
+// the schema of the resource. For List methods, that's the items of the field with the resources. A view that has
+// every field also matches the schemas of smaller views, so they're alternatives and not a oneOf.
+func referenceViews(opts options.Options, spec *v3.Document, op *v3.Operation, output, resource protoreflect.MessageDescriptor, views *orderedmap.Map[string, string]) {
+	refs := []string{}
+	for ref := range views.ValuesFromOldest() {
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) < 2 {
+		return
+	}
+	variants := func() *base.SchemaProxy {
+		s := &base.Schema{}
+		for _, ref := range refs {
+			s.AnyOf = append(s.AnyOf, base.CreateSchemaProxyRef(ref))
+		}
+		return base.CreateSchemaProxy(s)
+	}
+	resourceRef := "#/components/schemas/" + string(resource.FullName())
+
+	if output != resource {
+		outputSchema := spec.Components.Schemas.GetOrZero(string(output.FullName()))
+		if outputSchema == nil || outputSchema.Schema() == nil {
+			return
+		}
+		fields := output.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if !field.IsList() || field.Message() != resource {
+				continue
+			}
+			prop := outputSchema.Schema().Properties.GetOrZero(util.MakeFieldName(opts, field))
+			if prop == nil || prop.Schema() == nil || prop.Schema().Items == nil {
+				continue
+			}
+			if items := prop.Schema().Items; items.A != nil && items.A.GetReference() == resourceRef {
+				items.A = variants()
+			}
+		}
+		return
+	}
+	if op.Responses == nil {
+		return
+	}
+	for response := range op.Responses.Codes.ValuesFromOldest() {
+		if response == nil {
+			continue
+		}
+		for mediaType := range response.Content.ValuesFromOldest() {
+			if mediaType != nil && mediaType.Schema != nil && mediaType.Schema.GetReference() == resourceRef {
+				mediaType.Schema = variants()
+			}
+		}
+	}
+}
+
+// viewResource returns the resource that the view of a method applies to. That's the message of the only repeated
+// message field of List responses, and the response itself otherwise.
+func viewResource(method protoreflect.MethodDescriptor) protoreflect.MessageDescriptor {
+	output := method.Output()
+	if !isStandardMethod(method, "List") {
+		return output
+	}
+	var resource protoreflect.MessageDescriptor
+	fields := output.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !field.IsList() || field.Message() == nil {
+			continue
+		}
+		if resource != nil {
+			return output
+		}
+		resource = field.Message()
+	}
+	if resource == nil {
+		return output
+	}
+	return resource
+}
+
+// viewSchema adds the schema of a resource in a view to components.schemas and returns its name. Views that have
+// every field of the resource don't need their own schema, and "" is returned for them. The schema is readOnly, since
+// views are only returned by the service and never sent to it.
+func viewSchema(opts options.Options, spec *v3.Document, resource protoreflect.MessageDescriptor, resourceSchema *base.Schema, value protoreflect.EnumValueDescriptor) string {
+	name := string(resource.FullName()) + "." + string(value.Name())
+	if _, ok := spec.Components.Schemas.Get(name); ok {
+		return name
+	}
+	s := copySchema(resourceSchema)
+	omitted := false
+	fields := resource.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if fieldName := util.MakeFieldName(opts, field); s.Properties.GetOrZero(fieldName) != nil && !inView(gnostic.FieldViews(field), value) {
+			s.Properties.Delete(fieldName)
+			omitted = true
+		}
+	}
+	if !omitted {
+		return ""
+	}
+
+	s.Required = slices.DeleteFunc(s.Required, func(required string) bool {
+		return s.Properties.GetOrZero(required) == nil
+	})
+	s.Title = fmt.Sprintf("%s (%s)", resourceSchema.Title, value.Name())
+	s.Description = fmt.Sprintf("The fields of %s that are returned in the %s view.", resource.FullName(), value.Name())
+	readOnly := true
+	s.ReadOnly = &readOnly
+	spec.Components.Schemas.Set(name, base.CreateSchemaProxy(s))
+	return name
+}
+
+// inView returns true if a field with the given views is in the view of an enum value. Views match the name of the
+// value, or its last part after the prefix of the enum, like BASIC for BOOK_VIEW_BASIC.
+func inView(views []string, value protoreflect.EnumValueDescriptor) bool {
+	if views == nil {
+		return true
+	}
+	for _, view := range views {
+		if string(value.Name()) == view || strings.HasSuffix(string(value.Name()), "_"+view) {
+			return true
+		}
+	}
+	return false
+}
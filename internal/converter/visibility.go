@@ -0,0 +1,93 @@
+package converter
+
+import (
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"google.golang.org/genproto/googleapis/api/visibility"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// internalExtension marks operations that documentation portals like Redocly and ReadMe should hide.
+const internalExtension = "x-internal"
+
+// visibilityRestriction returns the google.api.method_visibility restriction of a method, falling back to the
+// google.api.api_visibility restriction of its service.
+func visibilityRestriction(method protoreflect.MethodDescriptor) string {
+	if rule, ok := proto.GetExtension(method.Options(), visibility.E_MethodVisibility).(*visibility.VisibilityRule); ok && rule != nil {
+		return rule.GetRestriction()
+	}
+	service, ok := method.Parent().(protoreflect.ServiceDescriptor)
+	if !ok {
+		return ""
+	}
+	if rule, ok := proto.GetExtension(service.Options(), visibility.E_ApiVisibility).(*visibility.VisibilityRule); ok && rule != nil {
+		return rule.GetRestriction()
+	}
+	return ""
+}
+
+// isInternal returns true if a method is restricted to visibility labels that aren't in VisibilityLabels. Like
+// google.api.VisibilityRule, a restriction is a comma-separated list of labels and a method is visible to an audience
+// that has any of them.
+func isInternal(opts options.Options, method protoreflect.MethodDescriptor) bool {
+	restriction := visibilityRestriction(method)
+	if strings.TrimSpace(restriction) == "" {
+		return false
+	}
+	for _, label := range strings.Split(restriction, ",") {
+		for _, visible := range opts.VisibilityLabels {
+			if strings.EqualFold(strings.TrimSpace(label), visible) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// markInternal sets x-internal: true on an operation of a restricted method. A value that's already set, like one
+// from a (gnostic.openapi.v3.operation) annotation, is kept.
+func markInternal(opts options.Options, method protoreflect.MethodDescriptor, op *v3.Operation) {
+	if !isInternal(opts, method) {
+		return
+	}
+	if op.Extensions != nil && op.Extensions.GetOrZero(internalExtension) != nil {
+		return
+	}
+	op.Extensions = util.SetExtension(op.Extensions, internalExtension, util.BoolNode(true))
+}
+
+// removeInternalOperations removes every operation with x-internal: true and the path items that have no operations
+// left.
+func removeInternalOperations(spec *v3.Document) {
+	if spec.Paths == nil || spec.Paths.PathItems == nil {
+		return
+	}
+	var emptyPaths []string
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for _, op := range []**v3.Operation{&item.Get, &item.Put, &item.Post, &item.Delete, &item.Options, &item.Head, &item.Patch, &item.Trace} {
+			if *op != nil && isMarkedInternal(*op) {
+				*op = nil
+			}
+		}
+		if item.GetOperations().Len() == 0 {
+			emptyPaths = append(emptyPaths, path)
+		}
+	}
+	for _, path := range emptyPaths {
+		spec.Paths.PathItems.Delete(path)
+	}
+}
+
+func isMarkedInternal(op *v3.Operation) bool {
+	if op.Extensions == nil {
+		return false
+	}
+	node, ok := op.Extensions.Get(internalExtension)
+	return ok && node != nil && node.Kind == yaml.ScalarNode && node.Value == "true"
+}
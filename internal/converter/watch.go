@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/gnostic"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter/util"
+)
+
+// applyWatch documents methods marked with the x-watch extension, which hold the request open until something
+// changes, so they stand out from regular methods: the operation gets a note about the style and the timeout,
+// server-sent events get a text/event-stream response and chunked responses get a Transfer-Encoding header.
+func applyWatch(method protoreflect.MethodDescriptor, op *v3.Operation) {
+	watch, ok := gnostic.WatchMethod(method)
+	if !ok || method.IsStreamingClient() {
+		return
+	}
+
+	var note string
+	switch watch.Style {
+	case gnostic.WatchStyleSSE:
+		note = "This is a watch method that sends changes as server-sent events. The data of every event is a " + string(method.Output().Name()) + " in JSON."
+	case gnostic.WatchStyleChunked:
+		note = "This is a watch method whose response is written in chunks as changes happen, so read the response incrementally."
+	default:
+		note = "This is a watch method: the server holds the request open until something changes or the request times out. Send the request again to keep watching."
+	}
+	if watch.Timeout > 0 {
+		note += fmt.Sprintf(" Requests stay open for up to %s, so use a longer client timeout.", formatSeconds(watch.Timeout))
+	}
+	appendDescription(op, note)
+
+	extension := struct {
+		Style   string `yaml:"style"`
+		Timeout string `yaml:"timeout,omitempty"`
+	}{Style: watch.Style}
+	if watch.Timeout > 0 {
+		extension.Timeout = formatSeconds(watch.Timeout)
+	}
+	if node, err := util.ExtensionNode(extension); err == nil {
+		op.Extensions = util.SetExtension(op.Extensions, gnostic.WatchExtension, node)
+	}
+
+	if watch.Timeout > 0 {
+		for _, param := range op.Parameters {
+			if param != nil && param.Name == "Connect-Timeout-Ms" {
+				param.Description = fmt.Sprintf("The timeout of the request in ms. Use more than %d, since the server holds the request open for up to %s.", watch.Timeout.Milliseconds(), formatSeconds(watch.Timeout))
+			}
+		}
+	}
+
+	if op.Responses == nil || op.Responses.Codes == nil {
+		return
+	}
+	response, ok := op.Responses.Codes.Get("200")
+	if !ok || response == nil {
+		return
+	}
+	switch watch.Style {
+	case gnostic.WatchStyleSSE:
+		if response.Content == nil {
+			response.Content = orderedmap.New[string, *v3.MediaType]()
+		}
+		response.Content.Set("text/event-stream", &v3.MediaType{
+			Schema: base.CreateSchemaProxy(&base.Schema{
+				Type:        []string{"string"},
+				Description: "Server-sent events. The data of every event is a " + string(method.Output().FullName()) + " in JSON.",
+			}),
+		})
+	case gnostic.WatchStyleChunked:
+		if response.Headers == nil {
+			response.Headers = orderedmap.New[string, *v3.Header]()
+		}
+		response.Headers.Set("Transfer-Encoding", &v3.Header{
+			Description: "The response is written in chunks as changes happen.",
+			Schema:      base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}, Const: util.StringNode("chunked")}),
+		})
+	}
+}
+
+// formatSeconds formats a duration as whole seconds, like 300s.
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Round(time.Second)/time.Second))
+}
+
+// appendDescription adds a paragraph to the description of an operation, unless the description already has it.
+func appendDescription(op *v3.Operation, note string) {
+	if op.Description == "" {
+		op.Description = note
+	} else if !strings.Contains(op.Description, note) {
+		op.Description += "\n\n" + note
+	}
+}
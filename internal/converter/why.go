@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sudorandom/protoc-gen-connect-openapi/converter/options"
+)
+
+// whyTracer explains which stage of the converter produced an element of the generated documents, for the why
+// option. After every stage it renders the element and logs when the stage added, changed or removed it.
+type whyTracer struct {
+	element string
+	path    []string
+	// rendered is the element as it was after the last stage, by document. Documents without it aren't in the map.
+	rendered map[*v3.Document]string
+	found    bool
+}
+
+// newWhyTracer returns a tracer for the element of the why option, or nil when it isn't set. Tracing with a nil
+// tracer does nothing.
+func newWhyTracer(opts options.Options) *whyTracer {
+	if opts.Why == "" {
+		return nil
+	}
+	return &whyTracer{
+		element:  opts.Why,
+		path:     whyPath(opts.Why),
+		rendered: map[*v3.Document]string{},
+	}
+}
+
+// whyPath splits an element like components.schemas.connect.error or a JSON pointer like
+// /components/schemas/connect.error into its parts. Dotted parts are joined again when they're looked up, since
+// names like connect.error contain dots too.
+func whyPath(element string) []string {
+	if !strings.HasPrefix(element, "/") {
+		return strings.Split(element, ".")
+	}
+	parts := strings.Split(strings.TrimPrefix(element, "/"), "/")
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+	}
+	return parts
+}
+
+// trace logs how a stage changed the element in a document.
+func (t *whyTracer) trace(spec *v3.Document, stage string) {
+	if t == nil {
+		return
+	}
+	b, err := spec.Render()
+	if err != nil {
+		slog.Debug("why: rendering document", slog.Any("error", err))
+		return
+	}
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(b, root); err != nil {
+		slog.Debug("why: parsing document", slog.Any("error", err))
+		return
+	}
+	rendered, ok := "", false
+	if len(root.Content) > 0 {
+		if node := lookupYAML(root.Content[0], t.path); node != nil {
+			out, err := yaml.Marshal(node)
+			if err != nil {
+				return
+			}
+			rendered, ok = string(out), true
+		}
+	}
+
+	previous, existed := t.rendered[spec]
+	switch {
+	case ok && !existed:
+		slog.Info(fmt.Sprintf("why: %s was added by %s", t.element, stage))
+	case ok && rendered != previous:
+		slog.Info(fmt.Sprintf("why: %s was changed by %s", t.element, stage))
+	case !ok && existed:
+		slog.Info(fmt.Sprintf("why: %s was removed by %s", t.element, stage))
+	}
+	if ok {
+		t.rendered[spec] = rendered
+		t.found = true
+	} else {
+		delete(t.rendered, spec)
+	}
+}
+
+// done logs when the element wasn't in any document.
+func (t *whyTracer) done() {
+	if t == nil || t.found {
+		return
+	}
+	slog.Info(fmt.Sprintf("why: %s isn't in any generated document", t.element))
+}
+
+// lookupYAML returns the node at path, or nil if there isn't one. Keys of mappings can span several parts of the
+// path, like connect.error, and the longest key that leads to a node wins.
+func lookupYAML(node *yaml.Node, path []string) *yaml.Node {
+	if len(path) == 0 {
+		return node
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for n := len(path); n > 0; n-- {
+			key := strings.Join(path[:n], ".")
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value != key {
+					continue
+				}
+				if found := lookupYAML(node.Content[i+1], path[n:]); found != nil {
+					return found
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(node.Content) {
+			return lookupYAML(node.Content[i], path[1:])
+		}
+	}
+	return nil
+}
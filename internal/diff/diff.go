@@ -0,0 +1,380 @@
+// Package diff compares two OpenAPI documents and describes the changes between them.
+package diff
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the kind of a change.
+type Kind string
+
+const (
+	Added   Kind = "Added"
+	Changed Kind = "Changed"
+	Removed Kind = "Removed"
+)
+
+// Change is a single difference between two documents.
+type Change struct {
+	Kind Kind
+	// Breaking is true when clients of the old document can break because of the change.
+	Breaking bool
+	// Subject is what changed, like "operation `POST /example.v1.BookService/GetBook`".
+	Subject string
+	// Detail describes how a changed subject changed.
+	Detail string
+}
+
+func (c Change) String() string {
+	if c.Detail == "" {
+		return c.Subject
+	}
+	return c.Subject + ": " + c.Detail
+}
+
+// Report is the list of changes between two documents.
+type Report struct {
+	// PreviousVersion is info.version of the old document.
+	PreviousVersion string
+	Changes         []Change
+	// Identical is true when the documents are the same apart from info.version.
+	Identical bool
+}
+
+// Bump is a semantic versioning increment.
+type Bump string
+
+const (
+	Major Bump = "major"
+	Minor Bump = "minor"
+	Patch Bump = "patch"
+	None  Bump = "none"
+)
+
+// Bump recommends a version increment: major for breaking changes, minor for any other change to operations,
+// schemas or fields, patch when only things like descriptions changed and none when the documents are identical.
+func (r *Report) Bump() Bump {
+	if r.HasBreakingChanges() {
+		return Major
+	}
+	if len(r.Changes) > 0 {
+		return Minor
+	}
+	if r.Identical {
+		return None
+	}
+	return Patch
+}
+
+// NextVersion applies the bump to a semantic version like "1.2.3" or "v1.2.3". Pre-release and build metadata are
+// dropped, unless the bump is None and the version is returned as is.
+func NextVersion(version string, bump Bump) (string, error) {
+	original := version
+	prefix := ""
+	if strings.HasPrefix(version, "v") {
+		prefix, version = "v", version[1:]
+	}
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("version should be in the form MAJOR.MINOR.PATCH, not '%s'", prefix+version)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("version should be in the form MAJOR.MINOR.PATCH, not '%s'", prefix+version)
+		}
+		numbers[i] = n
+	}
+	switch bump {
+	case Major:
+		numbers = []int{numbers[0] + 1, 0, 0}
+	case Minor:
+		numbers = []int{numbers[0], numbers[1] + 1, 0}
+	case None:
+		return original, nil
+	default:
+		numbers[2]++
+	}
+	return fmt.Sprintf("%s%d.%d.%d", prefix, numbers[0], numbers[1], numbers[2]), nil
+}
+
+// HasBreakingChanges returns true if any change is breaking.
+func (r *Report) HasBreakingChanges() bool {
+	for _, change := range r.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the report as a changelog section with a heading for each kind of change.
+func (r *Report) Markdown(title string) string {
+	var sb strings.Builder
+	sb.WriteString("## " + title + "\n")
+	if len(r.Changes) == 0 {
+		sb.WriteString("\nNo changes.\n")
+		return sb.String()
+	}
+	for _, kind := range []Kind{Added, Changed, Removed} {
+		var lines []string
+		for _, change := range r.Changes {
+			if change.Kind != kind {
+				continue
+			}
+			line := "- " + change.String()
+			if change.Breaking {
+				line += " **(breaking)**"
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		sb.WriteString("\n### " + string(kind) + "\n")
+		sb.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	return sb.String()
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Compare returns the changes from the old document to the new one. Both documents can be YAML or JSON. Operations
+// and schemas are compared, including the properties of each schema.
+func Compare(oldDoc, newDoc []byte) (*Report, error) {
+	var before, after map[string]any
+	if err := yaml.Unmarshal(oldDoc, &before); err != nil {
+		return nil, fmt.Errorf("unable to parse old document: %w", err)
+	}
+	if err := yaml.Unmarshal(newDoc, &after); err != nil {
+		return nil, fmt.Errorf("unable to parse new document: %w", err)
+	}
+	report := &Report{}
+	if info, ok := before["info"].(map[string]any); ok && info["version"] != nil {
+		report.PreviousVersion = fmt.Sprint(info["version"])
+	}
+	compareOperations(report, before, after)
+	compareSchemas(report, before, after)
+	report.Identical = reflect.DeepEqual(withoutVersion(before), withoutVersion(after))
+	return report, nil
+}
+
+// withoutVersion returns a shallow copy of a document without info.version.
+func withoutVersion(doc map[string]any) map[string]any {
+	info, ok := doc["info"].(map[string]any)
+	if !ok {
+		return doc
+	}
+	res := maps.Clone(doc)
+	info = maps.Clone(info)
+	delete(info, "version")
+	res["info"] = info
+	return res
+}
+
+func compareOperations(report *Report, before, after map[string]any) {
+	oldOps, newOps := operations(before), operations(after)
+	for _, key := range sortedKeys(newOps) {
+		subject := "operation `" + key + "`"
+		oldOp, ok := oldOps[key]
+		if !ok {
+			report.Changes = append(report.Changes, Change{Kind: Added, Subject: subject})
+			continue
+		}
+		newOp := newOps[key]
+		if isTrue(newOp["deprecated"]) && !isTrue(oldOp["deprecated"]) {
+			report.Changes = append(report.Changes, Change{Kind: Changed, Subject: subject, Detail: "deprecated"})
+		}
+	}
+	for _, key := range sortedKeys(oldOps) {
+		if _, ok := newOps[key]; !ok {
+			report.Changes = append(report.Changes, Change{Kind: Removed, Breaking: true, Subject: "operation `" + key + "`"})
+		}
+	}
+}
+
+// operations returns every operation in the document, keyed by method and path, like "POST /example.v1.Svc/Get".
+func operations(doc map[string]any) map[string]map[string]any {
+	res := map[string]map[string]any{}
+	paths, _ := doc["paths"].(map[string]any)
+	for path, item := range paths {
+		item, _ := item.(map[string]any)
+		for _, method := range methods {
+			if op, ok := item[method].(map[string]any); ok {
+				res[strings.ToUpper(method)+" "+path] = op
+			}
+		}
+	}
+	return res
+}
+
+func compareSchemas(report *Report, before, after map[string]any) {
+	oldSchemas, newSchemas := schemas(before), schemas(after)
+	for _, name := range sortedKeys(newSchemas) {
+		oldSchema, ok := oldSchemas[name]
+		if !ok {
+			report.Changes = append(report.Changes, Change{Kind: Added, Subject: "schema `" + name + "`"})
+			continue
+		}
+		compareSchema(report, name, oldSchema, newSchemas[name])
+	}
+	for _, name := range sortedKeys(oldSchemas) {
+		if _, ok := newSchemas[name]; !ok {
+			report.Changes = append(report.Changes, Change{Kind: Removed, Breaking: true, Subject: "schema `" + name + "`"})
+		}
+	}
+}
+
+func schemas(doc map[string]any) map[string]map[string]any {
+	res := map[string]map[string]any{}
+	components, _ := doc["components"].(map[string]any)
+	all, _ := components["schemas"].(map[string]any)
+	for name, schema := range all {
+		if schema, ok := schema.(map[string]any); ok {
+			res[name] = schema
+		}
+	}
+	return res
+}
+
+func compareSchema(report *Report, name string, before, after map[string]any) {
+	subject := "schema `" + name + "`"
+	if oldType, newType := typeOf(before), typeOf(after); oldType != newType {
+		report.Changes = append(report.Changes, Change{Kind: Changed, Breaking: true, Subject: subject, Detail: fmt.Sprintf("type changed from %s to %s", oldType, newType)})
+	}
+	compareEnum(report, subject, before, after)
+
+	oldProps, _ := before["properties"].(map[string]any)
+	newProps, _ := after["properties"].(map[string]any)
+	oldRequired, newRequired := stringSet(before["required"]), stringSet(after["required"])
+	for _, prop := range sortedKeys(newProps) {
+		fieldSubject := "field `" + name + "." + prop + "`"
+		oldProp, ok := oldProps[prop]
+		if !ok {
+			_, required := newRequired[prop]
+			report.Changes = append(report.Changes, Change{Kind: Added, Breaking: required, Subject: fieldSubject})
+			continue
+		}
+		oldSchema, _ := oldProp.(map[string]any)
+		newSchema, _ := newProps[prop].(map[string]any)
+		if oldType, newType := typeOf(oldSchema), typeOf(newSchema); oldType != newType {
+			report.Changes = append(report.Changes, Change{Kind: Changed, Breaking: true, Subject: fieldSubject, Detail: fmt.Sprintf("type changed from %s to %s", oldType, newType)})
+		}
+		_, wasRequired := oldRequired[prop]
+		_, isRequired := newRequired[prop]
+		if isRequired && !wasRequired {
+			report.Changes = append(report.Changes, Change{Kind: Changed, Breaking: true, Subject: fieldSubject, Detail: "now required"})
+		} else if wasRequired && !isRequired {
+			report.Changes = append(report.Changes, Change{Kind: Changed, Subject: fieldSubject, Detail: "no longer required"})
+		}
+		if isTrue(newSchema["deprecated"]) && !isTrue(oldSchema["deprecated"]) {
+			report.Changes = append(report.Changes, Change{Kind: Changed, Subject: fieldSubject, Detail: "deprecated"})
+		}
+	}
+	for _, prop := range sortedKeys(oldProps) {
+		if _, ok := newProps[prop]; !ok {
+			report.Changes = append(report.Changes, Change{Kind: Removed, Breaking: true, Subject: "field `" + name + "." + prop + "`"})
+		}
+	}
+}
+
+// compareEnum reports enum values that were added or removed. Removing a value is breaking because clients may
+// still send it.
+func compareEnum(report *Report, subject string, before, after map[string]any) {
+	oldValues, newValues := valueSet(before["enum"]), valueSet(after["enum"])
+	if len(oldValues) == 0 || len(newValues) == 0 {
+		return
+	}
+	var added, removed []string
+	for value := range newValues {
+		if _, ok := oldValues[value]; !ok {
+			added = append(added, value)
+		}
+	}
+	for value := range oldValues {
+		if _, ok := newValues[value]; !ok {
+			removed = append(removed, value)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	if len(added) > 0 {
+		report.Changes = append(report.Changes, Change{Kind: Changed, Subject: subject, Detail: "added enum values " + strings.Join(added, ", ")})
+	}
+	if len(removed) > 0 {
+		report.Changes = append(report.Changes, Change{Kind: Changed, Breaking: true, Subject: subject, Detail: "removed enum values " + strings.Join(removed, ", ")})
+	}
+}
+
+// typeOf describes the type of a schema, using the name of the referenced schema for references.
+func typeOf(schema map[string]any) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		return "`" + strings.TrimPrefix(ref, "#/components/schemas/") + "`"
+	}
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, v := range t {
+			types = append(types, fmt.Sprint(v))
+		}
+	}
+	if len(types) == 0 {
+		return "any"
+	}
+	res := strings.Join(types, "|")
+	if res == "array" {
+		items, _ := schema["items"].(map[string]any)
+		return "array of " + typeOf(items)
+	}
+	return res
+}
+
+func stringSet(v any) map[string]struct{} {
+	res := map[string]struct{}{}
+	items, _ := v.([]any)
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			res[s] = struct{}{}
+		}
+	}
+	return res
+}
+
+func valueSet(v any) map[string]struct{} {
+	res := map[string]struct{}{}
+	items, _ := v.([]any)
+	for _, item := range items {
+		res[fmt.Sprint(item)] = struct{}{}
+	}
+	return res
+}
+
+func isTrue(v any) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const before = `
+openapi: 3.1.0
+paths:
+  /example.v1.BookService/GetBook:
+    post: {}
+  /example.v1.BookService/DeleteBook:
+    post: {}
+components:
+  schemas:
+    example.v1.Book:
+      type: object
+      properties:
+        title:
+          type: string
+        pages:
+          type: integer
+        isbn:
+          type: string
+      required: [title]
+    example.v1.Genre:
+      type: string
+      enum: [FICTION, POETRY]
+    example.v1.Shelf:
+      type: object
+`
+
+const after = `
+openapi: 3.1.0
+paths:
+  /example.v1.BookService/GetBook:
+    post:
+      deprecated: true
+  /example.v1.BookService/ListBooks:
+    post: {}
+components:
+  schemas:
+    example.v1.Book:
+      type: object
+      properties:
+        title:
+          type: string
+        pages:
+          type: string
+        author:
+          $ref: '#/components/schemas/example.v1.Author'
+    example.v1.Author:
+      type: object
+    example.v1.Genre:
+      type: string
+      enum: [FICTION, ESSAY]
+`
+
+func TestCompare(t *testing.T) {
+	report, err := Compare([]byte(before), []byte(after))
+	require.NoError(t, err)
+	assert.True(t, report.HasBreakingChanges())
+	assert.Equal(t, `## Changelog
+
+### Added
+- operation `+"`POST /example.v1.BookService/ListBooks`"+`
+- schema `+"`example.v1.Author`"+`
+- field `+"`example.v1.Book.author`"+`
+
+### Changed
+- operation `+"`POST /example.v1.BookService/GetBook`"+`: deprecated
+- field `+"`example.v1.Book.pages`"+`: type changed from integer to string **(breaking)**
+- field `+"`example.v1.Book.title`"+`: no longer required
+- schema `+"`example.v1.Genre`"+`: added enum values ESSAY
+- schema `+"`example.v1.Genre`"+`: removed enum values POETRY **(breaking)**
+
+### Removed
+- operation `+"`POST /example.v1.BookService/DeleteBook`"+` **(breaking)**
+- field `+"`example.v1.Book.isbn`"+` **(breaking)**
+- schema `+"`example.v1.Shelf`"+` **(breaking)**
+`, report.Markdown("Changelog"))
+}
+
+func TestCompareWithoutChanges(t *testing.T) {
+	report, err := Compare([]byte(before), []byte(before))
+	require.NoError(t, err)
+	assert.False(t, report.HasBreakingChanges())
+	assert.Empty(t, report.Changes)
+	assert.True(t, report.Identical)
+	assert.Equal(t, None, report.Bump())
+	assert.Equal(t, "## Changelog\n\nNo changes.\n", report.Markdown("Changelog"))
+}
+
+func TestBump(t *testing.T) {
+	report, err := Compare([]byte(before), []byte(after))
+	require.NoError(t, err)
+	assert.Equal(t, Major, report.Bump())
+
+	assert.Equal(t, Minor, (&Report{Changes: []Change{{Kind: Added, Subject: "schema `a`"}}}).Bump())
+	assert.Equal(t, Patch, (&Report{}).Bump())
+}
+
+func TestNextVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		bump     Bump
+		expected string
+	}{
+		{"1.2.3", Major, "2.0.0"},
+		{"1.2.3", Minor, "1.3.0"},
+		{"1.2.3", Patch, "1.2.4"},
+		{"v0.9.1", Minor, "v0.10.0"},
+		{"1.2.3-rc.1+build", Patch, "1.2.4"},
+		{"1.2.3-rc.1", None, "1.2.3-rc.1"},
+	} {
+		next, err := NextVersion(tc.version, tc.bump)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, next, tc.version)
+	}
+
+	_, err := NextVersion("v1", Major)
+	assert.ErrorContains(t, err, "not 'v1'")
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `
+openapi: 3.1.0
+components:
+  schemas:
+    example.v1.Book:
+      type: object
+      properties:
+        author:
+          $ref: '#/components/schemas/example.v1.Author'
+        related:
+          type: array
+          items:
+            $ref: '#/components/schemas/example.v1.Book'
+    example.v1.Author:
+      type: object
+      properties:
+        name:
+          type: string
+        country:
+          $ref: '#/components/schemas/example.v1.Country'
+    example.v1.Country:
+      type: string
+    example.v1.Unused:
+      type: object
+`
+
+func TestJSONSchemas(t *testing.T) {
+	schemas, err := JSONSchemas([]byte(document), []string{"example.v1.Book"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"author": {"$ref": "#/$defs/example.v1.Author"},
+			"related": {"type": "array", "items": {"$ref": "#"}}
+		},
+		"$defs": {
+			"example.v1.Author": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"country": {"$ref": "#/$defs/example.v1.Country"}
+				}
+			},
+			"example.v1.Country": {"type": "string"}
+		}
+	}`, string(schemas["example.v1.Book"]))
+
+	_, err = JSONSchemas([]byte(document), []string{"example.v1.Missing"})
+	assert.ErrorContains(t, err, "no schema found for message 'example.v1.Missing'")
+}
+
+func TestSubject(t *testing.T) {
+	subject, err := RecordNameStrategy.Subject("books", "example.v1.Book")
+	require.NoError(t, err)
+	assert.Equal(t, "example.v1.Book", subject)
+
+	subject, err = TopicNameStrategy.Subject("books", "example.v1.Book")
+	require.NoError(t, err)
+	assert.Equal(t, "books-value", subject)
+
+	subject, err = TopicRecordNameStrategy.Subject("books", "example.v1.Book")
+	require.NoError(t, err)
+	assert.Equal(t, "books-example.v1.Book", subject)
+
+	_, err = TopicRecordNameStrategy.Subject("", "example.v1.Book")
+	assert.Error(t, err)
+	_, err = SubjectStrategy("other").Subject("books", "example.v1.Book")
+	assert.Error(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	type registered struct {
+		path       string
+		schemaType string
+		schema     string
+	}
+	var requests []registered
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SchemaType string `json:"schemaType"`
+			Schema     string `json:"schema"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "application/vnd.schemaregistry.v1+json", r.Header.Get("Content-Type"))
+		requests = append(requests, registered{path: r.URL.Path, schemaType: body.SchemaType, schema: body.Schema})
+		if r.URL.Path == "/subjects/books-example.v1.Rejected/versions" {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error_code":409,"message":"incompatible schema"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":42}`))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL + "/", HTTPClient: server.Client()}
+	ids, err := client.Publish(map[string][]byte{
+		"example.v1.Book":   []byte(`{"type":"object"}`),
+		"example.v1.Author": []byte(`{"type":"string"}`),
+	}, TopicRecordNameStrategy, "books")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"books-example.v1.Author": 42, "books-example.v1.Book": 42}, ids)
+	assert.Equal(t, []registered{
+		{path: "/subjects/books-example.v1.Author/versions", schemaType: "JSON", schema: `{"type":"string"}`},
+		{path: "/subjects/books-example.v1.Book/versions", schemaType: "JSON", schema: `{"type":"object"}`},
+	}, requests)
+
+	_, err = client.Publish(map[string][]byte{"example.v1.Rejected": []byte(`{}`)}, TopicRecordNameStrategy, "books")
+	assert.ErrorContains(t, err, "409 Conflict: {\"error_code\":409,\"message\":\"incompatible schema\"}")
+
+	_, err = client.Publish(map[string][]byte{"a.A": []byte(`{}`), "a.B": []byte(`{}`)}, TopicNameStrategy, "books")
+	assert.ErrorContains(t, err, "needs exactly one message")
+}
+
+func TestPublishTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client := &Client{URL: server.URL, Timeout: 10 * time.Millisecond}
+	_, err := client.Publish(map[string][]byte{"example.v1.Book": []byte(`{}`)}, RecordNameStrategy, "")
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 	pluginpb "google.golang.org/protobuf/types/pluginpb"
 
 	"github.com/lmittmann/tint"
 	"github.com/sudorandom/protoc-gen-connect-openapi/internal/converter"
+	"github.com/sudorandom/protoc-gen-connect-openapi/internal/registry"
 )
 
 var (
@@ -22,12 +30,72 @@ var (
 
 func main() {
 	showVersion := flag.Bool("version", false, "print the version and exit")
+	stdin := flag.Bool("stdin", false, "read a FileDescriptorSet from stdin and write the OpenAPI document to stdout")
+	params := flag.String("params", "", "options in the same format as the plugin parameter, like 'format=json,with-streaming'")
+	files := flag.String("files", "", "comma-separated proto files to generate; defaults to the files in the set that aren't imported by other files")
+	service := flag.String("service", "", "with -stdin, generate a document for only this service, like 'foo.v1.UserService', and the types it uses")
+	outputDir := flag.String("output_dir", "", "with -stdin, write the generated files to this directory instead of stdout")
+	outputs := flag.String("outputs", "", "with -output_dir, comma-separated list of the files that must be generated; any other file is an error")
+	registryURL := flag.String("registry_url", "", "with -stdin, publish the JSON Schema of each message to this Confluent-compatible schema registry")
+	registryStrategy := flag.String("registry_subject_strategy", string(registry.RecordNameStrategy), "subject naming strategy for -registry_url: record, topic or topic-record")
+	registryTopic := flag.String("registry_topic", "", "topic used by the topic and topic-record subject strategies")
+	registryTimeout := flag.Duration("registry_timeout", registry.DefaultTimeout, "how long each request to -registry_url can take")
+	registryMessages := flag.String("registry_messages", "", "comma-separated full names of the messages to publish; defaults to the top-level messages of the generated files")
 	flag.Parse()
 	if *showVersion {
 		fmt.Printf("protoc-gen-connect-openapi %s\n", fullVersion())
 		return
 	}
 
+	if !*stdin && (*outputDir != "" || *outputs != "") {
+		fmt.Fprintln(os.Stderr, "error: -output_dir and -outputs can only be used with -stdin")
+		os.Exit(1)
+	}
+	if *outputs != "" && *outputDir == "" {
+		fmt.Fprintln(os.Stderr, "error: -outputs can only be used with -output_dir")
+		os.Exit(1)
+	}
+
+	if *stdin {
+		var fileList []string
+		if *files != "" {
+			fileList = strings.Split(*files, ",")
+		}
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		parameter := *params
+		if *service != "" {
+			if *files != "" {
+				fmt.Fprintln(os.Stderr, "error: -service and -files can't be used together")
+				os.Exit(1)
+			}
+			fileList, parameter, err = serviceOptions(input, *service, parameter)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		resp, err := runStdin(input, fileList, parameter, *outputDir, *outputs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if *registryURL != "" {
+			var messages []string
+			if *registryMessages != "" {
+				messages = strings.Split(*registryMessages, ",")
+			}
+			if err := publishSchemas(input, fileList, resp, messages, &registry.Client{URL: *registryURL, Timeout: *registryTimeout}, registry.SubjectStrategy(*registryStrategy), *registryTopic); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
 	resp, err := converter.ConvertFrom(os.Stdin)
 	if err != nil {
 		message := fmt.Sprintf("Failed to read input: %v", err)
@@ -47,6 +115,122 @@ func main() {
 	renderResponse(resp)
 }
 
+// runStdin converts a FileDescriptorSet from stdin. Without an output directory, a single merged document is written
+// to stdout. The generated response is returned so it can be reused.
+func runStdin(input []byte, files []string, params, outputDir, outputs string) (*pluginpb.CodeGeneratorResponse, error) {
+	resp, err := converter.ConvertFileDescriptorSet(bytes.NewReader(input), params, files, outputDir == "")
+	if err != nil {
+		return nil, err
+	}
+	if outputDir == "" {
+		if len(resp.File) != 1 {
+			return nil, fmt.Errorf("expected one output file, got %d; use -output_dir to write multiple files", len(resp.File))
+		}
+		_, err := os.Stdout.WriteString(resp.File[0].GetContent())
+		return resp, err
+	}
+
+	if outputs != "" {
+		if err := checkOutputs(resp.File, strings.Split(outputs, ",")); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range resp.File {
+		if !filepath.IsLocal(file.GetName()) {
+			return nil, fmt.Errorf("generated file '%s' would be written outside of the output directory", file.GetName())
+		}
+		path := filepath.Join(outputDir, file.GetName())
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(file.GetContent()), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// serviceOptions returns the files and parameters that generate a document for a single service: only the file that
+// defines the service is generated, other services are filtered out and only the types the service uses are kept.
+func serviceOptions(input []byte, service, params string) ([]string, string, error) {
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(input, fds); err != nil {
+		return nil, "", fmt.Errorf("can't unmarshal descriptor set: %w", err)
+	}
+	file, err := converter.ServiceFile(fds, service)
+	if err != nil {
+		return nil, "", err
+	}
+	if params != "" {
+		params += ","
+	}
+	return []string{file}, params + "services=" + service + ",trim-unused-types", nil
+}
+
+// publishSchemas publishes the JSON Schema of each message to a schema registry. The schemas are taken from the
+// generated documents, so they match the schemas in the OpenAPI output.
+func publishSchemas(input []byte, files []string, resp *pluginpb.CodeGeneratorResponse, messages []string, client *registry.Client, strategy registry.SubjectStrategy, topic string) error {
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(input, fds); err != nil {
+		return fmt.Errorf("can't unmarshal descriptor set: %w", err)
+	}
+	if len(files) == 0 {
+		files = converter.RootFiles(fds)
+	}
+	if len(messages) == 0 {
+		messages = registry.Messages(fds, files)
+	}
+	if len(resp.File) == 0 {
+		return fmt.Errorf("no document was generated")
+	}
+	// Without -output_dir there's a single merged document, otherwise each message is looked up in every document.
+	schemas := map[string][]byte{}
+	for _, message := range messages {
+		var err error
+		for _, file := range resp.File {
+			var found map[string][]byte
+			if found, err = registry.JSONSchemas([]byte(file.GetContent()), []string{message}); err == nil {
+				maps.Copy(schemas, found)
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	ids, err := client.Publish(schemas, strategy, topic)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		subject, _ := strategy.Subject(topic, message)
+		slog.Info("published schema", slog.String("subject", subject), slog.Int("id", ids[subject]))
+	}
+	return nil
+}
+
+// checkOutputs makes sure the generated files exactly match the pre-declared outputs, which is what build systems
+// like Bazel expect.
+func checkOutputs(files []*pluginpb.CodeGeneratorResponse_File, declared []string) error {
+	expected := map[string]struct{}{}
+	for _, name := range declared {
+		expected[name] = struct{}{}
+	}
+	var errs []error
+	for _, file := range files {
+		if _, ok := expected[file.GetName()]; !ok {
+			errs = append(errs, fmt.Errorf("generated file '%s' was not declared as an output", file.GetName()))
+		}
+		delete(expected, file.GetName())
+	}
+	for _, name := range declared {
+		if _, ok := expected[name]; ok {
+			errs = append(errs, fmt.Errorf("declared output '%s' was not generated", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func renderResponse(resp *pluginpb.CodeGeneratorResponse) {
 	data, err := proto.Marshal(resp)
 	if err != nil {